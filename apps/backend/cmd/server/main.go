@@ -1,11 +1,23 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/dhani/bill-tracker-backend/internal/config"
 	"github.com/dhani/bill-tracker-backend/internal/database"
+	"github.com/dhani/bill-tracker-backend/internal/mailer"
+	"github.com/dhani/bill-tracker-backend/internal/oidc"
 	"github.com/dhani/bill-tracker-backend/internal/routes"
+	"github.com/dhani/bill-tracker-backend/internal/scheduler"
+	"github.com/dhani/bill-tracker-backend/internal/services"
+	"github.com/dhani/bill-tracker-backend/internal/smtpd"
+	"github.com/dhani/bill-tracker-backend/internal/storage"
 )
 
 func main() {
@@ -21,12 +33,98 @@ func main() {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Outgoing email
+	mail, err := mailer.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize mailer: %v", err)
+	}
+
+	// Start background jobs
+	jobs := scheduler.New()
+	jobs.Register(scheduler.RecurringBillsJob(services.NewRecurringService(db), cfg.RecurringBillsInterval))
+	jobs.Register(scheduler.OverdueBillsJob(services.NewOverdueService(db), cfg.OverdueSweepInterval))
+	jobs.Register(scheduler.ExpiredSessionsJob(services.NewAuthService(db), cfg.SessionPurgeInterval))
+	jobs.Register(scheduler.PaymentRemindersJob(services.NewReminderService(db, mail, cfg.FrontendURL), cfg.ReminderInterval))
+	jobs.Register(scheduler.WebhookDeliveriesJob(
+		services.NewWebhookDispatcher(db, cfg.WebhookTimeout, cfg.WebhookAllowPrivateNetworks), cfg.WebhookDeliveryInterval))
+	jobs.Start(ctx)
+
+	// Attachment storage
+	store, err := storage.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+
+	// Email-to-bill SMTP listener
+	if cfg.SMTPListenAddr != "" {
+		signer := services.NewAttachmentSigner(cfg.AttachmentURLSecret, cfg.AttachmentURLTTL)
+		attachments := services.NewAttachmentService(db, store, signer, cfg.MaxAttachmentSize)
+		smtpServer := &smtpd.Server{
+			Addr:           cfg.SMTPListenAddr,
+			Hostname:       cfg.SMTPHostname,
+			MaxSize:        cfg.SMTPMaxMessageSize,
+			MaxConnections: cfg.SMTPMaxConnections,
+			Handler:        services.NewEmailIngestService(db, attachments, cfg.InboundEmailPrefix, cfg.InboundEmailDomain),
+		}
+
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := smtpServer.Shutdown(shutdownCtx); err != nil {
+				log.Printf("SMTP server shutdown error: %v", err)
+			}
+		}()
+
+		go func() {
+			log.Printf("SMTP server listening on %s", cfg.SMTPListenAddr)
+			if err := smtpServer.ListenAndServe(); err != nil && !errors.Is(err, smtpd.ErrServerClosed) {
+				log.Printf("SMTP server stopped: %v", err)
+			}
+		}()
+	}
+
+	// Sign-in with Google (optional)
+	var google services.IdentityProvider
+	if cfg.GoogleClientID != "" {
+		provider, err := oidc.New(oidc.Options{
+			Issuer:       cfg.GoogleIssuerURL,
+			ClientID:     cfg.GoogleClientID,
+			ClientSecret: cfg.GoogleClientSecret,
+			RedirectURL:  cfg.GoogleRedirectURL,
+		})
+		if err != nil {
+			log.Fatalf("Failed to initialize Google sign-in: %v", err)
+		}
+		google = provider
+	}
+
 	// Setup router
-	router := routes.SetupRouter(db)
+	router := routes.SetupRouter(db, store, mail, google)
+
+	server := &http.Server{
+		Addr:    ":" + cfg.Port,
+		Handler: router,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Server shutdown error: %v", err)
+		}
+	}()
 
 	// Start server
 	log.Printf("Server starting on port %s", cfg.Port)
-	if err := router.Run(":" + cfg.Port); err != nil {
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatalf("Failed to start server: %v", err)
 	}
+
+	jobs.Wait()
+	log.Println("Server stopped")
 }
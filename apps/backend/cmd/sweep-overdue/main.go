@@ -0,0 +1,29 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/dhani/bill-tracker-backend/internal/config"
+	"github.com/dhani/bill-tracker-backend/internal/database"
+	"github.com/dhani/bill-tracker-backend/internal/services"
+)
+
+// One-shot overdue sweep, e.g. for cron or manual runs:
+//
+//	go run ./cmd/sweep-overdue
+func main() {
+	cfg := config.Load()
+	db := database.Connect(cfg)
+
+	if err := database.Migrate(); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	updated, err := services.NewOverdueService(db).SweepOverdue(time.Now())
+	if err != nil {
+		log.Fatalf("Overdue sweep failed: %v", err)
+	}
+
+	log.Printf("Marked %d bill(s) as overdue", updated)
+}
@@ -0,0 +1,120 @@
+package currency
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Rate is a parsed exchange rate: 1 unit of Base equals Rate units of Quote
+type Rate struct {
+	Base          string
+	Quote         string
+	Rate          decimal.Decimal
+	EffectiveDate time.Time
+}
+
+// ParseCSV reads rates from CSV with a header row containing the columns
+// date (YYYY-MM-DD), base, quote and rate, in any order
+func ParseCSV(r io.Reader) ([]Rate, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"date", "base", "quote", "rate"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV is missing the %q column", required)
+		}
+	}
+
+	var rates []Rate
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		rate, err := newRate(record[columns["base"]], record[columns["quote"]], record[columns["rate"]], record[columns["date"]])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rates = append(rates, rate)
+	}
+
+	return rates, nil
+}
+
+// ecbEnvelope matches the European Central Bank reference rate feeds
+// (eurofxref-daily.xml and eurofxref-hist.xml), which quote against EUR
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// ParseECB reads rates from an ECB euro foreign exchange reference rate XML file
+func ParseECB(r io.Reader) ([]Rate, error) {
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("parsing ECB XML: %w", err)
+	}
+
+	var rates []Rate
+	for _, day := range envelope.Days {
+		for _, entry := range day.Rates {
+			rate, err := newRate("EUR", entry.Currency, entry.Rate, day.Time)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", day.Time, entry.Currency, err)
+			}
+			rates = append(rates, rate)
+		}
+	}
+
+	if len(rates) == 0 {
+		return nil, errors.New("no rates found in ECB XML")
+	}
+	return rates, nil
+}
+
+func newRate(base, quote, value, date string) (Rate, error) {
+	base, quote = Normalize(base), Normalize(quote)
+	if !IsValid(base) {
+		return Rate{}, fmt.Errorf("invalid currency %q", base)
+	}
+	if !IsValid(quote) {
+		return Rate{}, fmt.Errorf("invalid currency %q", quote)
+	}
+
+	rate, err := decimal.NewFromString(strings.TrimSpace(value))
+	if err != nil || !rate.IsPositive() {
+		return Rate{}, fmt.Errorf("invalid rate %q", value)
+	}
+
+	effective, err := time.Parse("2006-01-02", strings.TrimSpace(date))
+	if err != nil {
+		return Rate{}, fmt.Errorf("invalid date %q", date)
+	}
+
+	return Rate{Base: base, Quote: quote, Rate: rate, EffectiveDate: effective}, nil
+}
@@ -36,21 +36,138 @@ func Connect(cfg *config.Config) *gorm.DB {
 func Migrate() error {
 	log.Println("Running database migrations...")
 
+	if err := dropGlobalInvoiceIndex(); err != nil {
+		return err
+	}
+
+	if err := dropSessionTokens(); err != nil {
+		return err
+	}
+
 	err := DB.AutoMigrate(
 		&models.Company{},
 		&models.User{},
 		&models.Session{},
+		&models.UsedRefreshToken{},
+		&models.UserIdentity{},
+		&models.SSOConfig{},
 		&models.Vendor{},
 		&models.Category{},
 		&models.Bill{},
 		&models.BillAttachment{},
 		&models.BillActivity{},
+		&models.BillLineItem{},
+		&models.Payment{},
+		&models.ApprovalPolicy{},
+		&models.BillApproval{},
+		&models.ExchangeRate{},
+		&models.ReminderRule{},
+		&models.ReminderDelivery{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
 	)
 
 	if err != nil {
 		return err
 	}
 
+	if err := backfillPayments(); err != nil {
+		return err
+	}
+
+	if err := backfillInboundTokens(); err != nil {
+		return err
+	}
+
+	if err := backfillSSOVerificationTokens(); err != nil {
+		return err
+	}
+
+	if err := backfillUsedRefreshTokens(); err != nil {
+		return err
+	}
+
 	log.Println("Database migrations completed")
 	return nil
 }
+
+// dropGlobalInvoiceIndex removes the original unique index on invoice_number,
+// which blocked different companies from recording the same vendor invoice
+// number. Uniqueness is now scoped to (company, vendor, invoice number).
+func dropGlobalInvoiceIndex() error {
+	if !DB.Migrator().HasIndex(&models.Bill{}, "idx_bills_invoice_number") {
+		return nil
+	}
+	return DB.Migrator().DropIndex(&models.Bill{}, "idx_bills_invoice_number")
+}
+
+// dropSessionTokens removes the raw token column from sessions. Sessions are
+// now identified by the token's jti; tokens issued before carry none and can
+// no longer authenticate, so their sessions are removed as well.
+func dropSessionTokens() error {
+	if !DB.Migrator().HasColumn(&models.Session{}, "token") {
+		return nil
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`DELETE FROM sessions`).Error; err != nil {
+			return err
+		}
+		return tx.Migrator().DropColumn(&models.Session{}, "token")
+	})
+}
+
+// backfillPayments records a single payment for bills that were marked paid
+// before the payment ledger existed, so reports based on payments stay complete
+func backfillPayments() error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`
+			INSERT INTO payments (id, company_id, bill_id, amount, paid_date, method, created_at)
+			SELECT gen_random_uuid(), b.company_id, b.id, b.amount, COALESCE(b.paid_date, b.updated_at::date), b.payment_method, NOW()
+			FROM bills b
+			WHERE b.status = 'paid' AND b.amount_paid = 0
+			AND NOT EXISTS (SELECT 1 FROM payments p WHERE p.bill_id = b.id)
+		`).Error; err != nil {
+			return err
+		}
+
+		return tx.Exec(`UPDATE bills SET amount_paid = amount WHERE status = 'paid' AND amount_paid = 0`).Error
+	})
+}
+
+// backfillInboundTokens gives companies created before email-to-bill existed
+// their inbound address token
+func backfillInboundTokens() error {
+	var companies []models.Company
+	if err := DB.Select("id").Where("inbound_token IS NULL").Find(&companies).Error; err != nil {
+		return err
+	}
+	for _, company := range companies {
+		if err := DB.Model(&company).Update("inbound_token", models.NewInboundToken()).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillSSOVerificationTokens gives single sign-on settings saved before
+// domain verification existed a token to publish. Their domains are
+// unverified until the company proves it owns them.
+func backfillSSOVerificationTokens() error {
+	return DB.Exec(`
+		UPDATE sso_configs
+		SET verification_token = 'billtracker-verification=' || replace(gen_random_uuid()::text, '-', '')
+		WHERE verification_token = ''
+	`).Error
+}
+
+// backfillUsedRefreshTokens remembers the last rotated token of sessions
+// refreshed before used tokens were kept, so replaying it still revokes them
+func backfillUsedRefreshTokens() error {
+	return DB.Exec(`
+		INSERT INTO used_refresh_tokens (hash, session_id, created_at)
+		SELECT previous_refresh_hash, id, COALESCE(refreshed_at, NOW())
+		FROM sessions
+		WHERE previous_refresh_hash IS NOT NULL
+		ON CONFLICT DO NOTHING
+	`).Error
+}
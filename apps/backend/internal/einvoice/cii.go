@@ -0,0 +1,196 @@
+package einvoice
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ciiInvoice maps the parts of a UN/CEFACT Cross Industry Invoice (D16B)
+// that bills use, as profiled by ZUGFeRD 2.x, Factur-X and XRechnung
+type ciiInvoice struct {
+	Document struct {
+		ID        string   `xml:"ID"`
+		TypeCode  string   `xml:"TypeCode"`
+		IssueDate string   `xml:"IssueDateTime>DateTimeString"`
+		Notes     []string `xml:"IncludedNote>Content"`
+	} `xml:"ExchangedDocument"`
+	Transaction struct {
+		Lines      []ciiLine `xml:"IncludedSupplyChainTradeLineItem"`
+		Seller     ciiParty  `xml:"ApplicableHeaderTradeAgreement>SellerTradeParty"`
+		Settlement struct {
+			Currency   string   `xml:"InvoiceCurrencyCode"`
+			DueDates   []string `xml:"SpecifiedTradePaymentTerms>DueDateDateTime>DateTimeString"`
+			TaxBasis   amount   `xml:"SpecifiedTradeSettlementHeaderMonetarySummation>TaxBasisTotalAmount"`
+			TaxTotals  []amount `xml:"SpecifiedTradeSettlementHeaderMonetarySummation>TaxTotalAmount"`
+			GrandTotal amount   `xml:"SpecifiedTradeSettlementHeaderMonetarySummation>GrandTotalAmount"`
+			DuePayable amount   `xml:"SpecifiedTradeSettlementHeaderMonetarySummation>DuePayableAmount"`
+		} `xml:"ApplicableHeaderTradeSettlement"`
+	} `xml:"SupplyChainTradeTransaction"`
+}
+
+type ciiParty struct {
+	Name             string `xml:"Name"`
+	TaxRegistrations []struct {
+		ID struct {
+			Value  string `xml:",chardata"`
+			Scheme string `xml:"schemeID,attr"`
+		} `xml:"ID"`
+	} `xml:"SpecifiedTaxRegistration"`
+	Email   string `xml:"URIUniversalCommunication>URIID"`
+	Contact string `xml:"DefinedTradeContact>EmailURIUniversalCommunication>URIID"`
+	Address struct {
+		Postcode string `xml:"PostcodeCode"`
+		LineOne  string `xml:"LineOne"`
+		LineTwo  string `xml:"LineTwo"`
+		City     string `xml:"CityName"`
+		Country  string `xml:"CountryID"`
+	} `xml:"PostalTradeAddress"`
+}
+
+type ciiLine struct {
+	Name       string `xml:"SpecifiedTradeProduct>Name"`
+	NetPrice   amount `xml:"SpecifiedLineTradeAgreement>NetPriceProductTradePrice>ChargeAmount"`
+	BasisQty   string `xml:"SpecifiedLineTradeAgreement>NetPriceProductTradePrice>BasisQuantity"`
+	Quantity   string `xml:"SpecifiedLineTradeDelivery>BilledQuantity"`
+	TaxPercent string `xml:"SpecifiedLineTradeSettlement>ApplicableTradeTax>RateApplicablePercent"`
+	LineTotal  amount `xml:"SpecifiedLineTradeSettlement>SpecifiedTradeSettlementLineMonetarySummation>LineTotalAmount"`
+}
+
+// ciiCreditNote is the document type code of a credit note; credits are not bills
+const ciiCreditNote = "381"
+
+func parseCII(data []byte) (*Invoice, error) {
+	var doc ciiInvoice
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInvoice, err)
+	}
+	if strings.TrimSpace(doc.Document.TypeCode) == ciiCreditNote {
+		return nil, fmt.Errorf("%w: credit notes cannot be recorded as bills", ErrUnsupportedDocument)
+	}
+
+	settlement := doc.Transaction.Settlement
+	inv := &Invoice{
+		Format:   FormatCII,
+		Number:   strings.TrimSpace(doc.Document.ID),
+		Currency: strings.ToUpper(strings.TrimSpace(settlement.Currency)),
+		Note:     joinNonEmpty("\n", doc.Document.Notes...),
+	}
+
+	var err error
+	if inv.IssueDate, err = parseCIIDate("issue date", doc.Document.IssueDate); err != nil {
+		return nil, err
+	}
+	if len(settlement.DueDates) > 0 {
+		due, err := parseCIIDate("due date", settlement.DueDates[0])
+		if err != nil {
+			return nil, err
+		}
+		inv.DueDate = &due
+	}
+
+	seller := doc.Transaction.Seller
+	inv.Supplier = Party{
+		Name:  strings.TrimSpace(seller.Name),
+		Email: strings.TrimSpace(seller.Email),
+		Address: joinNonEmpty(", ",
+			joinNonEmpty(" ", seller.Address.LineOne, seller.Address.LineTwo),
+			joinNonEmpty(" ", seller.Address.Postcode, seller.Address.City),
+			seller.Address.Country),
+	}
+	if inv.Supplier.Email == "" {
+		inv.Supplier.Email = strings.TrimSpace(seller.Contact)
+	}
+	// Prefer the VAT number (VA) over the local fiscal number (FC)
+	for _, reg := range seller.TaxRegistrations {
+		if id := strings.TrimSpace(reg.ID.Value); id != "" && (inv.Supplier.TaxID == "" || reg.ID.Scheme == "VA") {
+			inv.Supplier.TaxID = id
+		}
+	}
+
+	if inv.NetTotal, err = parseDecimal("tax basis amount", settlement.TaxBasis.Value); err != nil {
+		return nil, err
+	}
+	if inv.TaxTotal, err = parseDecimal("tax amount", pickAmount(settlement.TaxTotals, inv.Currency)); err != nil {
+		return nil, err
+	}
+	payable := settlement.DuePayable.Value
+	if strings.TrimSpace(payable) == "" {
+		payable = settlement.GrandTotal.Value
+	}
+	if inv.Payable, err = parseDecimal("payable amount", payable); err != nil {
+		return nil, err
+	}
+
+	for i, ln := range doc.Transaction.Lines {
+		line, err := parseCIILine(i+1, ln)
+		if err != nil {
+			return nil, err
+		}
+		inv.Lines = append(inv.Lines, line)
+	}
+
+	return inv, nil
+}
+
+func parseCIILine(n int, ln ciiLine) (Line, error) {
+	field := func(name string) string { return fmt.Sprintf("line %d %s", n, name) }
+
+	quantity, err := parseDecimal(field("quantity"), ln.Quantity)
+	if err != nil {
+		return Line{}, err
+	}
+	if quantity.IsZero() {
+		quantity = decimal.NewFromInt(1)
+	}
+	net, err := parseDecimal(field("amount"), ln.LineTotal.Value)
+	if err != nil {
+		return Line{}, err
+	}
+	price, err := parseDecimal(field("price"), ln.NetPrice.Value)
+	if err != nil {
+		return Line{}, err
+	}
+	basis, err := parseDecimal(field("basis quantity"), ln.BasisQty)
+	if err != nil {
+		return Line{}, err
+	}
+	if basis.IsPositive() {
+		price = price.Div(basis)
+	}
+	percent, err := parseDecimal(field("tax percent"), ln.TaxPercent)
+	if err != nil {
+		return Line{}, err
+	}
+
+	description := strings.TrimSpace(ln.Name)
+	if description == "" {
+		description = fmt.Sprintf("Line %d", n)
+	}
+
+	return Line{
+		Description: description,
+		Quantity:    quantity,
+		UnitPrice:   price,
+		NetAmount:   net,
+		TaxPercent:  percent,
+	}, nil
+}
+
+// parseCIIDate reads a qualified date; format 102 (CCYYMMDD) is the only one
+// the EN 16931 profiles allow, but ISO dates are accepted as well
+func parseCIIDate(field, value string) (time.Time, error) {
+	raw := strings.TrimSpace(value)
+	if raw == "" {
+		return time.Time{}, fmt.Errorf("%w: missing %s", ErrInvalidInvoice, field)
+	}
+	for _, layout := range []string{"20060102", "2006-01-02"} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: %s %q is not a date", ErrInvalidInvoice, field, raw)
+}
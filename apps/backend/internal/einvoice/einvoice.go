@@ -0,0 +1,165 @@
+// Package einvoice parses structured electronic invoices: UBL 2.1 (as used
+// by Peppol BIS Billing 3.0) and UN/CEFACT Cross Industry Invoice, the XML
+// carried by ZUGFeRD, Factur-X and XRechnung, either as a bare XML file or
+// embedded in a PDF/A-3.
+package einvoice
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrUnsupportedDocument = errors.New("unsupported e-invoice document")
+	ErrInvalidInvoice      = errors.New("invalid e-invoice")
+)
+
+// Format identifies the syntax an invoice was parsed from
+type Format string
+
+const (
+	FormatUBL Format = "ubl"
+	FormatCII Format = "cii"
+)
+
+// Party is the seller of an invoice
+type Party struct {
+	Name    string
+	TaxID   string
+	Email   string
+	Address string
+}
+
+// Line is an invoice line. Amounts exclude VAT; TaxPercent is the line's VAT rate.
+type Line struct {
+	Description string
+	Quantity    decimal.Decimal
+	UnitPrice   decimal.Decimal
+	NetAmount   decimal.Decimal
+	TaxPercent  decimal.Decimal
+}
+
+// Invoice holds the fields of an e-invoice needed to record a bill
+type Invoice struct {
+	Format    Format
+	Number    string
+	IssueDate time.Time
+	DueDate   *time.Time
+	Currency  string
+	Supplier  Party
+	Note      string
+	// NetTotal is the sum before VAT, TaxTotal the VAT in the invoice
+	// currency and Payable the amount due after prepayments and rounding
+	NetTotal decimal.Decimal
+	TaxTotal decimal.Decimal
+	Payable  decimal.Decimal
+	Lines    []Line
+}
+
+// Parse reads an e-invoice from a UBL or CII XML document or a PDF with one
+// of them embedded
+func Parse(data []byte) (*Invoice, error) {
+	if bytes.HasPrefix(data, []byte("%PDF-")) {
+		embedded, err := extractPDFInvoice(data)
+		if err != nil {
+			return nil, err
+		}
+		data = embedded
+	}
+
+	root, err := rootElement(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var invoice *Invoice
+	switch root {
+	case "Invoice":
+		invoice, err = parseUBL(data)
+	case "CrossIndustryInvoice":
+		invoice, err = parseCII(data)
+	default:
+		return nil, fmt.Errorf("%w: root element %s", ErrUnsupportedDocument, root)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return invoice, invoice.validate()
+}
+
+func (inv *Invoice) validate() error {
+	switch {
+	case inv.Number == "":
+		return fmt.Errorf("%w: missing invoice number", ErrInvalidInvoice)
+	case inv.Supplier.Name == "":
+		return fmt.Errorf("%w: missing seller name", ErrInvalidInvoice)
+	case inv.Currency == "":
+		return fmt.Errorf("%w: missing currency", ErrInvalidInvoice)
+	case !inv.Payable.IsPositive():
+		return fmt.Errorf("%w: amount due must be positive", ErrInvalidInvoice)
+	}
+	return nil
+}
+
+// rootElement returns the local name of the document element
+func rootElement(data []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", fmt.Errorf("%w: not an XML or PDF document", ErrUnsupportedDocument)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+// amount is a monetary XML element with an optional currency attribute
+type amount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"currencyID,attr"`
+}
+
+func parseDecimal(field, value string) (decimal.Decimal, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return decimal.Zero, nil
+	}
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("%w: %s %q is not a number", ErrInvalidInvoice, field, value)
+	}
+	return d, nil
+}
+
+// pickAmount returns the amount in the given currency, or the first one.
+// Invoices may repeat tax totals in the accounting currency.
+func pickAmount(amounts []amount, currency string) string {
+	for _, a := range amounts {
+		if a.Currency == "" || a.Currency == currency {
+			return a.Value
+		}
+	}
+	if len(amounts) > 0 {
+		return amounts[0].Value
+	}
+	return ""
+}
+
+// joinNonEmpty joins the non-blank parts with sep
+func joinNonEmpty(sep string, parts ...string) string {
+	var kept []string
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, sep)
+}
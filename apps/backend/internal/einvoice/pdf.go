@@ -0,0 +1,89 @@
+package einvoice
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// maxEmbeddedSize bounds a decompressed embedded file
+const maxEmbeddedSize = 20 << 20
+
+var (
+	streamKeyword = regexp.MustCompile(`stream\r?\n`)
+	directLength  = regexp.MustCompile(`/Length\s+(\d+)(\s+\d+\s+R)?`)
+)
+
+// extractPDFInvoice finds the invoice XML embedded in a ZUGFeRD or Factur-X
+// PDF. Rather than resolving the PDF's name tree it scans the file's stream
+// objects for an embedded file whose content is a UBL or CII document, which
+// also copes with producers that name the attachment differently.
+func extractPDFInvoice(data []byte) ([]byte, error) {
+	for _, loc := range streamKeyword.FindAllIndex(data, -1) {
+		start := loc[0]
+		// Skip "endstream", which the pattern also matches
+		if start >= 3 && string(data[start-3:start]) == "end" {
+			continue
+		}
+
+		// The stream dictionary runs from the object header to the keyword
+		header := bytes.LastIndex(data[:start], []byte(" obj"))
+		if header < 0 {
+			continue
+		}
+		dict := data[header:start]
+		if !bytes.Contains(dict, []byte("/EmbeddedFile")) {
+			continue
+		}
+
+		content, ok := streamContent(data, loc[1], dict)
+		if !ok {
+			continue
+		}
+		if root, err := rootElement(content); err == nil && (root == "CrossIndustryInvoice" || root == "Invoice") {
+			return content, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: PDF has no embedded ZUGFeRD, Factur-X or UBL invoice", ErrUnsupportedDocument)
+}
+
+// streamContent returns the decoded bytes of the stream starting at offset
+func streamContent(data []byte, offset int, dict []byte) ([]byte, bool) {
+	end := -1
+	if m := directLength.FindSubmatch(dict); m != nil && m[2] == nil {
+		if n, err := strconv.Atoi(string(m[1])); err == nil && offset+n <= len(data) {
+			end = offset + n
+		}
+	}
+	if end < 0 {
+		// The length is an indirect object; fall back to the end marker
+		i := bytes.Index(data[offset:], []byte("endstream"))
+		if i < 0 {
+			return nil, false
+		}
+		end = offset + i
+	}
+	raw := data[offset:end]
+
+	switch {
+	case bytes.Contains(dict, []byte("/FlateDecode")):
+		reader, err := zlib.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, false
+		}
+		defer reader.Close()
+		content, err := io.ReadAll(io.LimitReader(reader, maxEmbeddedSize))
+		if err != nil && len(content) == 0 {
+			return nil, false
+		}
+		return content, true
+	case bytes.Contains(dict, []byte("/Filter")):
+		// Other filters are not used for embedded invoices in practice
+		return nil, false
+	default:
+		return bytes.TrimRight(raw, "\r\n"), true
+	}
+}
@@ -0,0 +1,191 @@
+package einvoice
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ublInvoice maps the parts of a UBL 2.1 Invoice that bills use. Element
+// names are matched without namespaces, so the cbc/cac prefixes may vary.
+type ublInvoice struct {
+	ID                   string           `xml:"ID"`
+	IssueDate            string           `xml:"IssueDate"`
+	DueDate              string           `xml:"DueDate"`
+	Notes                []string         `xml:"Note"`
+	DocumentCurrencyCode string           `xml:"DocumentCurrencyCode"`
+	Supplier             ublParty         `xml:"AccountingSupplierParty>Party"`
+	PaymentDueDates      []string         `xml:"PaymentMeans>PaymentDueDate"`
+	TaxTotals            []amount         `xml:"TaxTotal>TaxAmount"`
+	Totals               ublTotals        `xml:"LegalMonetaryTotal"`
+	Lines                []ublInvoiceLine `xml:"InvoiceLine"`
+}
+
+type ublParty struct {
+	Names            []string `xml:"PartyName>Name"`
+	RegistrationName string   `xml:"PartyLegalEntity>RegistrationName"`
+	TaxIDs           []string `xml:"PartyTaxScheme>CompanyID"`
+	Email            string   `xml:"Contact>ElectronicMail"`
+	Address          struct {
+		Street     string `xml:"StreetName"`
+		Additional string `xml:"AdditionalStreetName"`
+		City       string `xml:"CityName"`
+		PostalZone string `xml:"PostalZone"`
+		Country    string `xml:"Country>IdentificationCode"`
+	} `xml:"PostalAddress"`
+}
+
+type ublTotals struct {
+	TaxExclusive amount `xml:"TaxExclusiveAmount"`
+	TaxInclusive amount `xml:"TaxInclusiveAmount"`
+	Payable      amount `xml:"PayableAmount"`
+}
+
+type ublInvoiceLine struct {
+	Quantity            string `xml:"InvoicedQuantity"`
+	LineExtensionAmount amount `xml:"LineExtensionAmount"`
+	Item                struct {
+		Name        string `xml:"Name"`
+		Description string `xml:"Description"`
+		TaxPercent  string `xml:"ClassifiedTaxCategory>Percent"`
+	} `xml:"Item"`
+	Price struct {
+		Amount       amount `xml:"PriceAmount"`
+		BaseQuantity string `xml:"BaseQuantity"`
+	} `xml:"Price"`
+}
+
+func parseUBL(data []byte) (*Invoice, error) {
+	var doc ublInvoice
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInvoice, err)
+	}
+
+	inv := &Invoice{
+		Format:   FormatUBL,
+		Number:   strings.TrimSpace(doc.ID),
+		Currency: strings.ToUpper(strings.TrimSpace(doc.DocumentCurrencyCode)),
+		Note:     joinNonEmpty("\n", doc.Notes...),
+	}
+
+	var err error
+	if inv.IssueDate, err = parseUBLDate("issue date", doc.IssueDate); err != nil {
+		return nil, err
+	}
+	// UBL 2.1 has a header due date; older documents only carry it on the payment means
+	dueDate := doc.DueDate
+	if dueDate == "" && len(doc.PaymentDueDates) > 0 {
+		dueDate = doc.PaymentDueDates[0]
+	}
+	if strings.TrimSpace(dueDate) != "" {
+		due, err := parseUBLDate("due date", dueDate)
+		if err != nil {
+			return nil, err
+		}
+		inv.DueDate = &due
+	}
+
+	party := doc.Supplier
+	inv.Supplier = Party{
+		Name:  strings.TrimSpace(party.RegistrationName),
+		Email: strings.TrimSpace(party.Email),
+		Address: joinNonEmpty(", ",
+			joinNonEmpty(" ", party.Address.Street, party.Address.Additional),
+			joinNonEmpty(" ", party.Address.PostalZone, party.Address.City),
+			party.Address.Country),
+	}
+	if len(party.Names) > 0 && strings.TrimSpace(party.Names[0]) != "" {
+		// The trading name is what people know the vendor by
+		inv.Supplier.Name = strings.TrimSpace(party.Names[0])
+	}
+	if len(party.TaxIDs) > 0 {
+		inv.Supplier.TaxID = strings.TrimSpace(party.TaxIDs[0])
+	}
+
+	if inv.NetTotal, err = parseDecimal("tax exclusive amount", doc.Totals.TaxExclusive.Value); err != nil {
+		return nil, err
+	}
+	if inv.TaxTotal, err = parseDecimal("tax amount", pickAmount(doc.TaxTotals, inv.Currency)); err != nil {
+		return nil, err
+	}
+	payable := doc.Totals.Payable.Value
+	if strings.TrimSpace(payable) == "" {
+		payable = doc.Totals.TaxInclusive.Value
+	}
+	if inv.Payable, err = parseDecimal("payable amount", payable); err != nil {
+		return nil, err
+	}
+
+	for i, ln := range doc.Lines {
+		line, err := parseUBLLine(i+1, ln)
+		if err != nil {
+			return nil, err
+		}
+		inv.Lines = append(inv.Lines, line)
+	}
+
+	return inv, nil
+}
+
+func parseUBLLine(n int, ln ublInvoiceLine) (Line, error) {
+	field := func(name string) string { return fmt.Sprintf("line %d %s", n, name) }
+
+	quantity, err := parseDecimal(field("quantity"), ln.Quantity)
+	if err != nil {
+		return Line{}, err
+	}
+	if quantity.IsZero() {
+		quantity = decimal.NewFromInt(1)
+	}
+	net, err := parseDecimal(field("amount"), ln.LineExtensionAmount.Value)
+	if err != nil {
+		return Line{}, err
+	}
+	price, err := parseDecimal(field("price"), ln.Price.Amount.Value)
+	if err != nil {
+		return Line{}, err
+	}
+	// Prices may be quoted per base quantity, e.g. per 100 units
+	base, err := parseDecimal(field("base quantity"), ln.Price.BaseQuantity)
+	if err != nil {
+		return Line{}, err
+	}
+	if base.IsPositive() {
+		price = price.Div(base)
+	}
+	percent, err := parseDecimal(field("tax percent"), ln.Item.TaxPercent)
+	if err != nil {
+		return Line{}, err
+	}
+
+	description := strings.TrimSpace(ln.Item.Name)
+	if description == "" {
+		description = strings.TrimSpace(ln.Item.Description)
+	}
+	if description == "" {
+		description = fmt.Sprintf("Line %d", n)
+	}
+
+	return Line{
+		Description: description,
+		Quantity:    quantity,
+		UnitPrice:   price,
+		NetAmount:   net,
+		TaxPercent:  percent,
+	}, nil
+}
+
+func parseUBLDate(field, value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("%w: missing %s", ErrInvalidInvoice, field)
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s %q is not a date", ErrInvalidInvoice, field, value)
+	}
+	return t, nil
+}
@@ -0,0 +1,180 @@
+// Package ical writes iCalendar (RFC 5545) feeds of all-day events.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Calendar is a VCALENDAR holding events
+type Calendar struct {
+	ProdID string
+	// Name is shown by calendar apps that support X-WR-CALNAME
+	Name string
+	// RefreshInterval suggests how often subscribers poll the feed
+	RefreshInterval time.Duration
+	Events          []Event
+}
+
+// Event is an all-day VEVENT
+type Event struct {
+	UID         string
+	Stamp       time.Time
+	Date        time.Time
+	Summary     string
+	Description string
+	URL         string
+	Categories  []string
+	// RRule is the recurrence rule value without the "RRULE:" prefix
+	RRule string
+	// Alarms are display reminders at these offsets from the start of the
+	// day; negative offsets are before it
+	Alarms []time.Duration
+}
+
+// WriteTo writes the calendar with CRLF line endings and folded lines
+func (c *Calendar) WriteTo(w io.Writer) (int64, error) {
+	lw := &lineWriter{w: bufio.NewWriter(w)}
+
+	lw.line("BEGIN:VCALENDAR")
+	lw.line("VERSION:2.0")
+	lw.line("PRODID:" + c.ProdID)
+	lw.line("CALSCALE:GREGORIAN")
+	lw.line("METHOD:PUBLISH")
+	if c.Name != "" {
+		lw.line("X-WR-CALNAME:" + Escape(c.Name))
+	}
+	if c.RefreshInterval > 0 {
+		interval := Duration(c.RefreshInterval)
+		lw.line("REFRESH-INTERVAL;VALUE=DURATION:" + interval)
+		lw.line("X-PUBLISHED-TTL:" + interval)
+	}
+
+	for i := range c.Events {
+		c.Events[i].write(lw)
+	}
+	lw.line("END:VCALENDAR")
+
+	if lw.err == nil {
+		lw.err = lw.w.Flush()
+	}
+	return lw.n, lw.err
+}
+
+func (e *Event) write(lw *lineWriter) {
+	lw.line("BEGIN:VEVENT")
+	lw.line("UID:" + e.UID)
+	lw.line("DTSTAMP:" + e.Stamp.UTC().Format("20060102T150405Z"))
+	lw.line("DTSTART;VALUE=DATE:" + e.Date.Format("20060102"))
+	lw.line("DTEND;VALUE=DATE:" + e.Date.AddDate(0, 0, 1).Format("20060102"))
+	if e.RRule != "" {
+		lw.line("RRULE:" + e.RRule)
+	}
+	lw.line("SUMMARY:" + Escape(e.Summary))
+	if e.Description != "" {
+		lw.line("DESCRIPTION:" + Escape(e.Description))
+	}
+	if e.URL != "" {
+		lw.line("URL:" + e.URL)
+	}
+	if len(e.Categories) > 0 {
+		escaped := make([]string, len(e.Categories))
+		for i, category := range e.Categories {
+			escaped[i] = Escape(category)
+		}
+		lw.line("CATEGORIES:" + strings.Join(escaped, ","))
+	}
+	lw.line("TRANSP:TRANSPARENT")
+
+	for _, offset := range e.Alarms {
+		lw.line("BEGIN:VALARM")
+		lw.line("ACTION:DISPLAY")
+		lw.line("DESCRIPTION:" + Escape(e.Summary))
+		if offset < 0 {
+			lw.line("TRIGGER:-" + Duration(-offset))
+		} else {
+			lw.line("TRIGGER:" + Duration(offset))
+		}
+		lw.line("END:VALARM")
+	}
+	lw.line("END:VEVENT")
+}
+
+// Escape escapes a TEXT value
+func Escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(s)
+}
+
+// Duration formats a non-negative duration as an RFC 5545 DURATION value
+func Duration(d time.Duration) string {
+	if d <= 0 {
+		return "PT0S"
+	}
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	if d == 0 {
+		return fmt.Sprintf("P%dD", days)
+	}
+
+	var b strings.Builder
+	b.WriteString("P")
+	if days > 0 {
+		fmt.Fprintf(&b, "%dD", days)
+	}
+	b.WriteString("T")
+	if h := d / time.Hour; h > 0 {
+		fmt.Fprintf(&b, "%dH", h)
+		d -= h * time.Hour
+	}
+	if m := d / time.Minute; m > 0 {
+		fmt.Fprintf(&b, "%dM", m)
+		d -= m * time.Minute
+	}
+	if s := d / time.Second; s > 0 {
+		fmt.Fprintf(&b, "%dS", s)
+	}
+	return b.String()
+}
+
+// lineWriter folds content lines at 75 octets without splitting UTF-8
+// sequences and remembers the first error
+type lineWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (lw *lineWriter) line(s string) {
+	const limit = 75
+	for first := true; lw.err == nil; first = false {
+		width := limit
+		if !first {
+			// Continuation lines start with a space
+			width--
+		}
+		cut := len(s)
+		if cut > width {
+			cut = width
+			for cut > 0 && !utf8.RuneStart(s[cut]) {
+				cut--
+			}
+		}
+
+		prefix := ""
+		if !first {
+			prefix = " "
+		}
+		n, err := lw.w.WriteString(prefix + s[:cut] + "\r\n")
+		lw.n += int64(n)
+		lw.err = err
+
+		s = s[cut:]
+		if s == "" {
+			return
+		}
+	}
+}
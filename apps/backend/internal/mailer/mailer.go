@@ -0,0 +1,60 @@
+// Package mailer sends outgoing email behind a small interface so the
+// transport can be switched between SMTP and a development logger by
+// configuration.
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/mail"
+
+	"github.com/dhani/bill-tracker-backend/internal/config"
+)
+
+// Message is a single email with a plain text body and an optional HTML
+// alternative
+type Message struct {
+	To      []*mail.Address
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers messages
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// New creates the mailer selected by the configuration
+func New(cfg *config.Config) (Mailer, error) {
+	from, err := mail.ParseAddress(cfg.MailFrom)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAIL_FROM %q: %w", cfg.MailFrom, err)
+	}
+
+	switch cfg.MailDriver {
+	case "", "smtp":
+		return &SMTP{
+			Addr:     cfg.MailSMTPAddr,
+			Username: cfg.MailSMTPUsername,
+			Password: cfg.MailSMTPPassword,
+			Hostname: cfg.SMTPHostname,
+			From:     from,
+		}, nil
+	case "log":
+		return Log{}, nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.MailDriver)
+	}
+}
+
+// Log writes messages to the application log instead of sending them. Only
+// recipients and subject are logged; bodies carry sign-in and verification
+// links that must not end up in logs.
+type Log struct{}
+
+func (Log) Send(ctx context.Context, msg *Message) error {
+	log.Printf("[mail] to=%v subject=%q", msg.To, msg.Subject)
+	return nil
+}
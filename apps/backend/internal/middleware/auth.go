@@ -23,17 +23,17 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-// GenerateToken creates a new JWT token for a user
-func GenerateToken(user *models.User) (string, error) {
-	expirationTime := time.Now().Add(time.Duration(config.AppConfig.JWTExpirationHours) * time.Hour)
-
+// GenerateToken creates a new JWT token for a user's session. The session ID
+// is the token's jti, which AuthMiddleware checks against the sessions table.
+func GenerateToken(user *models.User, sessionID uuid.UUID, expiresAt time.Time) (string, error) {
 	claims := &Claims{
 		UserID:    user.ID,
 		CompanyID: user.CompanyID,
 		Email:     user.Email,
 		Role:      string(user.Role),
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			ID:        sessionID.String(),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Subject:   user.ID.String(),
 		},
@@ -48,7 +48,7 @@ func ValidateToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		return []byte(config.AppConfig.JWTSecret), nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil || !token.Valid {
 		return nil, err
@@ -57,7 +57,14 @@ func ValidateToken(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
-// AuthMiddleware validates JWT tokens and sets user context
+// SessionID returns the session a token belongs to
+func (c *Claims) SessionID() (uuid.UUID, error) {
+	return uuid.Parse(c.ID)
+}
+
+// AuthMiddleware validates JWT tokens and sets user context. The token's
+// session must still exist, so logging out or revoking a session rejects
+// its token before the token itself expires.
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
@@ -82,23 +89,59 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Load user from database
-		var user models.User
-		if err := database.DB.First(&user, "id = ?", claims.UserID).Error; err != nil {
-			utils.Unauthorized(c, "User not found")
+		sessionID, err := claims.SessionID()
+		if err != nil {
+			utils.Unauthorized(c, "Invalid or expired token")
+			c.Abort()
+			return
+		}
+
+		user, err := sessionUser(sessionID, claims.UserID)
+		if err != nil {
+			utils.Unauthorized(c, "Session has ended, please log in again")
 			c.Abort()
 			return
 		}
 
 		// Set user in context
-		c.Set("user", &user)
+		c.Set("user", user)
 		c.Set("user_id", user.ID)
 		c.Set("company_id", user.CompanyID)
+		c.Set("session_id", sessionID)
 
 		c.Next()
 	}
 }
 
+// sessionUser returns the user of an active session, from the cache when
+// the session was verified recently
+func sessionUser(sessionID, userID uuid.UUID) (*models.User, error) {
+	now := time.Now()
+	if user, ok := sessions.get(sessionID, now); ok && user.ID == userID {
+		return user, nil
+	}
+
+	var session models.Session
+	if err := database.DB.Where("id = ? AND user_id = ? AND expires_at > ?", sessionID, userID, now).
+		First(&session).Error; err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+
+	database.DB.Model(&session).UpdateColumn("last_used_at", now)
+
+	cacheUntil := now.Add(config.AppConfig.SessionCacheTTL)
+	if session.ExpiresAt.Before(cacheUntil) {
+		cacheUntil = session.ExpiresAt
+	}
+	sessions.put(sessionID, &user, cacheUntil)
+	return &user, nil
+}
+
 // GetCurrentUser retrieves the authenticated user from context
 func GetCurrentUser(c *gin.Context) *models.User {
 	user, exists := c.Get("user")
@@ -108,6 +151,15 @@ func GetCurrentUser(c *gin.Context) *models.User {
 	return user.(*models.User)
 }
 
+// GetSessionID retrieves the current session's ID from context
+func GetSessionID(c *gin.Context) uuid.UUID {
+	sessionID, exists := c.Get("session_id")
+	if !exists {
+		return uuid.Nil
+	}
+	return sessionID.(uuid.UUID)
+}
+
 // GetCompanyID retrieves the company ID from context
 func GetCompanyID(c *gin.Context) uuid.UUID {
 	companyID, exists := c.Get("company_id")
@@ -2,11 +2,18 @@ package middleware
 
 import (
 	"log"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// credentialParams names, per route, the path parameter that carries a
+// credential, e.g. the calendar feed token, so it stays out of the logs
+var credentialParams = map[string]string{
+	"/api/calendar/:file": "file",
+}
+
 // LoggerMiddleware logs HTTP requests
 func LoggerMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -16,6 +23,12 @@ func LoggerMiddleware() gin.HandlerFunc {
 
 		c.Next()
 
+		if param, ok := credentialParams[c.FullPath()]; ok {
+			if value := c.Param(param); value != "" {
+				path = strings.Replace(path, value, "[REDACTED]", 1)
+			}
+		}
+
 		endTime := time.Now()
 		latency := endTime.Sub(startTime)
 		statusCode := c.Writer.Status()
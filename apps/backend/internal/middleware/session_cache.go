@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dhani/bill-tracker-backend/internal/models"
+)
+
+// maxCachedSessions bounds the cache; it is cleared when full
+const maxCachedSessions = 10000
+
+// sessionCache remembers recently verified sessions with their user so that
+// most requests need no database round trip. Revocations made by this
+// process take effect immediately; other replicas notice them once their
+// entry expires, which bounds how long a revoked token keeps working.
+type sessionCache struct {
+	mu      sync.Mutex
+	entries map[uuid.UUID]sessionCacheEntry
+}
+
+type sessionCacheEntry struct {
+	user      models.User
+	expiresAt time.Time
+}
+
+var sessions = &sessionCache{entries: make(map[uuid.UUID]sessionCacheEntry)}
+
+func (c *sessionCache) get(sessionID uuid.UUID, now time.Time) (*models.User, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[sessionID]
+	if !ok || now.After(entry.expiresAt) {
+		delete(c.entries, sessionID)
+		return nil, false
+	}
+	// Handlers get their own copy to modify
+	user := entry.user
+	return &user, true
+}
+
+func (c *sessionCache) put(sessionID uuid.UUID, user *models.User, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= maxCachedSessions {
+		c.entries = make(map[uuid.UUID]sessionCacheEntry)
+	}
+	c.entries[sessionID] = sessionCacheEntry{user: *user, expiresAt: expiresAt}
+}
+
+// ForgetSession drops a revoked session from this process's cache
+func ForgetSession(sessionID uuid.UUID) {
+	sessions.mu.Lock()
+	defer sessions.mu.Unlock()
+	delete(sessions.entries, sessionID)
+}
+
+// ForgetUserSessions drops a user's sessions from this process's cache,
+// except the one given, e.g. after logging out other devices
+func ForgetUserSessions(userID uuid.UUID, except uuid.UUID) {
+	sessions.mu.Lock()
+	defer sessions.mu.Unlock()
+	for id, entry := range sessions.entries {
+		if entry.user.ID == userID && id != except {
+			delete(sessions.entries, id)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,20 +16,60 @@ const (
 	ActionCreated             ActivityAction = "created"
 	ActionUpdated             ActivityAction = "updated"
 	ActionStatusChanged       ActivityAction = "status_changed"
+	ActionPaymentRecorded     ActivityAction = "payment_recorded"
+	ActionApproved            ActivityAction = "approved"
+	ActionRejected            ActivityAction = "rejected"
 	ActionPaymentReminderSent ActivityAction = "payment_reminder_sent"
 	ActionAttachmentAdded     ActivityAction = "attachment_added"
 	ActionAttachmentRemoved   ActivityAction = "attachment_removed"
 	ActionDeleted             ActivityAction = "deleted"
 )
 
+// FieldChange records the old and new value of a single bill column
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// FieldChanges is a structured diff stored as JSON on an activity
+type FieldChanges []FieldChange
+
+func (c FieldChanges) Value() (driver.Value, error) {
+	if len(c) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (c *FieldChanges) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	}
+	return errors.New("unsupported type for FieldChanges")
+}
+
 // BillActivity represents an activity log entry for a bill
 type BillActivity struct {
-	ID        uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	BillID    uuid.UUID      `gorm:"type:uuid;not null;index" json:"bill_id"`
-	UserID    *uuid.UUID     `gorm:"type:uuid;index" json:"user_id"`
-	Action    ActivityAction `gorm:"type:varchar(100);not null" json:"action"`
-	Details   *string        `gorm:"type:text" json:"details"`
-	CreatedAt time.Time      `json:"created_at"`
+	ID         uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	BillID     uuid.UUID      `gorm:"type:uuid;not null;index" json:"bill_id"`
+	UserID     *uuid.UUID     `gorm:"type:uuid;index" json:"user_id"`
+	Action     ActivityAction `gorm:"type:varchar(100);not null" json:"action"`
+	Details    *string        `gorm:"type:text" json:"details"`
+	FromStatus *BillStatus    `gorm:"type:varchar(20)" json:"from_status,omitempty"`
+	ToStatus   *BillStatus    `gorm:"type:varchar(20)" json:"to_status,omitempty"`
+	Changes    FieldChanges   `gorm:"type:jsonb" json:"changes,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
 
 	// Relations
 	Bill Bill  `gorm:"foreignKey:BillID" json:"-"`
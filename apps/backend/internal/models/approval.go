@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type ApprovalDecision string
+
+const (
+	DecisionApproved ApprovalDecision = "approved"
+	DecisionRejected ApprovalDecision = "rejected"
+)
+
+// ApprovalPolicy requires sign-off for bills above an amount threshold, in
+// the company's base currency
+type ApprovalPolicy struct {
+	ID                uuid.UUID       `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	CompanyID         uuid.UUID       `gorm:"type:uuid;not null;index" json:"company_id"`
+	AmountAbove       decimal.Decimal `gorm:"type:decimal(15,2);not null" json:"amount_above"`
+	RequiredApprovals int             `gorm:"not null;default:1" json:"required_approvals"`
+	ApproverRole      UserRole        `gorm:"type:varchar(50);not null;default:'admin'" json:"approver_role"`
+	CreatedAt         time.Time       `json:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at"`
+
+	// Relations
+	Company Company `gorm:"foreignKey:CompanyID" json:"-"`
+}
+
+func (p *ApprovalPolicy) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// BillApproval is a single approve/reject decision on a bill
+type BillApproval struct {
+	ID        uuid.UUID        `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	BillID    uuid.UUID        `gorm:"type:uuid;not null;uniqueIndex:idx_bill_approvals_round_user" json:"bill_id"`
+	Round     int              `gorm:"not null;uniqueIndex:idx_bill_approvals_round_user" json:"round"`
+	UserID    uuid.UUID        `gorm:"type:uuid;not null;uniqueIndex:idx_bill_approvals_round_user" json:"user_id"`
+	Decision  ApprovalDecision `gorm:"type:varchar(20);not null" json:"decision"`
+	Comment   *string          `gorm:"type:text" json:"comment"`
+	CreatedAt time.Time        `json:"created_at"`
+
+	// Relations
+	Bill Bill  `gorm:"foreignKey:BillID" json:"-"`
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+func (a *BillApproval) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
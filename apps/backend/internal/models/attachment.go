@@ -9,16 +9,24 @@ import (
 
 // BillAttachment represents an invoice or document attachment
 type BillAttachment struct {
-	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	BillID     uuid.UUID `gorm:"type:uuid;not null;index" json:"bill_id"`
-	Filename   string    `gorm:"type:varchar(255);not null" json:"filename"`
-	FileURL    string    `gorm:"type:text;not null" json:"file_url"`
-	FileType   string    `gorm:"type:varchar(50);not null" json:"file_type"`
-	FileSize   int64     `gorm:"not null" json:"file_size"`
-	UploadedAt time.Time `json:"uploaded_at"`
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	BillID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"bill_id"`
+	Filename   string     `gorm:"type:varchar(255);not null" json:"filename"`
+	FileURL    string     `gorm:"type:text;not null" json:"file_url"`
+	FileType   string     `gorm:"type:varchar(255);not null" json:"file_type"`
+	FileSize   int64      `gorm:"not null" json:"file_size"`
+	SHA256     string     `gorm:"column:sha256;type:char(64);index" json:"sha256"`
+	StorageKey string     `gorm:"type:varchar(500);not null;default:''" json:"-"`
+	UploadedBy *uuid.UUID `gorm:"type:uuid" json:"uploaded_by"`
+	UploadedAt time.Time  `json:"uploaded_at"`
+
+	// DownloadURL is a signed, expiring link issued per response; it is never stored
+	DownloadURL          string     `gorm:"-" json:"download_url,omitempty"`
+	DownloadURLExpiresAt *time.Time `gorm:"-" json:"download_url_expires_at,omitempty"`
 
 	// Relations
-	Bill Bill `gorm:"foreignKey:BillID" json:"-"`
+	Bill     Bill  `gorm:"foreignKey:BillID" json:"-"`
+	Uploader *User `gorm:"foreignKey:UploadedBy" json:"uploader,omitempty"`
 }
 
 func (a *BillAttachment) BeforeCreate(tx *gorm.DB) error {
@@ -11,12 +11,40 @@ import (
 type BillStatus string
 
 const (
-	StatusDraft   BillStatus = "draft"
-	StatusUnpaid  BillStatus = "unpaid"
-	StatusPaid    BillStatus = "paid"
-	StatusOverdue BillStatus = "overdue"
+	StatusDraft           BillStatus = "draft"
+	StatusPendingApproval BillStatus = "pending_approval"
+	StatusUnpaid          BillStatus = "unpaid"
+	StatusPartiallyPaid   BillStatus = "partially_paid"
+	StatusPaid            BillStatus = "paid"
+	StatusOverdue         BillStatus = "overdue"
 )
 
+// billTransitions lists the statuses each status may move to
+var billTransitions = map[BillStatus][]BillStatus{
+	StatusDraft:           {StatusUnpaid, StatusPendingApproval},
+	StatusPendingApproval: {StatusUnpaid, StatusDraft},
+	StatusUnpaid:          {StatusDraft, StatusPendingApproval, StatusPartiallyPaid, StatusPaid, StatusOverdue},
+	StatusPartiallyPaid:   {StatusPaid, StatusOverdue},
+	StatusOverdue:         {StatusUnpaid, StatusPendingApproval, StatusPartiallyPaid, StatusPaid},
+	StatusPaid:            {},
+}
+
+// IsValid reports whether the status is a known bill status
+func (s BillStatus) IsValid() bool {
+	_, ok := billTransitions[s]
+	return ok
+}
+
+// CanTransitionTo reports whether a bill may move from s to the target status
+func (s BillStatus) CanTransitionTo(target BillStatus) bool {
+	for _, allowed := range billTransitions[s] {
+		if allowed == target {
+			return true
+		}
+	}
+	return false
+}
+
 type RecurringFrequency string
 
 const (
@@ -28,17 +56,20 @@ const (
 // Bill represents a payable bill
 type Bill struct {
 	ID                 uuid.UUID           `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	CompanyID          uuid.UUID           `gorm:"type:uuid;not null;index" json:"company_id"`
+	CompanyID          uuid.UUID           `gorm:"type:uuid;not null;index;uniqueIndex:idx_bills_company_vendor_invoice,priority:1,where:deleted_at IS NULL" json:"company_id"`
 	UserID             uuid.UUID           `gorm:"type:uuid;not null;index" json:"user_id"`
-	VendorID           *uuid.UUID          `gorm:"type:uuid;index" json:"vendor_id"`
+	ParentBillID       *uuid.UUID          `gorm:"type:uuid;index;uniqueIndex:idx_bills_series_due_date" json:"parent_bill_id"`
+	VendorID           *uuid.UUID          `gorm:"type:uuid;index;uniqueIndex:idx_bills_company_vendor_invoice,priority:2" json:"vendor_id"`
 	CategoryID         *uuid.UUID          `gorm:"type:uuid;index" json:"category_id"`
 	Title              string              `gorm:"type:varchar(255);not null" json:"title"`
-	InvoiceNumber      *string             `gorm:"type:varchar(100);uniqueIndex" json:"invoice_number"`
+	InvoiceNumber      *string             `gorm:"type:varchar(100);uniqueIndex:idx_bills_company_vendor_invoice,priority:3" json:"invoice_number"`
 	Amount             decimal.Decimal     `gorm:"type:decimal(15,2);not null" json:"amount"`
+	AmountPaid         decimal.Decimal     `gorm:"type:decimal(15,2);not null;default:0" json:"amount_paid"`
 	Currency           string              `gorm:"type:varchar(10);default:'USD'" json:"currency"`
-	DueDate            time.Time           `gorm:"type:date;not null" json:"due_date"`
+	DueDate            time.Time           `gorm:"type:date;not null;uniqueIndex:idx_bills_series_due_date" json:"due_date"`
 	PaidDate           *time.Time          `gorm:"type:date" json:"paid_date"`
 	Status             BillStatus          `gorm:"type:varchar(20);default:'draft'" json:"status"`
+	ApprovalRound      int                 `gorm:"not null;default:0" json:"approval_round"`
 	IsRecurring        bool                `gorm:"default:false" json:"is_recurring"`
 	RecurringFrequency *RecurringFrequency `gorm:"type:varchar(20)" json:"recurring_frequency"`
 	RecurringDay       *int                `json:"recurring_day"`
@@ -48,13 +79,20 @@ type Bill struct {
 	UpdatedAt          time.Time           `json:"updated_at"`
 	DeletedAt          gorm.DeletedAt      `gorm:"index" json:"-"`
 
+	// Computed
+	OutstandingBalance decimal.Decimal `gorm:"-" json:"outstanding_balance"`
+
 	// Relations
 	Company     Company          `gorm:"foreignKey:CompanyID" json:"-"`
+	ParentBill  *Bill            `gorm:"foreignKey:ParentBillID" json:"-"`
 	User        User             `gorm:"foreignKey:UserID" json:"user,omitempty"`
 	Vendor      *Vendor          `gorm:"foreignKey:VendorID" json:"vendor,omitempty"`
 	Category    *Category        `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
 	Attachments []BillAttachment `gorm:"foreignKey:BillID" json:"attachments,omitempty"`
 	Activities  []BillActivity   `gorm:"foreignKey:BillID" json:"activities,omitempty"`
+	Payments    []Payment        `gorm:"foreignKey:BillID" json:"payments,omitempty"`
+	Approvals   []BillApproval   `gorm:"foreignKey:BillID" json:"approvals,omitempty"`
+	LineItems   []BillLineItem   `gorm:"foreignKey:BillID" json:"line_items,omitempty"`
 }
 
 func (b *Bill) BeforeCreate(tx *gorm.DB) error {
@@ -64,6 +102,24 @@ func (b *Bill) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+func (b *Bill) AfterFind(tx *gorm.DB) error {
+	b.OutstandingBalance = b.Outstanding()
+	return nil
+}
+
+// Outstanding returns the amount still owed on the bill
+func (b *Bill) Outstanding() decimal.Decimal {
+	return b.Amount.Sub(b.AmountPaid)
+}
+
+// SeriesID returns the ID of the bill that started this recurring series
+func (b *Bill) SeriesID() uuid.UUID {
+	if b.ParentBillID != nil {
+		return *b.ParentBillID
+	}
+	return b.ID
+}
+
 // IsOverdue checks if the bill is overdue
 func (b *Bill) IsOverdue() bool {
 	if b.Status == StatusPaid {
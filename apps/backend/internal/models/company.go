@@ -1,19 +1,58 @@
 package models
 
 import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// EmailVerificationPolicy is what a company's users cannot do before
+// verifying their email address
+type EmailVerificationPolicy string
+
+const (
+	// VerificationOff lets unverified users do everything
+	VerificationOff EmailVerificationPolicy = "off"
+	// VerificationBills stops unverified users from creating bills
+	VerificationBills EmailVerificationPolicy = "bills"
+	// VerificationLogin stops unverified users from logging in at all
+	VerificationLogin EmailVerificationPolicy = "login"
+)
+
+// IsValid reports whether p is a known policy
+func (p EmailVerificationPolicy) IsValid() bool {
+	return p == VerificationOff || p == VerificationBills || p == VerificationLogin
+}
+
+// BlocksLogin reports whether unverified users may not log in
+func (p EmailVerificationPolicy) BlocksLogin() bool {
+	return p == VerificationLogin
+}
+
+// BlocksBillCreation reports whether unverified users may not create bills;
+// sessions from before login was blocked are covered as well
+func (p EmailVerificationPolicy) BlocksBillCreation() bool {
+	return p == VerificationBills || p == VerificationLogin
+}
+
 // Company represents a tenant/organization
 type Company struct {
-	ID        uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	Name      string         `gorm:"type:varchar(255);not null" json:"name"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                uuid.UUID               `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Name              string                  `gorm:"type:varchar(255);not null" json:"name"`
+	Timezone          string                  `gorm:"type:varchar(64);not null;default:'UTC'" json:"timezone"`
+	BaseCurrency      string                  `gorm:"type:varchar(3);not null;default:'USD'" json:"base_currency"`
+	InboundToken      *string                 `gorm:"type:varchar(32);uniqueIndex" json:"-"`
+	EmailVerification EmailVerificationPolicy `gorm:"type:varchar(20);not null;default:'off'" json:"email_verification"`
+	CreatedAt         time.Time               `json:"created_at"`
+	UpdatedAt         time.Time               `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt          `gorm:"index" json:"-"`
+
+	// InboundEmail is the address forwarded mail is received at; see InboundAddress
+	InboundEmail string `gorm:"-" json:"inbound_email,omitempty"`
 
 	// Relations
 	Users      []User     `gorm:"foreignKey:CompanyID" json:"-"`
@@ -26,5 +65,46 @@ func (c *Company) BeforeCreate(tx *gorm.DB) error {
 	if c.ID == uuid.Nil {
 		c.ID = uuid.New()
 	}
+	if c.Timezone == "" {
+		c.Timezone = "UTC"
+	}
+	if c.BaseCurrency == "" {
+		c.BaseCurrency = "USD"
+	}
+	if c.EmailVerification == "" {
+		c.EmailVerification = VerificationOff
+	}
+	if c.InboundToken == nil {
+		token := NewInboundToken()
+		c.InboundToken = &token
+	}
 	return nil
 }
+
+// NewInboundToken returns a random token identifying a company's inbound
+// email address. It is lower case because mail systems may fold local parts.
+func NewInboundToken() string {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))
+}
+
+// InboundAddress returns the company's email-to-bill address, e.g.
+// bills+k3j9x2m4q8r7t6v5@example.com
+func (c *Company) InboundAddress(prefix, domain string) string {
+	if c.InboundToken == nil {
+		return ""
+	}
+	return prefix + "+" + *c.InboundToken + "@" + domain
+}
+
+// Location returns the company's time zone, falling back to UTC
+func (c *Company) Location() *time.Location {
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil || c.Timezone == "" {
+		return time.UTC
+	}
+	return loc
+}
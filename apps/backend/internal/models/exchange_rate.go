@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ExchangeRate stores how many units of Quote one unit of Base buys from EffectiveDate on
+type ExchangeRate struct {
+	ID            uuid.UUID       `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	CompanyID     uuid.UUID       `gorm:"type:uuid;not null;uniqueIndex:idx_exchange_rates_pair_date" json:"company_id"`
+	Base          string          `gorm:"type:varchar(3);not null;uniqueIndex:idx_exchange_rates_pair_date" json:"base"`
+	Quote         string          `gorm:"type:varchar(3);not null;uniqueIndex:idx_exchange_rates_pair_date" json:"quote"`
+	EffectiveDate time.Time       `gorm:"type:date;not null;uniqueIndex:idx_exchange_rates_pair_date" json:"effective_date"`
+	Rate          decimal.Decimal `gorm:"type:decimal(24,10);not null" json:"rate"`
+	Source        *string         `gorm:"type:varchar(50)" json:"source"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+
+	// Relations
+	Company Company `gorm:"foreignKey:CompanyID" json:"-"`
+}
+
+func (r *ExchangeRate) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// BillLineItem is a single line of an invoice, allocatable to its own category
+type BillLineItem struct {
+	ID          uuid.UUID       `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	BillID      uuid.UUID       `gorm:"type:uuid;not null;index" json:"bill_id"`
+	CategoryID  *uuid.UUID      `gorm:"type:uuid;index" json:"category_id"`
+	Position    int             `gorm:"not null;default:0" json:"position"`
+	Description string          `gorm:"type:varchar(500);not null" json:"description"`
+	Quantity    decimal.Decimal `gorm:"type:decimal(15,4);not null;default:1" json:"quantity"`
+	UnitPrice   decimal.Decimal `gorm:"type:decimal(15,2);not null" json:"unit_price"`
+	TaxAmount   decimal.Decimal `gorm:"type:decimal(15,2);not null;default:0" json:"tax_amount"`
+	Total       decimal.Decimal `gorm:"type:decimal(15,2);not null" json:"total"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+
+	// Relations
+	Bill     Bill      `gorm:"foreignKey:BillID" json:"-"`
+	Category *Category `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
+}
+
+func (l *BillLineItem) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == uuid.Nil {
+		l.ID = uuid.New()
+	}
+	return nil
+}
+
+// ComputeTotal returns quantity * unit price plus tax, rounded to cents
+func (l *BillLineItem) ComputeTotal() decimal.Decimal {
+	return l.Quantity.Mul(l.UnitPrice).Add(l.TaxAmount).Round(2)
+}
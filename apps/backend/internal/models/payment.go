@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Payment represents a single (possibly partial) payment made against a bill
+type Payment struct {
+	ID        uuid.UUID       `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	CompanyID uuid.UUID       `gorm:"type:uuid;not null;index" json:"company_id"`
+	BillID    uuid.UUID       `gorm:"type:uuid;not null;index" json:"bill_id"`
+	UserID    *uuid.UUID      `gorm:"type:uuid;index" json:"user_id"`
+	Amount    decimal.Decimal `gorm:"type:decimal(15,2);not null" json:"amount"`
+	PaidDate  time.Time       `gorm:"type:date;not null;index" json:"paid_date"`
+	Method    *string         `gorm:"type:varchar(100)" json:"method"`
+	Reference *string         `gorm:"type:varchar(255)" json:"reference"`
+	Notes     *string         `gorm:"type:text" json:"notes"`
+	CreatedAt time.Time       `json:"created_at"`
+
+	// Relations
+	Bill Bill  `gorm:"foreignKey:BillID" json:"-"`
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+func (p *Payment) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
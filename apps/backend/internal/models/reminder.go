@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ReminderTrigger string
+
+const (
+	// TriggerBeforeDue fires a number of days before the due date
+	TriggerBeforeDue ReminderTrigger = "before_due"
+	// TriggerOverdue fires a number of days after the due date has passed
+	TriggerOverdue ReminderTrigger = "overdue"
+)
+
+// ReminderRule emails the people responsible for unpaid bills relative to
+// their due date. Empty templates fall back to the built-in ones.
+type ReminderRule struct {
+	ID              uuid.UUID       `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	CompanyID       uuid.UUID       `gorm:"type:uuid;not null;index" json:"company_id"`
+	Name            string          `gorm:"type:varchar(255);not null" json:"name"`
+	Trigger         ReminderTrigger `gorm:"type:varchar(20);not null" json:"trigger"`
+	Days            int             `gorm:"not null;default:0" json:"days"`
+	NotifyAdmins    bool            `gorm:"not null;default:false" json:"notify_admins"`
+	Enabled         bool            `gorm:"not null;default:true" json:"enabled"`
+	SubjectTemplate *string         `gorm:"type:text" json:"subject_template"`
+	TextTemplate    *string         `gorm:"type:text" json:"text_template"`
+	HTMLTemplate    *string         `gorm:"type:text" json:"html_template"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+
+	// Relations
+	Company Company `gorm:"foreignKey:CompanyID" json:"-"`
+}
+
+func (r *ReminderRule) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// ReminderDelivery records that a rule has reminded about a bill, so each
+// reminder is sent once per bill and rule. When no recipient could be
+// reached, RetryAt marks the delivery for another attempt; after the last
+// attempt it stays without RetryAt and Error tells why it failed.
+type ReminderDelivery struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	RuleID     uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_reminder_deliveries_rule_bill" json:"rule_id"`
+	BillID     uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_reminder_deliveries_rule_bill;index" json:"bill_id"`
+	Recipients string     `gorm:"type:text;not null" json:"recipients"`
+	SentAt     time.Time  `gorm:"not null" json:"sent_at"`
+	Attempts   int        `gorm:"not null;default:1" json:"attempts"`
+	RetryAt    *time.Time `json:"retry_at"`
+	Error      *string    `gorm:"type:text" json:"error"`
+
+	// Relations
+	Rule ReminderRule `gorm:"foreignKey:RuleID;constraint:OnDelete:CASCADE" json:"-"`
+	Bill Bill         `gorm:"foreignKey:BillID" json:"-"`
+}
+
+func (d *ReminderDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
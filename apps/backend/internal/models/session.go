@@ -7,20 +7,49 @@ import (
 	"gorm.io/gorm"
 )
 
-// Session represents an authentication session
+// Sign-in methods a session can start with; sign-in with an external
+// identity provider records the provider's name, e.g. "google"
+const (
+	SignInPassword = "password"
+	SignInSSO      = "sso"
+)
+
+// Session represents an authentication session. Its ID is the jti of the
+// access tokens issued for it; deleting the row revokes them along with the
+// session's refresh token. A session is one refresh token family: each
+// refresh rotates the token, and presenting a rotated one revokes the session.
 type Session struct {
-	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	UserID    uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
-	Token     string    `gorm:"type:varchar(512);uniqueIndex;not null" json:"-"`
-	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
-	IPAddress *string   `gorm:"type:varchar(45)" json:"ip_address"`
-	UserAgent *string   `gorm:"type:text" json:"user_agent"`
-	CreatedAt time.Time `json:"created_at"`
+	ID                  uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID              uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	RefreshTokenHash    *string    `gorm:"type:varchar(64);uniqueIndex" json:"-"`
+	PreviousRefreshHash *string    `gorm:"type:varchar(64)" json:"-"`
+	RefreshedAt         *time.Time `json:"refreshed_at"`
+	ExpiresAt           time.Time  `gorm:"not null;index" json:"expires_at"`
+	IPAddress           *string    `gorm:"type:varchar(45)" json:"ip_address"`
+	UserAgent           *string    `gorm:"type:text" json:"user_agent"`
+	SignInMethod        *string    `gorm:"type:varchar(50)" json:"sign_in_method"`
+	LastUsedAt          *time.Time `json:"last_used_at"`
+	CreatedAt           time.Time  `json:"created_at"`
+
+	// Current marks the session the request was made with
+	Current bool `gorm:"-" json:"current"`
 
 	// Relations
 	User User `gorm:"foreignKey:UserID" json:"-"`
 }
 
+// UsedRefreshToken is the hash of a refresh token a session was rotated away
+// from. Only these revoke the session when presented again; an unknown secret
+// is just invalid, so knowing a session ID is not enough to end it.
+type UsedRefreshToken struct {
+	Hash      string    `gorm:"type:varchar(64);primary_key"`
+	SessionID uuid.UUID `gorm:"type:uuid;not null;index"`
+	CreatedAt time.Time
+
+	// Relations
+	Session Session `gorm:"foreignKey:SessionID;constraint:OnDelete:CASCADE"`
+}
+
 func (s *Session) BeforeCreate(tx *gorm.DB) error {
 	if s.ID == uuid.Nil {
 		s.ID = uuid.New()
@@ -18,6 +18,7 @@ type Vendor struct {
 	ContactInfo  *string        `gorm:"type:text" json:"contact_info"`
 	Address      *string        `gorm:"type:text" json:"address"`
 	Location     *string        `gorm:"type:varchar(255)" json:"location"`
+	TaxID        *string        `gorm:"type:varchar(50);index" json:"tax_id"`
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type WebhookEvent string
+
+const (
+	EventBillCreated         WebhookEvent = "bill.created"
+	EventBillUpdated         WebhookEvent = "bill.updated"
+	EventBillStatusChanged   WebhookEvent = "bill.status_changed"
+	EventBillPaymentRecorded WebhookEvent = "bill.payment_recorded"
+	EventBillPaid            WebhookEvent = "bill.paid"
+	EventBillDeleted         WebhookEvent = "bill.deleted"
+)
+
+// WebhookEvents lists every event a webhook can subscribe to
+var WebhookEvents = []WebhookEvent{
+	EventBillCreated,
+	EventBillUpdated,
+	EventBillStatusChanged,
+	EventBillPaymentRecorded,
+	EventBillPaid,
+	EventBillDeleted,
+}
+
+// IsValid reports whether the event is a known webhook event
+func (e WebhookEvent) IsValid() bool {
+	for _, event := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEventList is a set of subscribed events stored as JSON
+type WebhookEventList []WebhookEvent
+
+func (l WebhookEventList) Value() (driver.Value, error) {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (l *WebhookEventList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, l)
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	}
+	return errors.New("unsupported type for WebhookEventList")
+}
+
+// Contains reports whether the list subscribes to event
+func (l WebhookEventList) Contains(event WebhookEvent) bool {
+	for _, e := range l {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// RawJSON is a JSON document stored as is and embedded unescaped in API output
+type RawJSON string
+
+func (j RawJSON) MarshalJSON() ([]byte, error) {
+	if j == "" {
+		return []byte("null"), nil
+	}
+	return []byte(j), nil
+}
+
+// Webhook posts signed bill events to a company's endpoint. Webhooks that
+// keep failing are disabled until they are re-enabled by an admin.
+type Webhook struct {
+	ID                  uuid.UUID        `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	CompanyID           uuid.UUID        `gorm:"type:uuid;not null;index" json:"company_id"`
+	URL                 string           `gorm:"type:text;not null" json:"url"`
+	Secret              string           `gorm:"type:varchar(255);not null" json:"secret"`
+	Events              WebhookEventList `gorm:"type:jsonb;not null" json:"events"`
+	Description         *string          `gorm:"type:text" json:"description"`
+	Enabled             bool             `gorm:"not null;default:true" json:"enabled"`
+	ConsecutiveFailures int              `gorm:"not null;default:0" json:"consecutive_failures"`
+	DisabledAt          *time.Time       `json:"disabled_at"`
+	DisabledReason      *string          `gorm:"type:text" json:"disabled_reason"`
+	CreatedAt           time.Time        `json:"created_at"`
+	UpdatedAt           time.Time        `json:"updated_at"`
+
+	// Relations
+	Company Company `gorm:"foreignKey:CompanyID" json:"-"`
+}
+
+func (w *Webhook) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == uuid.Nil {
+		w.ID = uuid.New()
+	}
+	return nil
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	DeliveryPending   WebhookDeliveryStatus = "pending"
+	DeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	DeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is one event queued for, and logged against, a webhook.
+// All deliveries of the same event share its EventID, including manual
+// redeliveries, so receivers can deduplicate.
+type WebhookDelivery struct {
+	ID             uuid.UUID             `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	WebhookID      uuid.UUID             `gorm:"type:uuid;not null;index" json:"webhook_id"`
+	EventID        uuid.UUID             `gorm:"type:uuid;not null;index" json:"event_id"`
+	Event          WebhookEvent          `gorm:"type:varchar(50);not null" json:"event"`
+	Payload        RawJSON               `gorm:"type:jsonb;not null" json:"payload"`
+	Status         WebhookDeliveryStatus `gorm:"type:varchar(20);not null;default:'pending';index:idx_webhook_deliveries_queue,priority:1" json:"status"`
+	Attempts       int                   `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  *time.Time            `gorm:"index:idx_webhook_deliveries_queue,priority:2" json:"next_attempt_at"`
+	LastAttemptAt  *time.Time            `json:"last_attempt_at"`
+	ResponseStatus *int                  `json:"response_status"`
+	ResponseBody   *string               `gorm:"type:text" json:"response_body"`
+	Error          *string               `gorm:"type:text" json:"error"`
+	DurationMs     *int64                `json:"duration_ms"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+
+	// Relations
+	Webhook Webhook `gorm:"foreignKey:WebhookID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
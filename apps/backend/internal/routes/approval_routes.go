@@ -0,0 +1,157 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/dhani/bill-tracker-backend/internal/middleware"
+	"github.com/dhani/bill-tracker-backend/internal/models"
+	"github.com/dhani/bill-tracker-backend/internal/services"
+	"github.com/dhani/bill-tracker-backend/internal/utils"
+)
+
+type ApprovalHandler struct {
+	service *services.ApprovalService
+}
+
+func NewApprovalHandler(service *services.ApprovalService) *ApprovalHandler {
+	return &ApprovalHandler{service: service}
+}
+
+// ListPolicies retrieves the company's approval policies
+// GET /api/approval-policies
+func (h *ApprovalHandler) ListPolicies(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	policies, err := h.service.ListPolicies(companyID)
+	if err != nil {
+		utils.InternalError(c, "Failed to fetch approval policies")
+		return
+	}
+
+	utils.Success(c, "", policies)
+}
+
+// CreatePolicy creates an approval policy
+// POST /api/approval-policies
+func (h *ApprovalHandler) CreatePolicy(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	var input services.ApprovalPolicyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	policy, err := h.service.CreatePolicy(companyID, input)
+	if err != nil {
+		respondBillError(c, err)
+		return
+	}
+
+	utils.Created(c, "Approval policy created successfully", policy)
+}
+
+// UpdatePolicy updates an approval policy
+// PUT /api/approval-policies/:id
+func (h *ApprovalHandler) UpdatePolicy(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	policyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid policy ID")
+		return
+	}
+
+	var input services.ApprovalPolicyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	policy, err := h.service.UpdatePolicy(companyID, policyID, input)
+	if err != nil {
+		respondBillError(c, err)
+		return
+	}
+
+	utils.Success(c, "Approval policy updated successfully", policy)
+}
+
+// DeletePolicy deletes an approval policy
+// DELETE /api/approval-policies/:id
+func (h *ApprovalHandler) DeletePolicy(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	policyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid policy ID")
+		return
+	}
+
+	if err := h.service.DeletePolicy(companyID, policyID); err != nil {
+		respondBillError(c, err)
+		return
+	}
+
+	utils.Success(c, "Approval policy deleted successfully", nil)
+}
+
+// ListDecisions retrieves the approval history of a bill
+// GET /api/bills/:id/approvals
+func (h *ApprovalHandler) ListDecisions(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	billID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid bill ID")
+		return
+	}
+
+	approvals, err := h.service.ListDecisions(companyID, billID)
+	if err != nil {
+		utils.InternalError(c, "Failed to fetch approvals")
+		return
+	}
+
+	utils.Success(c, "", approvals)
+}
+
+// Approve approves a bill pending review
+// POST /api/bills/:id/approve
+func (h *ApprovalHandler) Approve(c *gin.Context) {
+	h.decide(c, h.service.Approve, "Bill approved")
+}
+
+// Reject rejects a bill pending review
+// POST /api/bills/:id/reject
+func (h *ApprovalHandler) Reject(c *gin.Context) {
+	h.decide(c, h.service.Reject, "Bill rejected")
+}
+
+type decisionFunc func(companyID, billID uuid.UUID, user *models.User, input services.ApprovalDecisionInput) (*models.BillApproval, error)
+
+func (h *ApprovalHandler) decide(c *gin.Context, decide decisionFunc, message string) {
+	companyID := middleware.GetCompanyID(c)
+	user := middleware.GetCurrentUser(c)
+
+	billID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid bill ID")
+		return
+	}
+
+	var input services.ApprovalDecisionInput
+	if err := c.ShouldBindJSON(&input); err != nil && c.Request.ContentLength > 0 {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	approval, err := decide(companyID, billID, user, input)
+	if err != nil {
+		respondBillError(c, err)
+		return
+	}
+
+	utils.Success(c, message, approval)
+}
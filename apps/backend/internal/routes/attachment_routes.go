@@ -0,0 +1,222 @@
+package routes
+
+import (
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/dhani/bill-tracker-backend/internal/middleware"
+	"github.com/dhani/bill-tracker-backend/internal/models"
+	"github.com/dhani/bill-tracker-backend/internal/services"
+	"github.com/dhani/bill-tracker-backend/internal/utils"
+)
+
+// multipartOverhead allows for multipart boundaries and headers on top of the
+// file itself when limiting the request body
+const multipartOverhead = 1 << 20
+
+type AttachmentHandler struct {
+	service *services.AttachmentService
+	maxSize int64
+}
+
+func NewAttachmentHandler(service *services.AttachmentService, maxSize int64) *AttachmentHandler {
+	return &AttachmentHandler{service: service, maxSize: maxSize}
+}
+
+// List returns the attachments of a bill
+// GET /api/bills/:id/attachments
+func (h *AttachmentHandler) List(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	billID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid bill ID")
+		return
+	}
+
+	attachments, err := h.service.List(companyID, billID)
+	if err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+
+	utils.Success(c, "Attachments retrieved successfully", attachments)
+}
+
+// Upload attaches a file sent as multipart form field "file" to a bill
+// POST /api/bills/:id/attachments
+func (h *AttachmentHandler) Upload(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	user := middleware.GetCurrentUser(c)
+
+	billID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid bill ID")
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxSize+multipartOverhead)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.Error(c, http.StatusRequestEntityTooLarge, services.ErrAttachmentTooLarge.Error())
+			return
+		}
+		utils.BadRequest(c, "A file is required in the 'file' field")
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.BadRequest(c, "Failed to read uploaded file")
+		return
+	}
+	defer file.Close()
+
+	attachment, created, err := h.service.Upload(c.Request.Context(), companyID, billID, user.ID, fileHeader.Filename, file)
+	if err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+
+	if !created {
+		utils.Success(c, "File is already attached to this bill", attachment)
+		return
+	}
+	utils.Created(c, "Attachment uploaded successfully", attachment)
+}
+
+// Download streams the content of an attachment
+// GET /api/bills/:id/attachments/:attachmentId/download
+func (h *AttachmentHandler) Download(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	billID, attachmentID, ok := attachmentParams(c)
+	if !ok {
+		return
+	}
+
+	attachment, content, err := h.service.Open(c.Request.Context(), companyID, billID, attachmentID)
+	if err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+	defer content.Close()
+
+	serveAttachment(c, attachment, content, "attachment")
+}
+
+// SignedDownload streams an attachment through a signed, expiring link so the
+// dashboard can embed previews without a bearer token. Pass
+// disposition=inline to display the file instead of downloading it.
+// GET /api/attachments/:attachmentId/download?company=&expires=&signature=
+func (h *AttachmentHandler) SignedDownload(c *gin.Context) {
+	attachmentID, err := uuid.Parse(c.Param("attachmentId"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid attachment ID")
+		return
+	}
+	companyID, err := uuid.Parse(c.Query("company"))
+	if err != nil {
+		utils.Forbidden(c, services.ErrInvalidSignature.Error())
+		return
+	}
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil {
+		utils.Forbidden(c, services.ErrInvalidSignature.Error())
+		return
+	}
+
+	attachment, content, err := h.service.OpenSigned(c.Request.Context(), companyID, attachmentID, expires, c.Query("signature"))
+	if err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+	defer content.Close()
+
+	disposition := "attachment"
+	if c.Query("disposition") == "inline" {
+		disposition = "inline"
+	}
+	// The link itself is the credential; keep it out of shared caches and referrers
+	c.Header("Cache-Control", "private, max-age="+strconv.FormatInt(max(expires-time.Now().Unix(), 0), 10))
+	c.Header("Referrer-Policy", "no-referrer")
+	serveAttachment(c, attachment, content, disposition)
+}
+
+// Delete removes an attachment from a bill
+// DELETE /api/bills/:id/attachments/:attachmentId
+func (h *AttachmentHandler) Delete(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	user := middleware.GetCurrentUser(c)
+
+	billID, attachmentID, ok := attachmentParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), companyID, billID, attachmentID, user.ID); err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+
+	utils.Success(c, "Attachment deleted successfully", nil)
+}
+
+// serveAttachment writes the attachment headers and streams its content
+func serveAttachment(c *gin.Context, attachment *models.BillAttachment, content io.Reader, disposition string) {
+	c.Header("Content-Type", attachment.FileType)
+	c.Header("Content-Length", strconv.FormatInt(attachment.FileSize, 10))
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	// Inline documents must not run scripts in the API's origin. Browsers
+	// refuse to show PDFs in a sandbox, and their built-in viewer runs none.
+	if disposition != "inline" || attachment.FileType != "application/pdf" {
+		c.Header("Content-Security-Policy", "sandbox")
+	}
+	c.Header("ETag", `"`+attachment.SHA256+`"`)
+	c.Status(http.StatusOK)
+
+	if _, err := io.Copy(c.Writer, content); err != nil {
+		// Headers are already sent, so the client only sees a truncated body
+		log.Printf("attachment download %s failed: %v", attachment.ID, err)
+	}
+}
+
+func attachmentParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	billID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid bill ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	attachmentID, err := uuid.Parse(c.Param("attachmentId"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid attachment ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return billID, attachmentID, true
+}
+
+func respondAttachmentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrBillNotFound), errors.Is(err, services.ErrAttachmentNotFound):
+		utils.NotFound(c, err.Error())
+	case errors.Is(err, services.ErrInvalidSignature), errors.Is(err, services.ErrSignatureExpired):
+		utils.Forbidden(c, err.Error())
+	case errors.Is(err, services.ErrAttachmentTooLarge):
+		utils.Error(c, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, services.ErrUnsupportedFileType):
+		utils.Error(c, http.StatusUnsupportedMediaType, err.Error())
+	default:
+		utils.InternalError(c, err.Error())
+	}
+}
@@ -1,7 +1,11 @@
 package routes
 
 import (
+	"errors"
+	"log"
+
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 
 	"github.com/dhani/bill-tracker-backend/internal/middleware"
 	"github.com/dhani/bill-tracker-backend/internal/services"
@@ -9,11 +13,12 @@ import (
 )
 
 type AuthHandler struct {
-	service *services.AuthService
+	service      *services.AuthService
+	verification *services.EmailVerificationService
 }
 
-func NewAuthHandler(service *services.AuthService) *AuthHandler {
-	return &AuthHandler{service: service}
+func NewAuthHandler(service *services.AuthService, verification *services.EmailVerificationService) *AuthHandler {
+	return &AuthHandler{service: service, verification: verification}
 }
 
 // Register handles user registration
@@ -25,12 +30,17 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	response, err := h.service.Register(input)
+	response, err := h.service.Register(input, sessionMeta(c))
 	if err != nil {
 		utils.BadRequest(c, err.Error())
 		return
 	}
 
+	// Registration succeeds even if the email cannot be sent; it can be resent
+	if err := h.verification.Send(c.Request.Context(), response.User); err != nil {
+		log.Printf("Failed to send verification email to %s: %v", response.User.Email, err)
+	}
+
 	utils.Created(c, "Registration successful", response)
 }
 
@@ -43,8 +53,12 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	response, err := h.service.Login(input)
+	response, err := h.service.Login(input, sessionMeta(c))
 	if err != nil {
+		if errors.Is(err, services.ErrEmailVerificationRequired) {
+			utils.Forbidden(c, err.Error())
+			return
+		}
 		utils.Unauthorized(c, err.Error())
 		return
 	}
@@ -52,6 +66,68 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	utils.Success(c, "Login successful", response)
 }
 
+// Refresh exchanges a refresh token for a new access and refresh token
+// POST /api/auth/refresh
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var input services.RefreshInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	response, err := h.service.Refresh(input, sessionMeta(c))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) ||
+			errors.Is(err, services.ErrSSORequired) {
+			utils.Unauthorized(c, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrEmailVerificationRequired) {
+			utils.Forbidden(c, err.Error())
+			return
+		}
+		utils.InternalError(c, "Failed to refresh token")
+		return
+	}
+
+	utils.Success(c, "Token refreshed", response)
+}
+
+// VerifyEmail confirms the address a verification link was sent to
+// GET /api/auth/verify-email?token=
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	user, err := h.verification.Verify(c.Query("token"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidVerificationToken) || errors.Is(err, services.ErrVerificationTokenExpired) {
+			utils.BadRequest(c, err.Error())
+			return
+		}
+		utils.InternalError(c, "Failed to verify email")
+		return
+	}
+
+	middleware.ForgetUserSessions(user.ID, uuid.Nil)
+	utils.Success(c, "Email verified", gin.H{"email": user.Email})
+}
+
+// ResendVerification emails a new verification link
+// POST /api/auth/verify-email/resend
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var input services.ResendVerificationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	// The response is the same whatever happened, so it reveals nothing about the address
+	if err := h.verification.Resend(c.Request.Context(), input, c.ClientIP()); err != nil &&
+		!errors.Is(err, services.ErrVerificationRateLimited) {
+		log.Printf("Failed to resend verification email: %v", err)
+	}
+
+	utils.Success(c, "If the address needs verifying, a new link has been sent", nil)
+}
+
 // Logout handles user logout
 // POST /api/auth/logout
 func (h *AuthHandler) Logout(c *gin.Context) {
@@ -61,7 +137,11 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		token = token[7:] // Remove "Bearer "
 	}
 
-	if err := h.service.Logout(token); err != nil {
+	// The refresh token is optional and only needed once the access token expired
+	var input services.RefreshInput
+	_ = c.ShouldBindJSON(&input)
+
+	if err := h.service.Logout(token, input.RefreshToken); err != nil {
 		utils.InternalError(c, "Failed to logout")
 		return
 	}
@@ -80,3 +160,62 @@ func (h *AuthHandler) Me(c *gin.Context) {
 
 	utils.Success(c, "", user)
 }
+
+// ListSessions returns the current user's active sessions
+// GET /api/auth/sessions
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	user := middleware.GetCurrentUser(c)
+
+	sessions, err := h.service.ListSessions(user.ID, middleware.GetSessionID(c))
+	if err != nil {
+		utils.InternalError(c, "Failed to fetch sessions")
+		return
+	}
+
+	utils.Success(c, "", sessions)
+}
+
+// RevokeSession logs out one of the current user's sessions
+// DELETE /api/auth/sessions/:id
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	user := middleware.GetCurrentUser(c)
+
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid session ID")
+		return
+	}
+
+	if err := h.service.RevokeSession(user.ID, sessionID); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			utils.NotFound(c, "Session not found")
+			return
+		}
+		utils.InternalError(c, "Failed to revoke session")
+		return
+	}
+
+	utils.Success(c, "Session revoked", nil)
+}
+
+// RevokeOtherSessions logs out every session of the current user except this one
+// POST /api/auth/sessions/revoke-others
+func (h *AuthHandler) RevokeOtherSessions(c *gin.Context) {
+	user := middleware.GetCurrentUser(c)
+
+	revoked, err := h.service.RevokeOtherSessions(user.ID, middleware.GetSessionID(c))
+	if err != nil {
+		utils.InternalError(c, "Failed to revoke sessions")
+		return
+	}
+
+	utils.Success(c, "Other sessions revoked", gin.H{"revoked": revoked})
+}
+
+// sessionMeta describes the client making an auth request
+func sessionMeta(c *gin.Context) services.SessionMeta {
+	return services.SessionMeta{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
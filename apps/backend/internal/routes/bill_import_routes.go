@@ -0,0 +1,83 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dhani/bill-tracker-backend/internal/middleware"
+	"github.com/dhani/bill-tracker-backend/internal/models"
+	"github.com/dhani/bill-tracker-backend/internal/services"
+	"github.com/dhani/bill-tracker-backend/internal/utils"
+)
+
+// maxImportSize bounds uploaded import files
+const maxImportSize = 10 << 20
+
+type BillImportHandler struct {
+	service *services.BillImportService
+}
+
+func NewBillImportHandler(service *services.BillImportService) *BillImportHandler {
+	return &BillImportHandler{service: service}
+}
+
+// Import creates bills from an uploaded CSV or XLSX file. Form fields:
+// file, mapping (JSON object of bill field to column header), date_format,
+// status, create_missing and dry_run.
+// POST /api/bills/import
+func (h *BillImportHandler) Import(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	user := middleware.GetCurrentUser(c)
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize+multipartOverhead)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.Error(c, http.StatusRequestEntityTooLarge, "Import files may be at most 10MB")
+			return
+		}
+		utils.BadRequest(c, "A CSV or XLSX file is required in the 'file' field")
+		return
+	}
+
+	options := services.ImportOptions{
+		DateFormat: c.PostForm("date_format"),
+		Status:     models.BillStatus(c.PostForm("status")),
+	}
+	if mapping := c.PostForm("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &options.Mapping); err != nil {
+			utils.BadRequest(c, "mapping must be a JSON object of field to column name")
+			return
+		}
+	}
+	options.CreateMissing, _ = strconv.ParseBool(c.PostForm("create_missing"))
+	options.DryRun, _ = strconv.ParseBool(c.DefaultPostForm("dry_run", c.Query("dry_run")))
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.BadRequest(c, "Failed to read uploaded file")
+		return
+	}
+	defer file.Close()
+
+	result, err := h.service.Import(companyID, user.ID, fileHeader.Filename, file, options)
+	switch {
+	case errors.Is(err, services.ErrImportRowsInvalid):
+		utils.ErrorWithData(c, http.StatusUnprocessableEntity, err.Error(), result)
+	case errors.Is(err, services.ErrInvalidImportFile), errors.Is(err, services.ErrInvalidMapping):
+		utils.BadRequest(c, err.Error())
+	case errors.Is(err, services.ErrEmailVerificationRequired):
+		utils.Forbidden(c, err.Error())
+	case err != nil:
+		utils.InternalError(c, err.Error())
+	case result.DryRun:
+		utils.Success(c, "Import validated", result)
+	default:
+		utils.Created(c, "Bills imported successfully", result)
+	}
+}
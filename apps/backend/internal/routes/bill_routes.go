@@ -1,6 +1,11 @@
 package routes
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -8,15 +13,17 @@ import (
 
 	"github.com/dhani/bill-tracker-backend/internal/middleware"
 	"github.com/dhani/bill-tracker-backend/internal/services"
+	"github.com/dhani/bill-tracker-backend/internal/spreadsheet"
 	"github.com/dhani/bill-tracker-backend/internal/utils"
 )
 
 type BillHandler struct {
 	service *services.BillService
+	signer  *services.AttachmentSigner
 }
 
-func NewBillHandler(service *services.BillService) *BillHandler {
-	return &BillHandler{service: service}
+func NewBillHandler(service *services.BillService, signer *services.AttachmentSigner) *BillHandler {
+	return &BillHandler{service: service, signer: signer}
 }
 
 // List retrieves bills with pagination and filters
@@ -24,10 +31,20 @@ func NewBillHandler(service *services.BillService) *BillHandler {
 func (h *BillHandler) List(c *gin.Context) {
 	companyID := middleware.GetCompanyID(c)
 	
-	filters := services.BillFilters{
-		Status:     c.Query("status"),
-		Search:     c.Query("search"),
-		Pagination: utils.GetPagination(c),
+	filters, err := billFilters(c)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	if filters.Pagination.UseCursor {
+		bills, page, err := h.service.ListByCursor(companyID, filters)
+		if err != nil {
+			respondListError(c, err, "Failed to fetch bills")
+			return
+		}
+		utils.CursorPaginated(c, bills, filters.Pagination.PageSize, page)
+		return
 	}
 
 	bills, total, err := h.service.List(companyID, filters)
@@ -39,6 +56,61 @@ func (h *BillHandler) List(c *gin.Context) {
 	utils.Paginated(c, bills, total, filters.Pagination.Page, filters.Pagination.PageSize)
 }
 
+// Export streams all bills matching the list filters as a file download
+// GET /api/bills/export?format=csv|xlsx|json&columns=title,amount,due_date
+func (h *BillHandler) Export(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	filters, err := billFilters(c)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	options := services.ExportOptions{Format: c.DefaultQuery("format", "csv")}
+	if columns := c.Query("columns"); columns != "" {
+		for _, column := range strings.Split(columns, ",") {
+			options.Columns = append(options.Columns, strings.TrimSpace(column))
+		}
+	}
+	if err := services.ValidateExport(&options); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("bills-%s.%s", time.Now().Format("20060102"), options.Format)
+	w := &downloadWriter{c: c, contentType: spreadsheet.ContentTypes[options.Format], filename: filename}
+	if err := h.service.Export(companyID, filters, options, w); err != nil {
+		log.Printf("bill export failed for company %s: %v", companyID, err)
+		if !w.started {
+			utils.InternalError(c, "Failed to export bills")
+			return
+		}
+		// Part of the file is sent; abort the connection so the client sees
+		// a failed download rather than a complete-looking truncated file
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// downloadWriter sends the download headers with the first byte of the
+// file, so a failure before then can still be answered with an error
+type downloadWriter struct {
+	c           *gin.Context
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (w *downloadWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.c.Header("Content-Type", w.contentType)
+		w.c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, w.filename))
+		w.c.Status(http.StatusOK)
+	}
+	return w.c.Writer.Write(p)
+}
+
 // GetByID retrieves a single bill
 // GET /api/bills/:id
 func (h *BillHandler) GetByID(c *gin.Context) {
@@ -55,6 +127,7 @@ func (h *BillHandler) GetByID(c *gin.Context) {
 		utils.NotFound(c, "Bill not found")
 		return
 	}
+	h.signer.SignAll(companyID, bill.Attachments)
 
 	utils.Success(c, "", bill)
 }
@@ -73,7 +146,7 @@ func (h *BillHandler) Create(c *gin.Context) {
 
 	bill, err := h.service.Create(companyID, user.ID, input)
 	if err != nil {
-		utils.InternalError(c, err.Error())
+		respondBillError(c, err)
 		return
 	}
 
@@ -100,7 +173,7 @@ func (h *BillHandler) Update(c *gin.Context) {
 
 	bill, err := h.service.Update(companyID, billID, user.ID, input)
 	if err != nil {
-		utils.NotFound(c, err.Error())
+		respondBillError(c, err)
 		return
 	}
 
@@ -150,15 +223,72 @@ func (h *BillHandler) Pay(c *gin.Context) {
 
 	bill, err := h.service.MarkAsPaid(companyID, billID, user.ID, paidDate)
 	if err != nil {
-		utils.NotFound(c, err.Error())
+		respondBillError(c, err)
 		return
 	}
 
 	utils.Success(c, "Bill marked as paid", bill)
 }
 
-// GetActivities retrieves activity log for a bill
-// GET /api/bills/:id/activities
+// Transition moves a bill to another status
+// POST /api/bills/:id/transition
+func (h *BillHandler) Transition(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	user := middleware.GetCurrentUser(c)
+
+	billID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid bill ID")
+		return
+	}
+
+	var input services.TransitionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	bill, err := h.service.Transition(companyID, billID, user.ID, input)
+	if err != nil {
+		respondBillError(c, err)
+		return
+	}
+
+	utils.Success(c, "Bill status updated", bill)
+}
+
+// GetAsOf renders a bill as it looked at a point in time
+// GET /api/bills/:id/history?at=2024-01-31T12:00:00Z
+func (h *BillHandler) GetAsOf(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	billID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid bill ID")
+		return
+	}
+
+	at := time.Now()
+	if value := c.Query("at"); value != "" {
+		at, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			utils.BadRequest(c, "Invalid 'at' timestamp, expected RFC 3339")
+			return
+		}
+	}
+
+	snapshot, err := h.service.GetAsOf(companyID, billID, at)
+	if err != nil {
+		respondBillError(c, err)
+		return
+	}
+
+	utils.Success(c, "", snapshot)
+}
+
+// GetActivities retrieves activity log for a bill. Passing a cursor
+// parameter (empty for the first page) returns it in keyset pages.
+// GET /api/bills/:id/activities?cursor=&limit=20
 func (h *BillHandler) GetActivities(c *gin.Context) {
 	companyID := middleware.GetCompanyID(c)
 
@@ -168,6 +298,16 @@ func (h *BillHandler) GetActivities(c *gin.Context) {
 		return
 	}
 
+	if pagination := utils.GetPagination(c); pagination.UseCursor {
+		activities, page, err := h.service.ListActivities(companyID, billID, pagination)
+		if err != nil {
+			respondListError(c, err, "Failed to fetch activities")
+			return
+		}
+		utils.CursorPaginated(c, activities, pagination.PageSize, page)
+		return
+	}
+
 	activities, err := h.service.GetActivities(companyID, billID)
 	if err != nil {
 		utils.InternalError(c, "Failed to fetch activities")
@@ -176,3 +316,77 @@ func (h *BillHandler) GetActivities(c *gin.Context) {
 
 	utils.Success(c, "", activities)
 }
+
+// billFilters parses the list query shared by List and Export, e.g.
+// ?status=unpaid,overdue&amount[gte]=100&due_date[lt]=2024-07-01&sort=amount
+func billFilters(c *gin.Context) (services.BillFilters, error) {
+	filters := services.BillFilters{
+		Search:     c.Query("search"),
+		Pagination: utils.GetPagination(c),
+	}
+
+	if _, ok := services.BillSortFields[filters.Pagination.Sort]; !ok {
+		_, err := filters.Pagination.OrderBy(services.BillSortFields)
+		return filters, err
+	}
+
+	// "all" is the dashboard's way of asking for no status filter
+	query := c.Request.URL.Query()
+	if query.Get("status") == "all" {
+		query.Del("status")
+	}
+
+	conditions, err := utils.ParseFilters(query, services.BillFilterFields)
+	if err != nil {
+		return filters, err
+	}
+	filters.Conditions = conditions
+	return filters, nil
+}
+
+// respondListError reports invalid pagination input as a bad request
+func respondListError(c *gin.Context, err error, message string) {
+	if errors.Is(err, utils.ErrInvalidCursor) || errors.Is(err, utils.ErrInvalidSort) {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	utils.InternalError(c, message)
+}
+
+// respondBillError maps bill service errors to HTTP responses
+func respondBillError(c *gin.Context, err error) {
+	var duplicate *services.DuplicateBillError
+	if errors.As(err, &duplicate) {
+		utils.ErrorWithData(c, http.StatusConflict, err.Error(), gin.H{"duplicate_bill_ids": duplicate.BillIDs})
+		return
+	}
+
+	switch {
+	case errors.Is(err, services.ErrBillNotFound),
+		errors.Is(err, services.ErrBillNotYetCreated),
+		errors.Is(err, services.ErrPolicyNotFound),
+		errors.Is(err, services.ErrReminderRuleNotFound):
+		utils.NotFound(c, err.Error())
+	case errors.Is(err, services.ErrNotAllowedApprover),
+		errors.Is(err, services.ErrEmailVerificationRequired):
+		utils.Forbidden(c, err.Error())
+	case errors.Is(err, services.ErrInvalidRecurrence),
+		errors.Is(err, services.ErrAmountBelowPaid),
+		errors.Is(err, services.ErrInvalidStatus),
+		errors.Is(err, services.ErrInvalidTransition),
+		errors.Is(err, services.ErrInvalidPaymentAmount),
+		errors.Is(err, services.ErrOverpayment),
+		errors.Is(err, services.ErrBillAlreadyPaid),
+		errors.Is(err, services.ErrApprovalRequired),
+		errors.Is(err, services.ErrNotPendingApproval),
+		errors.Is(err, services.ErrAlreadyDecided),
+		errors.Is(err, services.ErrInvalidPolicy),
+		errors.Is(err, services.ErrInvalidReminderRule),
+		errors.Is(err, services.ErrInvalidCurrency),
+		errors.Is(err, services.ErrLineItemsMismatch),
+		errors.Is(err, services.ErrInvalidLineItem):
+		utils.BadRequest(c, err.Error())
+	default:
+		utils.InternalError(c, err.Error())
+	}
+}
@@ -0,0 +1,161 @@
+package routes
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/dhani/bill-tracker-backend/internal/middleware"
+	"github.com/dhani/bill-tracker-backend/internal/services"
+	"github.com/dhani/bill-tracker-backend/internal/utils"
+)
+
+const (
+	// maxCalendarAlarms bounds the reminders a feed may ask for per event
+	maxCalendarAlarms    = 5
+	maxCalendarAlarmDays = 60
+)
+
+type CalendarHandler struct {
+	service *services.CalendarService
+}
+
+func NewCalendarHandler(service *services.CalendarService) *CalendarHandler {
+	return &CalendarHandler{service: service}
+}
+
+// CalendarSubscription holds the feed URLs of the current user's calendar
+type CalendarSubscription struct {
+	URL       string `json:"url"`
+	WebcalURL string `json:"webcal_url"`
+}
+
+// Feed renders the iCalendar feed of upcoming due dates. The token in the
+// path authenticates the request, as calendar apps cannot send headers.
+// Filters: category_id and vendor_id (repeated or comma separated), and
+// alarm=<days before>[,<days before>...] or alarm=none (default 1).
+// GET /api/calendar/:token.ics
+func (h *CalendarHandler) Feed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("file"), ".ics")
+
+	filters, err := calendarFilters(c)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	calendar, err := h.service.Feed(token, filters)
+	if err != nil {
+		if errors.Is(err, services.ErrCalendarNotFound) {
+			utils.NotFound(c, err.Error())
+			return
+		}
+		utils.InternalError(c, "Failed to render calendar")
+		return
+	}
+
+	var body bytes.Buffer
+	if _, err := calendar.WriteTo(&body); err != nil {
+		utils.InternalError(c, "Failed to render calendar")
+		return
+	}
+
+	c.Header("Content-Disposition", `inline; filename="bills.ics"`)
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", body.Bytes())
+}
+
+// Subscription returns the current user's calendar feed URL
+// GET /api/users/calendar
+func (h *CalendarHandler) Subscription(c *gin.Context) {
+	user := middleware.GetCurrentUser(c)
+
+	token, err := h.service.Token(user.ID)
+	if err != nil {
+		utils.InternalError(c, "Failed to create calendar token")
+		return
+	}
+
+	utils.Success(c, "", calendarSubscription(c, token))
+}
+
+// RotateSubscription replaces the current user's calendar feed URL, e.g.
+// after it was shared by mistake
+// POST /api/users/calendar/rotate
+func (h *CalendarHandler) RotateSubscription(c *gin.Context) {
+	user := middleware.GetCurrentUser(c)
+
+	token, err := h.service.RotateToken(user.ID)
+	if err != nil {
+		utils.InternalError(c, "Failed to rotate calendar token")
+		return
+	}
+
+	utils.Success(c, "Calendar URL rotated", calendarSubscription(c, token))
+}
+
+// calendarSubscription builds the feed URLs from the request's own address
+func calendarSubscription(c *gin.Context, token string) CalendarSubscription {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	path := c.Request.Host + "/api/calendar/" + token + ".ics"
+	return CalendarSubscription{
+		URL:       scheme + "://" + path,
+		WebcalURL: "webcal://" + path,
+	}
+}
+
+func calendarFilters(c *gin.Context) (services.CalendarFilters, error) {
+	var filters services.CalendarFilters
+	var err error
+
+	if filters.CategoryIDs, err = uuidList(c.QueryArray("category_id")); err != nil {
+		return filters, fmt.Errorf("invalid category_id: %w", err)
+	}
+	if filters.VendorIDs, err = uuidList(c.QueryArray("vendor_id")); err != nil {
+		return filters, fmt.Errorf("invalid vendor_id: %w", err)
+	}
+
+	alarm := c.DefaultQuery("alarm", "1")
+	if alarm == "none" || alarm == "" {
+		return filters, nil
+	}
+	for _, value := range strings.Split(alarm, ",") {
+		days, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || days < 0 || days > maxCalendarAlarmDays {
+			return filters, fmt.Errorf("alarm must be days between 0 and %d, or none", maxCalendarAlarmDays)
+		}
+		filters.AlarmDays = append(filters.AlarmDays, days)
+	}
+	if len(filters.AlarmDays) > maxCalendarAlarms {
+		return filters, fmt.Errorf("at most %d alarms are allowed", maxCalendarAlarms)
+	}
+	return filters, nil
+}
+
+// uuidList parses repeated and comma separated IDs
+func uuidList(values []string) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			id, err := uuid.Parse(part)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
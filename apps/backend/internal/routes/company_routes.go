@@ -0,0 +1,71 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/dhani/bill-tracker-backend/internal/config"
+	"github.com/dhani/bill-tracker-backend/internal/middleware"
+	"github.com/dhani/bill-tracker-backend/internal/services"
+	"github.com/dhani/bill-tracker-backend/internal/utils"
+)
+
+type CompanyHandler struct {
+	service *services.CompanyService
+}
+
+func NewCompanyHandler(service *services.CompanyService) *CompanyHandler {
+	return &CompanyHandler{service: service}
+}
+
+// Get retrieves the current user's company
+// GET /api/company
+func (h *CompanyHandler) Get(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	company, err := h.service.Get(companyID)
+	if err != nil {
+		utils.NotFound(c, "Company not found")
+		return
+	}
+	company.InboundEmail = company.InboundAddress(config.AppConfig.InboundEmailPrefix, config.AppConfig.InboundEmailDomain)
+
+	utils.Success(c, "", company)
+}
+
+// Update updates company settings
+// PUT /api/company
+func (h *CompanyHandler) Update(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	var input services.UpdateCompanyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	company, err := h.service.Update(companyID, input)
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	company.InboundEmail = company.InboundAddress(config.AppConfig.InboundEmailPrefix, config.AppConfig.InboundEmailDomain)
+
+	utils.Success(c, "Company updated successfully", company)
+}
+
+// RotateInboundEmail replaces the company's inbound email address, e.g.
+// after it leaked; mail to the old address is rejected from then on
+// POST /api/company/inbound-email/rotate
+func (h *CompanyHandler) RotateInboundEmail(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	company, err := h.service.RotateInboundToken(companyID)
+	if err != nil {
+		utils.InternalError(c, "Failed to rotate inbound email address")
+		return
+	}
+	company.InboundEmail = company.InboundAddress(config.AppConfig.InboundEmailPrefix, config.AppConfig.InboundEmailDomain)
+
+	utils.Success(c, "Inbound email address rotated", company)
+}
@@ -0,0 +1,63 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dhani/bill-tracker-backend/internal/einvoice"
+	"github.com/dhani/bill-tracker-backend/internal/middleware"
+	"github.com/dhani/bill-tracker-backend/internal/services"
+	"github.com/dhani/bill-tracker-backend/internal/utils"
+)
+
+type EInvoiceHandler struct {
+	service *services.EInvoiceService
+	maxSize int64
+}
+
+func NewEInvoiceHandler(service *services.EInvoiceService, maxSize int64) *EInvoiceHandler {
+	return &EInvoiceHandler{service: service, maxSize: maxSize}
+}
+
+// Ingest creates a draft bill from a UBL XML or ZUGFeRD/Factur-X PDF sent
+// in the "file" form field
+// POST /api/bills/einvoice
+func (h *EInvoiceHandler) Ingest(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	user := middleware.GetCurrentUser(c)
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxSize+multipartOverhead)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.Error(c, http.StatusRequestEntityTooLarge, services.ErrAttachmentTooLarge.Error())
+			return
+		}
+		utils.BadRequest(c, "An e-invoice XML or PDF file is required in the 'file' field")
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.BadRequest(c, "Failed to read uploaded file")
+		return
+	}
+	defer file.Close()
+
+	result, err := h.service.Ingest(c.Request.Context(), companyID, user.ID, fileHeader.Filename, file)
+	switch {
+	case errors.Is(err, einvoice.ErrUnsupportedDocument):
+		utils.Error(c, http.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, einvoice.ErrInvalidInvoice):
+		utils.Error(c, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, services.ErrAttachmentTooLarge), errors.Is(err, services.ErrUnsupportedFileType):
+		respondAttachmentError(c, err)
+	case err != nil:
+		respondBillError(c, err)
+	default:
+		utils.Created(c, "Bill created from e-invoice", result)
+	}
+}
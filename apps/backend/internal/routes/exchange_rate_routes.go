@@ -0,0 +1,91 @@
+package routes
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/dhani/bill-tracker-backend/internal/middleware"
+	"github.com/dhani/bill-tracker-backend/internal/services"
+	"github.com/dhani/bill-tracker-backend/internal/utils"
+)
+
+type ExchangeRateHandler struct {
+	service *services.ExchangeRateService
+}
+
+func NewExchangeRateHandler(service *services.ExchangeRateService) *ExchangeRateHandler {
+	return &ExchangeRateHandler{service: service}
+}
+
+// List retrieves stored exchange rates
+// GET /api/exchange-rates?base=EUR&quote=USD
+func (h *ExchangeRateHandler) List(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	rates, err := h.service.List(companyID, services.ExchangeRateFilters{
+		Base:  c.Query("base"),
+		Quote: c.Query("quote"),
+	})
+	if err != nil {
+		utils.InternalError(c, "Failed to fetch exchange rates")
+		return
+	}
+
+	utils.Success(c, "", rates)
+}
+
+// Create stores a single exchange rate
+// POST /api/exchange-rates
+func (h *ExchangeRateHandler) Create(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	var input services.CreateExchangeRateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	rate, err := h.service.Create(companyID, input)
+	if err != nil {
+		respondExchangeRateError(c, err)
+		return
+	}
+
+	utils.Created(c, "Exchange rate saved successfully", rate)
+}
+
+// Import loads exchange rates from an uploaded CSV or ECB XML file
+// POST /api/exchange-rates/import
+func (h *ExchangeRateHandler) Import(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		utils.BadRequest(c, "A rate file is required in the 'file' field")
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.BadRequest(c, "Failed to read uploaded file")
+		return
+	}
+	defer file.Close()
+
+	count, err := h.service.Import(companyID, fileHeader.Filename, file)
+	if err != nil {
+		respondExchangeRateError(c, err)
+		return
+	}
+
+	utils.Success(c, "Exchange rates imported successfully", gin.H{"imported": count})
+}
+
+func respondExchangeRateError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidCurrency) || errors.Is(err, services.ErrInvalidRateFile) {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	utils.InternalError(c, err.Error())
+}
@@ -0,0 +1,174 @@
+package routes
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/dhani/bill-tracker-backend/internal/config"
+	"github.com/dhani/bill-tracker-backend/internal/oidc"
+	"github.com/dhani/bill-tracker-backend/internal/services"
+	"github.com/dhani/bill-tracker-backend/internal/utils"
+)
+
+// oauthStateTTL is how long a user has to complete sign-in at the provider
+const oauthStateTTL = 10 * time.Minute
+
+// OAuthHandler signs users in with an external identity provider. The
+// state, nonce and PKCE verifier travel in a signed, short-lived cookie.
+type OAuthHandler struct {
+	service  *services.AuthService
+	name     string
+	provider services.IdentityProvider
+}
+
+// NewOAuthHandler creates a handler for the named provider; provider is nil
+// when sign-in with it is not configured
+func NewOAuthHandler(service *services.AuthService, name string, provider services.IdentityProvider) *OAuthHandler {
+	return &OAuthHandler{service: service, name: name, provider: provider}
+}
+
+// oauthState is stored in the state cookie between redirect and callback
+type oauthState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	jwt.RegisteredClaims
+}
+
+// Start redirects to the provider's sign-in page
+// GET /api/auth/google
+func (h *OAuthHandler) Start(c *gin.Context) {
+	if h.provider == nil {
+		utils.NotFound(c, "Sign-in with "+h.name+" is not configured")
+		return
+	}
+
+	if err := startOAuth(c, h.cookieName(), h.provider, ""); err != nil {
+		log.Printf("[oauth] %s: %v", h.name, err)
+		utils.InternalError(c, "Failed to start sign-in")
+	}
+}
+
+// Callback completes sign-in and redirects to the dashboard with the tokens
+// GET /api/auth/google/callback
+func (h *OAuthHandler) Callback(c *gin.Context) {
+	if h.provider == nil {
+		utils.NotFound(c, "Sign-in with "+h.name+" is not configured")
+		return
+	}
+
+	state, ok := finishOAuth(c, h.cookieName())
+	if !ok {
+		return
+	}
+
+	identity, err := h.provider.Exchange(c.Request.Context(), c.Query("code"), state.Verifier, state.Nonce)
+	if err != nil {
+		log.Printf("[oauth] %s: %v", h.name, err)
+		redirectSignInError(c, "Sign-in failed, please try again")
+		return
+	}
+
+	response, err := h.service.SignInWithIdentity(h.name, identity, sessionMeta(c))
+	if err != nil {
+		respondSignInError(c, h.name, err)
+		return
+	}
+
+	redirectSignedIn(c, response)
+}
+
+func (h *OAuthHandler) cookieName() string {
+	return "oauth_" + h.name
+}
+
+// startOAuth stores a new state in a cookie and redirects to the provider.
+// subject is kept with the state, e.g. to remember which provider was used.
+func startOAuth(c *gin.Context, cookieName string, provider services.IdentityProvider, subject string) error {
+	state := oauthState{
+		State:    oidc.RandomString(),
+		Nonce:    oidc.RandomString(),
+		Verifier: oidc.RandomString(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{cookieName},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(oauthStateTTL)),
+		},
+	}
+	cookie, err := jwt.NewWithClaims(jwt.SigningMethodHS256, state).SignedString([]byte(config.AppConfig.JWTSecret))
+	if err != nil {
+		return err
+	}
+
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), state.State, state.Nonce, oidc.CodeChallenge(state.Verifier))
+	if err != nil {
+		return err
+	}
+
+	setStateCookie(c, cookieName, cookie, int(oauthStateTTL.Seconds()))
+	c.Redirect(http.StatusFound, authURL)
+	return nil
+}
+
+// finishOAuth consumes the state cookie and checks it against the callback.
+// It redirects to the login page and returns false when sign-in cannot go on.
+func finishOAuth(c *gin.Context, cookieName string) (*oauthState, bool) {
+	cookie, _ := c.Cookie(cookieName)
+	setStateCookie(c, cookieName, "", -1)
+
+	if c.Query("error") != "" {
+		redirectSignInError(c, "Sign-in was cancelled or denied")
+		return nil, false
+	}
+
+	state := &oauthState{}
+	_, err := jwt.ParseWithClaims(cookie, state, func(token *jwt.Token) (interface{}, error) {
+		return []byte(config.AppConfig.JWTSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(cookieName))
+	if err != nil || state.State == "" || c.Query("state") != state.State {
+		redirectSignInError(c, "Sign-in expired, please try again")
+		return nil, false
+	}
+	return state, true
+}
+
+func setStateCookie(c *gin.Context, name, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(name, value, maxAge, "/api/auth", "", config.AppConfig.Env == "production", true)
+}
+
+// redirectSignedIn sends the user to the dashboard with the tokens in the
+// URL fragment, which browsers do not send to servers
+func redirectSignedIn(c *gin.Context, response *services.AuthResponse) {
+	fragment := url.Values{
+		"token":         {response.Token},
+		"refresh_token": {response.RefreshToken},
+		"expires_at":    {response.ExpiresAt.Format(time.RFC3339)},
+	}
+	c.Redirect(http.StatusFound, config.AppConfig.FrontendURL+"/auth/callback#"+fragment.Encode())
+}
+
+func redirectSignInError(c *gin.Context, message string) {
+	c.Redirect(http.StatusFound, config.AppConfig.FrontendURL+"/login?error="+url.QueryEscape(message))
+}
+
+// respondSignInError shows sign-in errors meant for the user on the login page
+func respondSignInError(c *gin.Context, provider string, err error) {
+	switch {
+	case errors.Is(err, services.ErrEmailNotVerified):
+		redirectSignInError(c, "Your email address is not verified")
+	case errors.Is(err, services.ErrSSORequired),
+		errors.Is(err, services.ErrSSODomainNotAllowed),
+		errors.Is(err, services.ErrSSOAccountConflict):
+		redirectSignInError(c, err.Error())
+	default:
+		log.Printf("[oauth] %s: %v", provider, err)
+		redirectSignInError(c, "Sign-in failed, please try again")
+	}
+}
@@ -0,0 +1,65 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/dhani/bill-tracker-backend/internal/middleware"
+	"github.com/dhani/bill-tracker-backend/internal/services"
+	"github.com/dhani/bill-tracker-backend/internal/utils"
+)
+
+type PaymentHandler struct {
+	service *services.PaymentService
+}
+
+func NewPaymentHandler(service *services.PaymentService) *PaymentHandler {
+	return &PaymentHandler{service: service}
+}
+
+// List retrieves the payment ledger for a bill
+// GET /api/bills/:id/payments
+func (h *PaymentHandler) List(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	billID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid bill ID")
+		return
+	}
+
+	payments, err := h.service.List(companyID, billID)
+	if err != nil {
+		utils.InternalError(c, "Failed to fetch payments")
+		return
+	}
+
+	utils.Success(c, "", payments)
+}
+
+// Create records a (partial) payment against a bill
+// POST /api/bills/:id/payments
+func (h *PaymentHandler) Create(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	user := middleware.GetCurrentUser(c)
+
+	billID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid bill ID")
+		return
+	}
+
+	var input services.RecordPaymentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	payment, err := h.service.Record(companyID, billID, user.ID, input)
+	if err != nil {
+		respondBillError(c, err)
+		return
+	}
+
+	utils.Created(c, "Payment recorded successfully", payment)
+}
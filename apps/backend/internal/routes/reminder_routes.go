@@ -0,0 +1,97 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/dhani/bill-tracker-backend/internal/middleware"
+	"github.com/dhani/bill-tracker-backend/internal/services"
+	"github.com/dhani/bill-tracker-backend/internal/utils"
+)
+
+type ReminderHandler struct {
+	service *services.ReminderService
+}
+
+func NewReminderHandler(service *services.ReminderService) *ReminderHandler {
+	return &ReminderHandler{service: service}
+}
+
+// ListRules retrieves the company's payment reminder rules
+// GET /api/reminder-rules
+func (h *ReminderHandler) ListRules(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	rules, err := h.service.ListRules(companyID)
+	if err != nil {
+		utils.InternalError(c, "Failed to fetch reminder rules")
+		return
+	}
+
+	utils.Success(c, "", rules)
+}
+
+// CreateRule creates a payment reminder rule
+// POST /api/reminder-rules
+func (h *ReminderHandler) CreateRule(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	var input services.ReminderRuleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	rule, err := h.service.CreateRule(companyID, input)
+	if err != nil {
+		respondBillError(c, err)
+		return
+	}
+
+	utils.Created(c, "Reminder rule created successfully", rule)
+}
+
+// UpdateRule updates a payment reminder rule
+// PUT /api/reminder-rules/:id
+func (h *ReminderHandler) UpdateRule(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	ruleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid reminder rule ID")
+		return
+	}
+
+	var input services.ReminderRuleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	rule, err := h.service.UpdateRule(companyID, ruleID, input)
+	if err != nil {
+		respondBillError(c, err)
+		return
+	}
+
+	utils.Success(c, "Reminder rule updated successfully", rule)
+}
+
+// DeleteRule deletes a payment reminder rule
+// DELETE /api/reminder-rules/:id
+func (h *ReminderHandler) DeleteRule(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	ruleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid reminder rule ID")
+		return
+	}
+
+	if err := h.service.DeleteRule(companyID, ruleID); err != nil {
+		respondBillError(c, err)
+		return
+	}
+
+	utils.Success(c, "Reminder rule deleted successfully", nil)
+}
@@ -1,37 +1,76 @@
 package routes
 
 import (
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 
+	"github.com/dhani/bill-tracker-backend/internal/config"
+	"github.com/dhani/bill-tracker-backend/internal/mailer"
 	"github.com/dhani/bill-tracker-backend/internal/middleware"
 	"github.com/dhani/bill-tracker-backend/internal/services"
+	"github.com/dhani/bill-tracker-backend/internal/storage"
 )
 
-// SetupRouter configures all API routes
-func SetupRouter(db *gorm.DB) *gin.Engine {
+// SetupRouter configures all API routes. google is nil when sign-in with
+// Google is not configured.
+func SetupRouter(db *gorm.DB, store storage.Storage, mail mailer.Mailer, google services.IdentityProvider) *gin.Engine {
 	router := gin.New()
 
 	// Apply global middleware
-	router.Use(gin.Recovery())
+	router.Use(gin.CustomRecovery(func(c *gin.Context, err interface{}) {
+		// Let net/http abort the connection, e.g. a download failing halfway
+		if err == http.ErrAbortHandler {
+			panic(err)
+		}
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
 	router.Use(middleware.LoggerMiddleware())
 	router.Use(middleware.CORSMiddleware())
 
 	// Initialize services
 	authService := services.NewAuthService(db)
 	billService := services.NewBillService(db)
+	billImportService := services.NewBillImportService(db)
+	paymentService := services.NewPaymentService(db)
 	vendorService := services.NewVendorService(db)
 	categoryService := services.NewCategoryService(db)
 	dashboardService := services.NewDashboardService(db)
 	userService := services.NewUserService(db)
+	companyService := services.NewCompanyService(db)
+	approvalService := services.NewApprovalService(db)
+	exchangeRateService := services.NewExchangeRateService(db)
+	attachmentSigner := services.NewAttachmentSigner(config.AppConfig.AttachmentURLSecret, config.AppConfig.AttachmentURLTTL)
+	attachmentService := services.NewAttachmentService(db, store, attachmentSigner, config.AppConfig.MaxAttachmentSize)
+	eInvoiceService := services.NewEInvoiceService(db, attachmentService)
+	reminderService := services.NewReminderService(db, mail, config.AppConfig.FrontendURL)
+	webhookService := services.NewWebhookService(db)
+	calendarService := services.NewCalendarService(db, config.AppConfig.FrontendURL)
+	ssoService := services.NewSSOService(db, authService, config.AppConfig.SSORedirectURL)
+	verificationService := services.NewEmailVerificationService(db, mail, config.AppConfig.JWTSecret,
+		config.AppConfig.EmailVerificationTTL, config.AppConfig.EmailVerificationCooldown,
+		config.AppConfig.EmailVerificationDailyLimit, config.AppConfig.EmailVerificationIPLimit, config.AppConfig.FrontendURL)
 
 	// Initialize handlers
-	authHandler := NewAuthHandler(authService)
-	billHandler := NewBillHandler(billService)
+	authHandler := NewAuthHandler(authService, verificationService)
+	googleHandler := NewOAuthHandler(authService, "google", google)
+	ssoHandler := NewSSOHandler(ssoService)
+	billHandler := NewBillHandler(billService, attachmentSigner)
+	billImportHandler := NewBillImportHandler(billImportService)
+	paymentHandler := NewPaymentHandler(paymentService)
 	vendorHandler := NewVendorHandler(vendorService)
 	categoryHandler := NewCategoryHandler(categoryService)
 	dashboardHandler := NewDashboardHandler(dashboardService)
 	userHandler := NewUserHandler(userService)
+	companyHandler := NewCompanyHandler(companyService)
+	approvalHandler := NewApprovalHandler(approvalService)
+	exchangeRateHandler := NewExchangeRateHandler(exchangeRateService)
+	attachmentHandler := NewAttachmentHandler(attachmentService, config.AppConfig.MaxAttachmentSize)
+	eInvoiceHandler := NewEInvoiceHandler(eInvoiceService, config.AppConfig.MaxAttachmentSize)
+	reminderHandler := NewReminderHandler(reminderService)
+	webhookHandler := NewWebhookHandler(webhookService)
+	calendarHandler := NewCalendarHandler(calendarService)
 
 	// API routes
 	api := router.Group("/api")
@@ -46,26 +85,56 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 		{
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
+			auth.POST("/refresh", authHandler.Refresh)
 			auth.POST("/logout", authHandler.Logout)
+			auth.GET("/verify-email", authHandler.VerifyEmail)
+			auth.POST("/verify-email/resend", authHandler.ResendVerification)
+			auth.GET("/google", googleHandler.Start)
+			auth.GET("/google/callback", googleHandler.Callback)
+			auth.GET("/sso", ssoHandler.Start)
+			auth.GET("/sso/callback", ssoHandler.Callback)
 		}
 
+		// Signed attachment downloads (the signature replaces the bearer token)
+		api.GET("/attachments/:attachmentId/download", attachmentHandler.SignedDownload)
+
+		// Calendar feeds (the token in the path replaces the bearer token)
+		api.GET("/calendar/:file", calendarHandler.Feed)
+
 		// Protected routes
 		protected := api.Group("")
 		protected.Use(middleware.AuthMiddleware())
 		{
 			// Auth (protected)
 			protected.GET("/auth/me", authHandler.Me)
+			protected.GET("/auth/sessions", authHandler.ListSessions)
+			protected.DELETE("/auth/sessions/:id", authHandler.RevokeSession)
+			protected.POST("/auth/sessions/revoke-others", authHandler.RevokeOtherSessions)
 
 			// Bills
 			bills := protected.Group("/bills")
 			{
 				bills.GET("", billHandler.List)
+				bills.GET("/export", billHandler.Export)
 				bills.GET("/:id", billHandler.GetByID)
 				bills.POST("", billHandler.Create)
+				bills.POST("/import", billImportHandler.Import)
+				bills.POST("/einvoice", eInvoiceHandler.Ingest)
 				bills.PUT("/:id", billHandler.Update)
 				bills.DELETE("/:id", billHandler.Delete)
 				bills.POST("/:id/pay", billHandler.Pay)
+				bills.POST("/:id/transition", billHandler.Transition)
 				bills.GET("/:id/activities", billHandler.GetActivities)
+				bills.GET("/:id/history", billHandler.GetAsOf)
+				bills.GET("/:id/payments", paymentHandler.List)
+				bills.POST("/:id/payments", paymentHandler.Create)
+				bills.GET("/:id/approvals", approvalHandler.ListDecisions)
+				bills.POST("/:id/approve", approvalHandler.Approve)
+				bills.POST("/:id/reject", approvalHandler.Reject)
+				bills.GET("/:id/attachments", attachmentHandler.List)
+				bills.POST("/:id/attachments", attachmentHandler.Upload)
+				bills.GET("/:id/attachments/:attachmentId/download", attachmentHandler.Download)
+				bills.DELETE("/:id/attachments/:attachmentId", attachmentHandler.Delete)
 			}
 
 			// Vendors
@@ -102,6 +171,58 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 				users.GET("/profile", userHandler.GetProfile)
 				users.PUT("/profile", userHandler.UpdateProfile)
 				users.PUT("/password", userHandler.ChangePassword)
+				users.GET("/calendar", calendarHandler.Subscription)
+				users.POST("/calendar/rotate", calendarHandler.RotateSubscription)
+			}
+
+			// Approval policies
+			policies := protected.Group("/approval-policies")
+			{
+				policies.GET("", approvalHandler.ListPolicies)
+				policies.POST("", middleware.AdminOnly(), approvalHandler.CreatePolicy)
+				policies.PUT("/:id", middleware.AdminOnly(), approvalHandler.UpdatePolicy)
+				policies.DELETE("/:id", middleware.AdminOnly(), approvalHandler.DeletePolicy)
+			}
+
+			// Payment reminder rules
+			reminders := protected.Group("/reminder-rules")
+			{
+				reminders.GET("", reminderHandler.ListRules)
+				reminders.POST("", middleware.AdminOnly(), reminderHandler.CreateRule)
+				reminders.PUT("/:id", middleware.AdminOnly(), reminderHandler.UpdateRule)
+				reminders.DELETE("/:id", middleware.AdminOnly(), reminderHandler.DeleteRule)
+			}
+
+			// Webhooks (their secrets are visible, so admins only)
+			webhooks := protected.Group("/webhooks", middleware.AdminOnly())
+			{
+				webhooks.GET("", webhookHandler.List)
+				webhooks.GET("/:id", webhookHandler.GetByID)
+				webhooks.POST("", webhookHandler.Create)
+				webhooks.PUT("/:id", webhookHandler.Update)
+				webhooks.DELETE("/:id", webhookHandler.Delete)
+				webhooks.GET("/:id/deliveries", webhookHandler.ListDeliveries)
+				webhooks.POST("/:id/deliveries/:deliveryId/redeliver", webhookHandler.Redeliver)
+			}
+
+			// Exchange rates
+			rates := protected.Group("/exchange-rates")
+			{
+				rates.GET("", exchangeRateHandler.List)
+				rates.POST("", middleware.AdminOnly(), exchangeRateHandler.Create)
+				rates.POST("/import", middleware.AdminOnly(), exchangeRateHandler.Import)
+			}
+
+			// Company settings
+			company := protected.Group("/company")
+			{
+				company.GET("", companyHandler.Get)
+				company.PUT("", middleware.AdminOnly(), companyHandler.Update)
+				company.POST("/inbound-email/rotate", middleware.AdminOnly(), companyHandler.RotateInboundEmail)
+				company.GET("/sso", middleware.AdminOnly(), ssoHandler.Get)
+				company.PUT("/sso", middleware.AdminOnly(), ssoHandler.Save)
+				company.DELETE("/sso", middleware.AdminOnly(), ssoHandler.Delete)
+				company.POST("/sso/verify-domains", middleware.AdminOnly(), ssoHandler.VerifyDomains)
 			}
 		}
 	}
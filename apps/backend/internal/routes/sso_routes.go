@@ -0,0 +1,169 @@
+package routes
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/dhani/bill-tracker-backend/internal/middleware"
+	"github.com/dhani/bill-tracker-backend/internal/services"
+	"github.com/dhani/bill-tracker-backend/internal/utils"
+)
+
+// ssoCookieName holds the sign-in state for company identity providers
+const ssoCookieName = "oauth_sso"
+
+type SSOHandler struct {
+	service *services.SSOService
+}
+
+func NewSSOHandler(service *services.SSOService) *SSOHandler {
+	return &SSOHandler{service: service}
+}
+
+// Start redirects to the identity provider of the company that owns the
+// email's domain
+// GET /api/auth/sso?email=
+func (h *SSOHandler) Start(c *gin.Context) {
+	cfg, err := h.service.ConfigForEmail(c.Query("email"))
+	if err != nil {
+		if errors.Is(err, services.ErrSSONotConfigured) {
+			redirectSignInError(c, err.Error())
+			return
+		}
+		log.Printf("[oauth] sso: %v", err)
+		redirectSignInError(c, "Sign-in failed, please try again")
+		return
+	}
+
+	provider, err := h.service.Provider(cfg)
+	if err == nil {
+		err = startOAuth(c, ssoCookieName, provider, cfg.ID.String())
+	}
+	if err != nil {
+		log.Printf("[oauth] sso %s: %v", cfg.CompanyID, err)
+		redirectSignInError(c, "Your company's sign-in service is unavailable")
+	}
+}
+
+// Callback completes sign-in and redirects to the dashboard with the tokens
+// GET /api/auth/sso/callback
+func (h *SSOHandler) Callback(c *gin.Context) {
+	state, ok := finishOAuth(c, ssoCookieName)
+	if !ok {
+		return
+	}
+
+	configID, err := uuid.Parse(state.Subject)
+	if err != nil {
+		redirectSignInError(c, "Sign-in expired, please try again")
+		return
+	}
+	cfg, err := h.service.ConfigByID(configID)
+	if err != nil {
+		redirectSignInError(c, "Single sign-on is no longer set up for your company")
+		return
+	}
+
+	provider, err := h.service.Provider(cfg)
+	if err != nil {
+		log.Printf("[oauth] sso %s: %v", cfg.CompanyID, err)
+		redirectSignInError(c, "Sign-in failed, please try again")
+		return
+	}
+	identity, err := provider.Exchange(c.Request.Context(), c.Query("code"), state.Verifier, state.Nonce)
+	if err != nil {
+		log.Printf("[oauth] sso %s: %v", cfg.CompanyID, err)
+		redirectSignInError(c, "Sign-in failed, please try again")
+		return
+	}
+
+	response, err := h.service.SignIn(cfg, identity, sessionMeta(c))
+	if err != nil {
+		respondSignInError(c, "sso "+cfg.CompanyID.String(), err)
+		return
+	}
+
+	redirectSignedIn(c, response)
+}
+
+// Get returns the company's single sign-on settings
+// GET /api/company/sso
+func (h *SSOHandler) Get(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	cfg, err := h.service.Get(companyID)
+	if err != nil {
+		respondSSOError(c, err)
+		return
+	}
+
+	utils.Success(c, "", cfg)
+}
+
+// Save creates or replaces the company's single sign-on settings
+// PUT /api/company/sso
+func (h *SSOHandler) Save(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+	user := middleware.GetCurrentUser(c)
+
+	var input services.SSOConfigInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	cfg, err := h.service.Save(c.Request.Context(), companyID, user.ID, input)
+	if err != nil {
+		respondSSOError(c, err)
+		return
+	}
+
+	utils.Success(c, "Single sign-on settings saved", cfg)
+}
+
+// VerifyDomains checks the DNS records proving ownership of the allowed domains
+// POST /api/company/sso/verify-domains
+func (h *SSOHandler) VerifyDomains(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	cfg, err := h.service.VerifyDomains(c.Request.Context(), companyID)
+	if errors.Is(err, services.ErrSSODomainUnverified) {
+		utils.ErrorWithData(c, http.StatusBadRequest, err.Error(), cfg)
+		return
+	}
+	if err != nil {
+		respondSSOError(c, err)
+		return
+	}
+
+	utils.Success(c, "All domains verified", cfg)
+}
+
+// Delete removes the company's single sign-on settings
+// DELETE /api/company/sso
+func (h *SSOHandler) Delete(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	if err := h.service.Delete(companyID); err != nil {
+		respondSSOError(c, err)
+		return
+	}
+
+	utils.Success(c, "Single sign-on removed", nil)
+}
+
+// respondSSOError maps single sign-on settings errors to HTTP responses
+func respondSSOError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrSSOConfigNotFound):
+		utils.NotFound(c, err.Error())
+	case errors.Is(err, services.ErrInvalidSSOConfig):
+		utils.BadRequest(c, err.Error())
+	default:
+		utils.InternalError(c, "Failed to process single sign-on settings")
+	}
+}
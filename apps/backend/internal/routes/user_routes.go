@@ -61,7 +61,7 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.ChangePassword(user.ID, input); err != nil {
+	if err := h.service.ChangePassword(user.ID, middleware.GetSessionID(c), input); err != nil {
 		utils.BadRequest(c, err.Error())
 		return
 	}
@@ -0,0 +1,177 @@
+package routes
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/dhani/bill-tracker-backend/internal/middleware"
+	"github.com/dhani/bill-tracker-backend/internal/services"
+	"github.com/dhani/bill-tracker-backend/internal/utils"
+)
+
+type WebhookHandler struct {
+	service *services.WebhookService
+}
+
+func NewWebhookHandler(service *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+// List retrieves the company's webhooks
+// GET /api/webhooks
+func (h *WebhookHandler) List(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	webhooks, err := h.service.List(companyID)
+	if err != nil {
+		utils.InternalError(c, "Failed to fetch webhooks")
+		return
+	}
+
+	utils.Success(c, "", webhooks)
+}
+
+// GetByID retrieves a webhook
+// GET /api/webhooks/:id
+func (h *WebhookHandler) GetByID(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	webhookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid webhook ID")
+		return
+	}
+
+	webhook, err := h.service.Get(companyID, webhookID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	utils.Success(c, "", webhook)
+}
+
+// Create creates a webhook
+// POST /api/webhooks
+func (h *WebhookHandler) Create(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	var input services.WebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	webhook, err := h.service.Create(companyID, input)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	utils.Created(c, "Webhook created successfully", webhook)
+}
+
+// Update updates a webhook
+// PUT /api/webhooks/:id
+func (h *WebhookHandler) Update(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	webhookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid webhook ID")
+		return
+	}
+
+	var input services.WebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	webhook, err := h.service.Update(companyID, webhookID, input)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	utils.Success(c, "Webhook updated successfully", webhook)
+}
+
+// Delete deletes a webhook
+// DELETE /api/webhooks/:id
+func (h *WebhookHandler) Delete(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	webhookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid webhook ID")
+		return
+	}
+
+	if err := h.service.Delete(companyID, webhookID); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	utils.Success(c, "Webhook deleted successfully", nil)
+}
+
+// ListDeliveries retrieves a webhook's delivery log
+// GET /api/webhooks/:id/deliveries
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	webhookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid webhook ID")
+		return
+	}
+
+	pagination := utils.GetPagination(c)
+	deliveries, total, err := h.service.ListDeliveries(companyID, webhookID, pagination)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	utils.Paginated(c, deliveries, total, pagination.Page, pagination.PageSize)
+}
+
+// Redeliver queues a delivery to be sent again
+// POST /api/webhooks/:id/deliveries/:deliveryId/redeliver
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	companyID := middleware.GetCompanyID(c)
+
+	webhookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid webhook ID")
+		return
+	}
+	deliveryID, err := uuid.Parse(c.Param("deliveryId"))
+	if err != nil {
+		utils.BadRequest(c, "Invalid delivery ID")
+		return
+	}
+
+	delivery, err := h.service.Redeliver(companyID, webhookID, deliveryID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	utils.Created(c, "Delivery queued", delivery)
+}
+
+func respondWebhookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrWebhookNotFound),
+		errors.Is(err, services.ErrWebhookDeliveryNotFound):
+		utils.NotFound(c, err.Error())
+	case errors.Is(err, services.ErrInvalidWebhook):
+		utils.BadRequest(c, err.Error())
+	default:
+		utils.InternalError(c, err.Error())
+	}
+}
@@ -0,0 +1,84 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/dhani/bill-tracker-backend/internal/services"
+)
+
+// RecurringBillsJob materializes upcoming occurrences of recurring bills
+func RecurringBillsJob(service *services.RecurringService, interval time.Duration) Job {
+	return Job{
+		Name:     "recurring-bills",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			created, err := service.GenerateDue(time.Now())
+			if created > 0 {
+				log.Printf("[scheduler] generated %d recurring bill(s)", created)
+			}
+			return err
+		},
+	}
+}
+
+// OverdueBillsJob marks unpaid bills past their due date as overdue
+func OverdueBillsJob(service *services.OverdueService, interval time.Duration) Job {
+	return Job{
+		Name:     "overdue-bills",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			updated, err := service.SweepOverdue(time.Now())
+			if updated > 0 {
+				log.Printf("[scheduler] marked %d bill(s) as overdue", updated)
+			}
+			return err
+		},
+	}
+}
+
+// ExpiredSessionsJob deletes sessions whose tokens have expired
+func ExpiredSessionsJob(service *services.AuthService, interval time.Duration) Job {
+	return Job{
+		Name:     "expired-sessions",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			purged, err := service.PurgeExpiredSessions(time.Now())
+			if purged > 0 {
+				log.Printf("[scheduler] purged %d expired session(s)", purged)
+			}
+			return err
+		},
+	}
+}
+
+// PaymentRemindersJob emails reminders for bills that are coming due or overdue
+func PaymentRemindersJob(service *services.ReminderService, interval time.Duration) Job {
+	return Job{
+		Name:     "payment-reminders",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			sent, err := service.SendDue(ctx, time.Now())
+			if sent > 0 {
+				log.Printf("[scheduler] sent reminders for %d bill(s)", sent)
+			}
+			return err
+		},
+	}
+}
+
+// WebhookDeliveriesJob sends queued webhook deliveries and retries failed ones
+func WebhookDeliveriesJob(dispatcher *services.WebhookDispatcher, interval time.Duration) Job {
+	return Job{
+		Name:     "webhook-deliveries",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			sent, err := dispatcher.DeliverDue(ctx, time.Now())
+			if sent > 0 {
+				log.Printf("[scheduler] made %d webhook delivery attempt(s)", sent)
+			}
+			return err
+		},
+	}
+}
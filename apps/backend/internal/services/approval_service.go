@@ -0,0 +1,290 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/dhani/bill-tracker-backend/internal/models"
+)
+
+var (
+	ErrApprovalRequired   = errors.New("bill requires approval before it can be paid")
+	ErrNotPendingApproval = errors.New("bill is not pending approval")
+	ErrNotAllowedApprover = errors.New("you are not allowed to approve this bill")
+	ErrAlreadyDecided     = errors.New("you have already reviewed this bill")
+	ErrInvalidPolicy      = errors.New("invalid approval policy")
+	ErrPolicyNotFound     = errors.New("approval policy not found")
+)
+
+type ApprovalService struct {
+	db *gorm.DB
+}
+
+func NewApprovalService(db *gorm.DB) *ApprovalService {
+	return &ApprovalService{db: db}
+}
+
+// ApprovalPolicyInput holds data for creating or updating an approval policy
+type ApprovalPolicyInput struct {
+	AmountAbove       decimal.Decimal `json:"amount_above" binding:"required"`
+	RequiredApprovals int             `json:"required_approvals" binding:"required"`
+	ApproverRole      models.UserRole `json:"approver_role"`
+}
+
+// ApprovalDecisionInput holds the reviewer's comment
+type ApprovalDecisionInput struct {
+	Comment *string `json:"comment"`
+}
+
+// ListPolicies retrieves a company's approval policies ordered by threshold
+func (s *ApprovalService) ListPolicies(companyID uuid.UUID) ([]models.ApprovalPolicy, error) {
+	var policies []models.ApprovalPolicy
+	err := s.db.Where("company_id = ?", companyID).Order("amount_above ASC").Find(&policies).Error
+	return policies, err
+}
+
+// CreatePolicy adds an approval policy
+func (s *ApprovalService) CreatePolicy(companyID uuid.UUID, input ApprovalPolicyInput) (*models.ApprovalPolicy, error) {
+	if err := validatePolicy(&input); err != nil {
+		return nil, err
+	}
+
+	policy := models.ApprovalPolicy{
+		CompanyID:         companyID,
+		AmountAbove:       input.AmountAbove,
+		RequiredApprovals: input.RequiredApprovals,
+		ApproverRole:      input.ApproverRole,
+	}
+	if err := s.db.Create(&policy).Error; err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// UpdatePolicy replaces an approval policy's settings
+func (s *ApprovalService) UpdatePolicy(companyID, policyID uuid.UUID, input ApprovalPolicyInput) (*models.ApprovalPolicy, error) {
+	if err := validatePolicy(&input); err != nil {
+		return nil, err
+	}
+
+	var policy models.ApprovalPolicy
+	if err := s.db.Where("company_id = ? AND id = ?", companyID, policyID).First(&policy).Error; err != nil {
+		return nil, ErrPolicyNotFound
+	}
+
+	if err := s.db.Model(&policy).Updates(map[string]interface{}{
+		"amount_above":       input.AmountAbove,
+		"required_approvals": input.RequiredApprovals,
+		"approver_role":      input.ApproverRole,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.db.First(&policy, "id = ?", policyID).Error; err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// DeletePolicy removes an approval policy
+func (s *ApprovalService) DeletePolicy(companyID, policyID uuid.UUID) error {
+	result := s.db.Where("company_id = ? AND id = ?", companyID, policyID).Delete(&models.ApprovalPolicy{})
+	if result.RowsAffected == 0 {
+		return ErrPolicyNotFound
+	}
+	return result.Error
+}
+
+func validatePolicy(input *ApprovalPolicyInput) error {
+	if input.ApproverRole == "" {
+		input.ApproverRole = models.RoleAdmin
+	}
+	if input.ApproverRole != models.RoleAdmin && input.ApproverRole != models.RoleMember {
+		return fmt.Errorf("%w: unknown approver_role %q", ErrInvalidPolicy, input.ApproverRole)
+	}
+	if input.AmountAbove.IsNegative() {
+		return fmt.Errorf("%w: amount_above cannot be negative", ErrInvalidPolicy)
+	}
+	if input.RequiredApprovals < 1 {
+		return fmt.Errorf("%w: required_approvals must be at least 1", ErrInvalidPolicy)
+	}
+	return nil
+}
+
+// policyFor returns the strictest policy whose threshold the bill's amount
+// exceeds, or nil when the bill needs no approval. Thresholds are in the
+// company's base currency; a bill whose amount cannot be converted for lack
+// of a rate falls under the strictest policy.
+func (s *ApprovalService) policyFor(tx *gorm.DB, bill *models.Bill) (*models.ApprovalPolicy, error) {
+	query := tx.Where("company_id = ?", bill.CompanyID)
+	amount, err := baseAmount(tx, bill)
+	if err != nil && !errors.Is(err, ErrRateNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		query = query.Where("amount_above < ?", amount)
+	}
+
+	var policy models.ApprovalPolicy
+	err = query.Order("amount_above DESC").First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// approvedCount counts distinct approvals in the bill's current review round
+func (s *ApprovalService) approvedCount(tx *gorm.DB, bill *models.Bill) (int64, error) {
+	var count int64
+	err := tx.Model(&models.BillApproval{}).
+		Where("bill_id = ? AND round = ? AND decision = ?", bill.ID, bill.ApprovalRound, models.DecisionApproved).
+		Count(&count).Error
+	return count, err
+}
+
+// RequiresApproval reports whether a bill must be reviewed before it becomes payable
+func (s *ApprovalService) RequiresApproval(tx *gorm.DB, bill *models.Bill) (bool, error) {
+	policy, err := s.policyFor(tx, bill)
+	return policy != nil, err
+}
+
+// Satisfied reports whether the bill has the approvals its amount requires
+func (s *ApprovalService) Satisfied(tx *gorm.DB, bill *models.Bill) (bool, error) {
+	policy, err := s.policyFor(tx, bill)
+	if err != nil || policy == nil {
+		return err == nil, err
+	}
+
+	count, err := s.approvedCount(tx, bill)
+	if err != nil {
+		return false, err
+	}
+	return count >= int64(policy.RequiredApprovals), nil
+}
+
+// Submit moves a bill into review, starting a new approval round
+func (s *ApprovalService) Submit(tx *gorm.DB, bill *models.Bill, userID *uuid.UUID) error {
+	if err := tx.Model(bill).Update("approval_round", bill.ApprovalRound+1).Error; err != nil {
+		return err
+	}
+	return transitionStatus(tx, bill, userID, models.StatusPendingApproval, "submitted for approval")
+}
+
+// ListDecisions retrieves all approval decisions on a bill
+func (s *ApprovalService) ListDecisions(companyID, billID uuid.UUID) ([]models.BillApproval, error) {
+	var approvals []models.BillApproval
+	err := s.db.
+		Preload("User").
+		Joins("JOIN bills ON bills.id = bill_approvals.bill_id").
+		Where("bills.company_id = ? AND bill_approvals.bill_id = ?", companyID, billID).
+		Order("bill_approvals.created_at ASC").
+		Find(&approvals).Error
+	return approvals, err
+}
+
+// Approve records an approval; once enough approvals are collected the bill becomes payable
+func (s *ApprovalService) Approve(companyID, billID uuid.UUID, user *models.User, input ApprovalDecisionInput) (*models.BillApproval, error) {
+	return s.decide(companyID, billID, user, models.DecisionApproved, input)
+}
+
+// Reject records a rejection and returns the bill to draft
+func (s *ApprovalService) Reject(companyID, billID uuid.UUID, user *models.User, input ApprovalDecisionInput) (*models.BillApproval, error) {
+	return s.decide(companyID, billID, user, models.DecisionRejected, input)
+}
+
+func (s *ApprovalService) decide(companyID, billID uuid.UUID, user *models.User, decision models.ApprovalDecision, input ApprovalDecisionInput) (*models.BillApproval, error) {
+	var approval models.BillApproval
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var bill models.Bill
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("company_id = ? AND id = ?", companyID, billID).
+			First(&bill).Error; err != nil {
+			return ErrBillNotFound
+		}
+		if bill.Status != models.StatusPendingApproval {
+			return ErrNotPendingApproval
+		}
+
+		policy, err := s.policyFor(tx, &bill)
+		if err != nil {
+			return err
+		}
+		if policy != nil && user.Role != policy.ApproverRole && user.Role != models.RoleAdmin {
+			return ErrNotAllowedApprover
+		}
+		// Approval is a second pair of eyes, so nobody approves their own bill
+		if decision == models.DecisionApproved && user.ID == bill.UserID {
+			return fmt.Errorf("%w: you created this bill", ErrNotAllowedApprover)
+		}
+
+		var existing int64
+		if err := tx.Model(&models.BillApproval{}).
+			Where("bill_id = ? AND round = ? AND user_id = ?", bill.ID, bill.ApprovalRound, user.ID).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrAlreadyDecided
+		}
+
+		approval = models.BillApproval{
+			BillID:   bill.ID,
+			Round:    bill.ApprovalRound,
+			UserID:   user.ID,
+			Decision: decision,
+			Comment:  input.Comment,
+		}
+		if err := tx.Create(&approval).Error; err != nil {
+			return err
+		}
+
+		if decision == models.DecisionRejected {
+			details := "Rejected by " + user.Name
+			if input.Comment != nil && *input.Comment != "" {
+				details += ": " + *input.Comment
+			}
+			if err := createActivity(tx, bill.ID, &user.ID, models.ActionRejected, details); err != nil {
+				return err
+			}
+			return transitionStatus(tx, &bill, &user.ID, models.StatusDraft, "approval rejected")
+		}
+
+		count, err := s.approvedCount(tx, &bill)
+		if err != nil {
+			return err
+		}
+
+		// Without a matching policy (e.g. it was removed meanwhile) one approval suffices
+		required := int64(1)
+		if policy != nil {
+			required = int64(policy.RequiredApprovals)
+		}
+
+		details := fmt.Sprintf("Approved by %s (%d of %d)", user.Name, count, required)
+		if input.Comment != nil && *input.Comment != "" {
+			details += ": " + *input.Comment
+		}
+		if err := createActivity(tx, bill.ID, &user.ID, models.ActionApproved, details); err != nil {
+			return err
+		}
+
+		if count >= required {
+			return transitionStatus(tx, &bill, &user.ID, models.StatusUnpaid, "approval complete")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &approval, nil
+}
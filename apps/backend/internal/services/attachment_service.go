@@ -0,0 +1,383 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/dhani/bill-tracker-backend/internal/models"
+	"github.com/dhani/bill-tracker-backend/internal/storage"
+)
+
+var (
+	ErrAttachmentNotFound  = errors.New("attachment not found")
+	ErrAttachmentTooLarge  = errors.New("attachment is too large")
+	ErrUnsupportedFileType = errors.New("unsupported file type")
+)
+
+// allowedAttachmentTypes lists the MIME types accepted as bill attachments.
+// Types are detected from the file content, not the name or client header.
+var allowedAttachmentTypes = map[string]bool{
+	"application/pdf": true,
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"image/heic":      true,
+	"image/tiff":      true,
+	"text/plain":      true,
+	"text/csv":        true,
+	"text/xml":        true,
+	"application/xml": true,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":       true,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": true,
+}
+
+type AttachmentService struct {
+	db      *gorm.DB
+	storage storage.Storage
+	signer  *AttachmentSigner
+	maxSize int64
+}
+
+func NewAttachmentService(db *gorm.DB, store storage.Storage, signer *AttachmentSigner, maxSize int64) *AttachmentService {
+	return &AttachmentService{db: db, storage: store, signer: signer, maxSize: maxSize}
+}
+
+// List retrieves a bill's attachments, newest first
+func (s *AttachmentService) List(companyID, billID uuid.UUID) ([]models.BillAttachment, error) {
+	if _, err := s.bill(s.db, companyID, billID); err != nil {
+		return nil, err
+	}
+
+	var attachments []models.BillAttachment
+	err := s.db.
+		Preload("Uploader").
+		Where("bill_id = ?", billID).
+		Order("uploaded_at DESC").
+		Find(&attachments).Error
+	if err != nil {
+		return nil, err
+	}
+
+	s.signer.SignAll(companyID, attachments)
+	return attachments, nil
+}
+
+// Upload stores a file and attaches it to a bill. Files are stored once per
+// company under their SHA-256, so the same document attached to several bills
+// takes space once; uploading it to the same bill again returns the existing
+// attachment with created set to false.
+func (s *AttachmentService) Upload(ctx context.Context, companyID, billID, userID uuid.UUID, filename string, r io.Reader) (*models.BillAttachment, bool, error) {
+	if _, err := s.bill(s.db, companyID, billID); err != nil {
+		return nil, false, err
+	}
+
+	file, err := s.spool(r)
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+
+	key, err := s.store(ctx, companyID, file)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var attachment *models.BillAttachment
+	var created bool
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		attachment, created, err = s.attach(ctx, tx, companyID, billID, userID, filename, file)
+		return err
+	})
+	if err != nil {
+		s.discard(ctx, key)
+		return nil, false, err
+	}
+
+	s.signer.Sign(companyID, attachment)
+	return attachment, created, nil
+}
+
+// spooledFile is an upload copied to a temporary file, hashed and sniffed
+type spooledFile struct {
+	*os.File
+	size        int64
+	sum         string
+	contentType string
+}
+
+func (f *spooledFile) Close() error {
+	defer os.Remove(f.Name())
+	return f.File.Close()
+}
+
+// spool copies r to disk so the content can be hashed and its type checked
+// before anything is stored
+func (s *AttachmentService) spool(r io.Reader) (*spooledFile, error) {
+	tmp, err := os.CreateTemp("", "attachment-*")
+	if err != nil {
+		return nil, err
+	}
+	file := &spooledFile{File: tmp}
+
+	hash := sha256.New()
+	file.size, err = io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(r, s.maxSize+1))
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if file.size > s.maxSize {
+		file.Close()
+		return nil, fmt.Errorf("%w: the limit is %d MB", ErrAttachmentTooLarge, s.maxSize>>20)
+	}
+	if file.size == 0 {
+		file.Close()
+		return nil, fmt.Errorf("%w: file is empty", ErrUnsupportedFileType)
+	}
+	file.sum = hex.EncodeToString(hash.Sum(nil))
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	detected, err := mimetype.DetectReader(tmp)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	file.contentType = detected.String()
+	if i := strings.Index(file.contentType, ";"); i >= 0 {
+		file.contentType = file.contentType[:i]
+	}
+	if !allowedAttachmentTypes[file.contentType] {
+		file.Close()
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFileType, file.contentType)
+	}
+
+	return file, nil
+}
+
+// store puts a spooled file into storage unless the company already has it
+// and returns its key. This happens before the database transaction that
+// records the attachment; if that fails, discard removes the file again.
+func (s *AttachmentService) store(ctx context.Context, companyID uuid.UUID, file *spooledFile) (string, error) {
+	key := storageKey(companyID, file.sum)
+	stored, err := s.storage.Exists(ctx, key)
+	if err != nil || stored {
+		return key, err
+	}
+	return key, s.put(ctx, key, file)
+}
+
+func (s *AttachmentService) put(ctx context.Context, key string, file *spooledFile) error {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return s.storage.Put(ctx, key, file, file.size, file.contentType)
+}
+
+// attach records a stored file on the bill within tx
+func (s *AttachmentService) attach(ctx context.Context, tx *gorm.DB, companyID, billID, userID uuid.UUID, filename string, file *spooledFile) (*models.BillAttachment, bool, error) {
+	var existing models.BillAttachment
+	err := tx.Where("bill_id = ? AND sha256 = ?", billID, file.sum).First(&existing).Error
+	if err == nil {
+		return &existing, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	key := storageKey(companyID, file.sum)
+	if err := lockStorageKey(tx, key); err != nil {
+		return nil, false, err
+	}
+	// Deleting another attachment of the same content may have removed the
+	// file since it was stored
+	stored, err := s.storage.Exists(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+	if !stored {
+		if err := s.put(ctx, key, file); err != nil {
+			return nil, false, err
+		}
+	}
+
+	attachment := models.BillAttachment{
+		ID:         uuid.New(),
+		BillID:     billID,
+		Filename:   sanitizeFilename(filename),
+		FileType:   file.contentType,
+		FileSize:   file.size,
+		SHA256:     file.sum,
+		StorageKey: key,
+		UploadedBy: &userID,
+	}
+	attachment.FileURL = fmt.Sprintf("/api/bills/%s/attachments/%s/download", billID, attachment.ID)
+
+	if err := tx.Create(&attachment).Error; err != nil {
+		return nil, false, err
+	}
+	if err := createActivity(tx, billID, &userID, models.ActionAttachmentAdded, "Attached "+attachment.Filename); err != nil {
+		return nil, false, err
+	}
+	return &attachment, true, nil
+}
+
+// Open returns an attachment and a reader for its content; the caller must close it
+func (s *AttachmentService) Open(ctx context.Context, companyID, billID, attachmentID uuid.UUID) (*models.BillAttachment, io.ReadCloser, error) {
+	attachment, err := s.get(companyID, billID, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.open(ctx, attachment)
+}
+
+// OpenSigned opens an attachment through a signed download link. The link is
+// verified before the database is touched.
+func (s *AttachmentService) OpenSigned(ctx context.Context, companyID, attachmentID uuid.UUID, expires int64, signature string) (*models.BillAttachment, io.ReadCloser, error) {
+	if err := s.signer.Verify(attachmentID, companyID, expires, signature); err != nil {
+		return nil, nil, err
+	}
+
+	var attachment models.BillAttachment
+	if err := s.scoped(companyID).Where("bill_attachments.id = ?", attachmentID).First(&attachment).Error; err != nil {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	return s.open(ctx, &attachment)
+}
+
+func (s *AttachmentService) open(ctx context.Context, attachment *models.BillAttachment) (*models.BillAttachment, io.ReadCloser, error) {
+	content, err := s.storage.Get(ctx, attachment.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, content, nil
+}
+
+// Delete removes an attachment. The stored file is deleted once no other
+// attachment in the company refers to the same content.
+func (s *AttachmentService) Delete(ctx context.Context, companyID, billID, attachmentID, userID uuid.UUID) error {
+	attachment, err := s.get(companyID, billID, attachmentID)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(attachment).Error; err != nil {
+			return err
+		}
+		if err := createActivity(tx, billID, &userID, models.ActionAttachmentRemoved, "Removed "+attachment.Filename); err != nil {
+			return err
+		}
+		return s.release(ctx, tx, attachment.StorageKey)
+	})
+}
+
+// release deletes a stored file once no attachment in tx refers to it. The
+// key stays locked until commit, so an upload of the same content cannot
+// find the file, skip storing it and then lose it to this delete.
+func (s *AttachmentService) release(ctx context.Context, tx *gorm.DB, key string) error {
+	if err := lockStorageKey(tx, key); err != nil {
+		return err
+	}
+	var remaining int64
+	if err := tx.Model(&models.BillAttachment{}).Where("storage_key = ?", key).Count(&remaining).Error; err != nil {
+		return err
+	}
+	if remaining == 0 {
+		if err := s.storage.Delete(ctx, key); err != nil {
+			// The attachment is gone either way; an orphaned file only costs space
+			log.Printf("failed to delete stored attachment %s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// discard removes a file stored for an attachment that was never recorded
+func (s *AttachmentService) discard(ctx context.Context, key string) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return s.release(ctx, tx, key)
+	})
+	if err != nil {
+		log.Printf("failed to discard stored attachment %s: %v", key, err)
+	}
+}
+
+// storageKey names a company's stored copy of some content
+func storageKey(companyID uuid.UUID, sum string) string {
+	return companyID.String() + "/" + sum
+}
+
+// lockStorageKey serializes uploads and deletes of the same stored file for
+// the rest of the transaction
+func lockStorageKey(tx *gorm.DB, key string) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", key).Error
+}
+
+func (s *AttachmentService) bill(tx *gorm.DB, companyID, billID uuid.UUID) (*models.Bill, error) {
+	var bill models.Bill
+	if err := tx.Select("id", "company_id").Where("company_id = ? AND id = ?", companyID, billID).First(&bill).Error; err != nil {
+		return nil, ErrBillNotFound
+	}
+	return &bill, nil
+}
+
+func (s *AttachmentService) get(companyID, billID, attachmentID uuid.UUID) (*models.BillAttachment, error) {
+	var attachment models.BillAttachment
+	err := s.scoped(companyID).
+		Where("bill_attachments.bill_id = ? AND bill_attachments.id = ?", billID, attachmentID).
+		First(&attachment).Error
+	if err != nil {
+		return nil, ErrAttachmentNotFound
+	}
+	return &attachment, nil
+}
+
+// scoped limits attachment queries to live bills of a company
+func (s *AttachmentService) scoped(companyID uuid.UUID) *gorm.DB {
+	return s.db.
+		Joins("JOIN bills ON bills.id = bill_attachments.bill_id AND bills.deleted_at IS NULL").
+		Where("bills.company_id = ?", companyID)
+}
+
+// sanitizeFilename keeps the base name of an uploaded file and strips
+// characters that would break a Content-Disposition header
+func sanitizeFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == '"' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == "/" {
+		name = "attachment"
+	}
+	if len(name) > 255 {
+		// Keep the end, where the extension is, without splitting a character
+		start := len(name) - 255
+		for start < len(name) && !utf8.RuneStart(name[start]) {
+			start++
+		}
+		name = name[start:]
+	}
+	return name
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dhani/bill-tracker-backend/internal/models"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid download signature")
+	ErrSignatureExpired = errors.New("download link has expired")
+)
+
+// AttachmentSigner issues and verifies short-lived download links. A link
+// carries the company and an expiry and is signed with HMAC-SHA256, so it
+// can be used without a bearer token (e.g. in an <img> or <iframe>) but
+// only for one attachment, in one company, until it expires.
+type AttachmentSigner struct {
+	key []byte
+	ttl time.Duration
+}
+
+func NewAttachmentSigner(secret string, ttl time.Duration) *AttachmentSigner {
+	// Derive a key of its own so download links can never be confused with
+	// other values signed with the same secret
+	return &AttachmentSigner{key: hmacSHA256([]byte(secret), "attachment-download"), ttl: ttl}
+}
+
+// Sign fills the attachment's download URL with a link valid for the signer's TTL
+func (s *AttachmentSigner) Sign(companyID uuid.UUID, attachment *models.BillAttachment) {
+	expires := time.Now().Add(s.ttl).Truncate(time.Second)
+	query := url.Values{
+		"company":   {companyID.String()},
+		"expires":   {strconv.FormatInt(expires.Unix(), 10)},
+		"signature": {s.signature(attachment.ID, companyID, expires.Unix())},
+	}
+	attachment.DownloadURL = fmt.Sprintf("/api/attachments/%s/download?%s", attachment.ID, query.Encode())
+	attachment.DownloadURLExpiresAt = &expires
+}
+
+// SignAll signs every attachment in the slice
+func (s *AttachmentSigner) SignAll(companyID uuid.UUID, attachments []models.BillAttachment) {
+	for i := range attachments {
+		s.Sign(companyID, &attachments[i])
+	}
+}
+
+// Verify checks a signature produced by Sign and that the link has not expired
+func (s *AttachmentSigner) Verify(attachmentID, companyID uuid.UUID, expires int64, signature string) error {
+	expected := s.signature(attachmentID, companyID, expires)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ErrInvalidSignature
+	}
+	if time.Now().Unix() > expires {
+		return ErrSignatureExpired
+	}
+	return nil
+}
+
+func (s *AttachmentSigner) signature(attachmentID, companyID uuid.UUID, expires int64) string {
+	return hex.EncodeToString(hmacSHA256(s.key, fmt.Sprintf("%s\n%s\n%d", attachmentID, companyID, expires)))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
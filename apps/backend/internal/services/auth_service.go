@@ -7,11 +7,31 @@ import (
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"github.com/dhani/bill-tracker-backend/internal/config"
 	"github.com/dhani/bill-tracker-backend/internal/middleware"
 	"github.com/dhani/bill-tracker-backend/internal/models"
 )
 
+var (
+	// ErrSessionNotFound is returned when a session does not exist or belongs to another user
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrInvalidRefreshToken is returned for unknown, expired or revoked refresh tokens
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused is returned when a rotated refresh token is presented
+	// again; the session it belongs to has been revoked
+	ErrRefreshTokenReused = errors.New("refresh token was already used, session revoked")
+)
+
+const (
+	// maxUserAgentLength bounds the user agent stored with a session
+	maxUserAgentLength = 512
+	// refreshReuseGrace lets a client that refreshed twice concurrently, e.g.
+	// from two tabs, present the previous token without losing the session
+	refreshReuseGrace = 10 * time.Second
+)
+
 type AuthService struct {
 	db *gorm.DB
 }
@@ -34,20 +54,57 @@ type LoginInput struct {
 	Password string `json:"password" binding:"required"`
 }
 
-// AuthResponse is returned after successful auth
+// SessionMeta describes the client a session is created for
+type SessionMeta struct {
+	IPAddress string
+	UserAgent string
+}
+
+// RefreshInput holds a refresh token to exchange
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// columns returns the meta as nullable session columns
+func (m SessionMeta) columns() (ipAddress, userAgent *string) {
+	if m.IPAddress != "" {
+		ip := m.IPAddress
+		ipAddress = &ip
+	}
+	if m.UserAgent != "" {
+		ua := m.UserAgent
+		if len(ua) > maxUserAgentLength {
+			ua = ua[:maxUserAgentLength]
+		}
+		userAgent = &ua
+	}
+	return ipAddress, userAgent
+}
+
+// AuthResponse is returned after successful auth. Token is a short-lived
+// access token; RefreshToken obtains a new pair from POST /api/auth/refresh.
 type AuthResponse struct {
-	Token string       `json:"token"`
-	User  *models.User `json:"user"`
+	Token        string       `json:"token"`
+	ExpiresAt    time.Time    `json:"expires_at"`
+	RefreshToken string       `json:"refresh_token"`
+	User         *models.User `json:"user"`
 }
 
 // Register creates a new user and company
-func (s *AuthService) Register(input RegisterInput) (*AuthResponse, error) {
+func (s *AuthService) Register(input RegisterInput, meta SessionMeta) (*AuthResponse, error) {
 	// Check if email already exists
 	var existingUser models.User
 	if err := s.db.Where("email = ?", input.Email).First(&existingUser).Error; err == nil {
 		return nil, errors.New("email already registered")
 	}
 
+	// Addresses of a company that enforces SSO are provisioned by signing in
+	if enforced, err := ssoEnforcedForEmail(s.db, input.Email); err != nil {
+		return nil, err
+	} else if enforced {
+		return nil, ErrSSORequired
+	}
+
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -74,20 +131,11 @@ func (s *AuthService) Register(input RegisterInput) (*AuthResponse, error) {
 		return nil, errors.New("failed to create user")
 	}
 
-	// Generate token
-	token, err := middleware.GenerateToken(&user)
-	if err != nil {
-		return nil, errors.New("failed to generate token")
-	}
-
-	return &AuthResponse{
-		Token: token,
-		User:  &user,
-	}, nil
+	return s.createSession(&user, models.SignInPassword, meta)
 }
 
 // Login authenticates a user
-func (s *AuthService) Login(input LoginInput) (*AuthResponse, error) {
+func (s *AuthService) Login(input LoginInput, meta SessionMeta) (*AuthResponse, error) {
 	var user models.User
 	if err := s.db.Where("email = ?", input.Email).First(&user).Error; err != nil {
 		return nil, errors.New("invalid email or password")
@@ -98,29 +146,234 @@ func (s *AuthService) Login(input LoginInput) (*AuthResponse, error) {
 		return nil, errors.New("invalid email or password")
 	}
 
-	// Generate token
-	token, err := middleware.GenerateToken(&user)
+	if enforced, err := ssoEnforced(s.db, user.CompanyID); err != nil {
+		return nil, err
+	} else if enforced {
+		return nil, ErrSSORequired
+	}
+
+	if err := requireVerifiedEmail(s.db, user.ID, models.EmailVerificationPolicy.BlocksLogin); err != nil {
+		return nil, err
+	}
+
+	return s.createSession(&user, models.SignInPassword, meta)
+}
+
+// createSession stores a session for the user and issues its tokens
+func (s *AuthService) createSession(user *models.User, method string, meta SessionMeta) (*AuthResponse, error) {
+	session := models.Session{ID: uuid.New(), UserID: user.ID, SignInMethod: &method}
+	session.IPAddress, session.UserAgent = meta.columns()
+
+	refreshToken, hash, err := newRefreshToken(session.ID)
 	if err != nil {
 		return nil, errors.New("failed to generate token")
 	}
+	session.RefreshTokenHash = &hash
+	session.ExpiresAt = time.Now().Add(config.AppConfig.RefreshTokenTTL)
 
-	// Create session
-	session := models.Session{
-		UserID:    user.ID,
-		Token:     token,
-		ExpiresAt: time.Now().Add(24 * time.Hour),
+	if err := s.db.Create(&session).Error; err != nil {
+		return nil, errors.New("failed to create session")
+	}
+
+	return s.issueTokens(user, &session, refreshToken)
+}
+
+// issueTokens signs an access token for the session and pairs it with refreshToken
+func (s *AuthService) issueTokens(user *models.User, session *models.Session, refreshToken string) (*AuthResponse, error) {
+	expiresAt := time.Now().Add(time.Duration(config.AppConfig.JWTExpirationHours) * time.Hour)
+	if expiresAt.After(session.ExpiresAt) {
+		expiresAt = session.ExpiresAt
+	}
+
+	token, err := middleware.GenerateToken(user, session.ID, expiresAt)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
 	}
-	s.db.Create(&session)
 
 	return &AuthResponse{
-		Token: token,
-		User:  &user,
+		Token:        token,
+		ExpiresAt:    expiresAt,
+		RefreshToken: refreshToken,
+		User:         user,
 	}, nil
 }
 
-// Logout invalidates a session
-func (s *AuthService) Logout(token string) error {
-	return s.db.Where("token = ?", token).Delete(&models.Session{}).Error
+// Refresh exchanges a refresh token for a new access token and a rotated
+// refresh token. A token that was already rotated away means it leaked or
+// was replayed, so the whole session is revoked; any other secret is invalid.
+func (s *AuthService) Refresh(input RefreshInput, meta SessionMeta) (*AuthResponse, error) {
+	sessionID, hash, err := parseRefreshToken(input.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	var session models.Session
+	var refreshToken string
+	reused := false
+	revoked := false
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&session, "id = ?", sessionID).Error; err != nil {
+			return ErrInvalidRefreshToken
+		}
+
+		now := time.Now()
+		if !refreshHashMatches(session.RefreshTokenHash, hash) {
+			// The token this one was rotated into was issued moments ago; a
+			// concurrent refresh from the same client, not a replay
+			if refreshHashMatches(session.PreviousRefreshHash, hash) &&
+				session.RefreshedAt != nil && now.Sub(*session.RefreshedAt) < refreshReuseGrace {
+				return ErrInvalidRefreshToken
+			}
+			var used int64
+			if err := tx.Model(&models.UsedRefreshToken{}).
+				Where("session_id = ? AND hash = ?", session.ID, hash).
+				Count(&used).Error; err != nil {
+				return err
+			}
+			if used == 0 {
+				return ErrInvalidRefreshToken
+			}
+			reused = true
+			return tx.Delete(&session).Error
+		}
+		if session.IsExpired() {
+			return ErrInvalidRefreshToken
+		}
+
+		if err := tx.First(&user, "id = ?", session.UserID).Error; err != nil {
+			return ErrInvalidRefreshToken
+		}
+		if err := requireVerifiedEmail(tx, user.ID, models.EmailVerificationPolicy.BlocksLogin); err != nil {
+			return err
+		}
+		if session.SignInMethod == nil || *session.SignInMethod != models.SignInSSO {
+			// The company may have enforced SSO since the session started
+			if enforced, err := ssoEnforced(tx, user.CompanyID); err != nil {
+				return err
+			} else if enforced {
+				revoked = true
+				return tx.Delete(&session).Error
+			}
+		}
+
+		var newHash string
+		refreshToken, newHash, err = newRefreshToken(session.ID)
+		if err != nil {
+			return err
+		}
+
+		expiresAt := now.Add(config.AppConfig.RefreshTokenTTL)
+		updates := map[string]interface{}{
+			"refresh_token_hash":    newHash,
+			"previous_refresh_hash": hash,
+			"refreshed_at":          now,
+			"last_used_at":          now,
+			"expires_at":            expiresAt,
+		}
+		if ip, userAgent := meta.columns(); ip != nil || userAgent != nil {
+			updates["ip_address"] = ip
+			updates["user_agent"] = userAgent
+		}
+		if err := tx.Model(&session).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.UsedRefreshToken{Hash: hash, SessionID: session.ID}).Error; err != nil {
+			return err
+		}
+		session.ExpiresAt = expiresAt
+		return nil
+	})
+	if reused {
+		middleware.ForgetSession(sessionID)
+		return nil, ErrRefreshTokenReused
+	}
+	if revoked && err == nil {
+		middleware.ForgetSession(sessionID)
+		return nil, ErrSSORequired
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(&user, &session, refreshToken)
+}
+
+// Logout ends the session the access token was issued for. Clients whose
+// access token already expired can identify the session by refresh token.
+func (s *AuthService) Logout(token, refreshToken string) error {
+	if claims, err := middleware.ValidateToken(token); err == nil {
+		if sessionID, err := claims.SessionID(); err == nil {
+			middleware.ForgetSession(sessionID)
+			return s.db.Where("id = ? AND user_id = ?", sessionID, claims.UserID).Delete(&models.Session{}).Error
+		}
+	}
+
+	if refreshToken == "" {
+		// An invalid or expired token has no session left to end
+		return nil
+	}
+	sessionID, hash, err := parseRefreshToken(refreshToken)
+	if err != nil {
+		return nil
+	}
+	middleware.ForgetSession(sessionID)
+	return s.db.Where("id = ? AND refresh_token_hash = ?", sessionID, hash).Delete(&models.Session{}).Error
+}
+
+// ListSessions returns the user's active sessions, most recently used first.
+// currentID marks the session the request was made with.
+func (s *AuthService) ListSessions(userID, currentID uuid.UUID) ([]models.Session, error) {
+	var sessions []models.Session
+	err := s.db.
+		Where("user_id = ? AND expires_at > ?", userID, time.Now()).
+		Order("COALESCE(last_used_at, created_at) DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+	return sessions, nil
+}
+
+// RevokeSession ends one of the user's sessions
+func (s *AuthService) RevokeSession(userID, sessionID uuid.UUID) error {
+	result := s.db.Where("id = ? AND user_id = ?", sessionID, userID).Delete(&models.Session{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+
+	middleware.ForgetSession(sessionID)
+	return nil
+}
+
+// RevokeOtherSessions ends all of the user's sessions except the current one,
+// logging out every other device
+func (s *AuthService) RevokeOtherSessions(userID, currentID uuid.UUID) (int64, error) {
+	result := s.db.Where("user_id = ? AND id <> ?", userID, currentID).Delete(&models.Session{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	middleware.ForgetUserSessions(userID, currentID)
+	return result.RowsAffected, nil
+}
+
+// PurgeExpiredSessions deletes sessions whose tokens have expired
+func (s *AuthService) PurgeExpiredSessions(now time.Time) (int64, error) {
+	result := s.db.Where("expires_at <= ?", now).Delete(&models.Session{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
 }
 
 // GetUserByID retrieves a user by ID
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/dhani/bill-tracker-backend/internal/models"
+)
+
+var (
+	ErrDuplicateInvoiceNumber = errors.New("a bill with this invoice number already exists for this vendor")
+	ErrPossibleDuplicate      = errors.New("bill looks like a duplicate of an existing bill, resend with confirm_duplicate to create it anyway")
+)
+
+// duplicateWindowDays is how far apart two due dates may be for bills from the
+// same vendor with the same amount to count as likely duplicates
+const duplicateWindowDays = 7
+
+// DuplicateBillError carries the IDs of the existing bills a bill conflicts with
+type DuplicateBillError struct {
+	Err     error
+	BillIDs []uuid.UUID
+}
+
+func (e *DuplicateBillError) Error() string { return e.Err.Error() }
+
+func (e *DuplicateBillError) Unwrap() error { return e.Err }
+
+// checkInvoiceNumber enforces (company, vendor, invoice number) uniqueness.
+// The database index does the same, but treats bills without a vendor as
+// distinct; here they are compared like any other vendor.
+func checkInvoiceNumber(tx *gorm.DB, bill *models.Bill) error {
+	if bill.InvoiceNumber == nil || *bill.InvoiceNumber == "" {
+		return nil
+	}
+
+	query := tx.Model(&models.Bill{}).
+		Where("company_id = ? AND invoice_number = ? AND id <> ?", bill.CompanyID, *bill.InvoiceNumber, bill.ID)
+	if bill.VendorID != nil {
+		query = query.Where("vendor_id = ?", *bill.VendorID)
+	} else {
+		query = query.Where("vendor_id IS NULL")
+	}
+
+	var ids []uuid.UUID
+	if err := query.Limit(10).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) > 0 {
+		return &DuplicateBillError{Err: ErrDuplicateInvoiceNumber, BillIDs: ids}
+	}
+	return nil
+}
+
+// findLikelyDuplicates returns bills from the same vendor with the same amount
+// and currency due within duplicateWindowDays of the bill's due date
+func findLikelyDuplicates(tx *gorm.DB, bill *models.Bill) ([]uuid.UUID, error) {
+	if bill.VendorID == nil {
+		return nil, nil
+	}
+
+	var ids []uuid.UUID
+	err := tx.Model(&models.Bill{}).
+		Where("company_id = ? AND vendor_id = ? AND id <> ?", bill.CompanyID, *bill.VendorID, bill.ID).
+		Where("amount = ? AND currency = ?", bill.Amount, bill.Currency).
+		Where("due_date BETWEEN ? AND ?",
+			bill.DueDate.AddDate(0, 0, -duplicateWindowDays), bill.DueDate.AddDate(0, 0, duplicateWindowDays)).
+		Order("due_date ASC").
+		Limit(10).
+		Pluck("id", &ids).Error
+	return ids, err
+}
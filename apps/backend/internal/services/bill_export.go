@@ -0,0 +1,207 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dhani/bill-tracker-backend/internal/models"
+	"github.com/dhani/bill-tracker-backend/internal/spreadsheet"
+)
+
+var (
+	ErrInvalidExportFormat = errors.New("invalid export format, expected csv, xlsx or json")
+	ErrInvalidExportColumn = errors.New("invalid export column")
+)
+
+// exportColumn renders one column of a bill export
+type exportColumn struct {
+	numeric bool
+	value   func(bill *models.Bill, names exportNames) string
+}
+
+// exportNames resolves vendor and category IDs to names without a join per row
+type exportNames struct {
+	vendors    map[uuid.UUID]string
+	categories map[uuid.UUID]string
+}
+
+func optionalString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func optionalDate(value *time.Time) string {
+	if value == nil {
+		return ""
+	}
+	return value.Format("2006-01-02")
+}
+
+// exportColumns lists every column an export can contain, keyed by name
+var exportColumns = map[string]exportColumn{
+	"id":    {value: func(b *models.Bill, _ exportNames) string { return b.ID.String() }},
+	"title": {value: func(b *models.Bill, _ exportNames) string { return b.Title }},
+	"vendor": {value: func(b *models.Bill, n exportNames) string {
+		if b.VendorID == nil {
+			return ""
+		}
+		return n.vendors[*b.VendorID]
+	}},
+	"category": {value: func(b *models.Bill, n exportNames) string {
+		if b.CategoryID == nil {
+			return ""
+		}
+		return n.categories[*b.CategoryID]
+	}},
+	"invoice_number":      {value: func(b *models.Bill, _ exportNames) string { return optionalString(b.InvoiceNumber) }},
+	"amount":              {numeric: true, value: func(b *models.Bill, _ exportNames) string { return b.Amount.StringFixed(2) }},
+	"amount_paid":         {numeric: true, value: func(b *models.Bill, _ exportNames) string { return b.AmountPaid.StringFixed(2) }},
+	"outstanding_balance": {numeric: true, value: func(b *models.Bill, _ exportNames) string { return b.Outstanding().StringFixed(2) }},
+	"currency":            {value: func(b *models.Bill, _ exportNames) string { return b.Currency }},
+	"due_date":            {value: func(b *models.Bill, _ exportNames) string { return b.DueDate.Format("2006-01-02") }},
+	"paid_date":           {value: func(b *models.Bill, _ exportNames) string { return optionalDate(b.PaidDate) }},
+	"status":              {value: func(b *models.Bill, _ exportNames) string { return string(b.Status) }},
+	"is_recurring":        {value: func(b *models.Bill, _ exportNames) string { return strconv.FormatBool(b.IsRecurring) }},
+	"recurring_frequency": {value: func(b *models.Bill, _ exportNames) string {
+		if b.RecurringFrequency == nil {
+			return ""
+		}
+		return string(*b.RecurringFrequency)
+	}},
+	"payment_method": {value: func(b *models.Bill, _ exportNames) string { return optionalString(b.PaymentMethod) }},
+	"notes":          {value: func(b *models.Bill, _ exportNames) string { return optionalString(b.Notes) }},
+	"created_at":     {value: func(b *models.Bill, _ exportNames) string { return b.CreatedAt.UTC().Format(time.RFC3339) }},
+}
+
+// DefaultExportColumns is the column set used when none is requested
+var DefaultExportColumns = []string{
+	"id", "title", "vendor", "category", "invoice_number", "amount", "amount_paid",
+	"outstanding_balance", "currency", "due_date", "paid_date", "status", "payment_method", "notes", "created_at",
+}
+
+// ExportOptions selects the output format and columns of a bill export
+type ExportOptions struct {
+	Format  string
+	Columns []string
+}
+
+// ValidateExport checks the format and columns so errors can be reported
+// before any output has been written
+func ValidateExport(options *ExportOptions) error {
+	options.Format = strings.ToLower(options.Format)
+	if options.Format == "" {
+		options.Format = "csv"
+	}
+	if _, ok := spreadsheet.ContentTypes[options.Format]; !ok {
+		return ErrInvalidExportFormat
+	}
+
+	if len(options.Columns) == 0 {
+		options.Columns = DefaultExportColumns
+	}
+	for _, name := range options.Columns {
+		if _, ok := exportColumns[name]; !ok {
+			return fmt.Errorf("%w: %q", ErrInvalidExportColumn, name)
+		}
+	}
+	return nil
+}
+
+// Export streams every bill matching the list filters to w. Rows are read
+// from a database cursor and written one at a time, so memory use does not
+// grow with the number of bills. Nothing is written to w until the first row
+// has been read, so errors up to then can still be reported normally.
+func (s *BillService) Export(companyID uuid.UUID, filters BillFilters, options ExportOptions, w io.Writer) error {
+	if err := ValidateExport(&options); err != nil {
+		return err
+	}
+
+	names, err := s.exportNames(companyID)
+	if err != nil {
+		return err
+	}
+
+	columns := make([]spreadsheet.Column, len(options.Columns))
+	renderers := make([]exportColumn, len(options.Columns))
+	for i, name := range options.Columns {
+		renderers[i] = exportColumns[name]
+		columns[i] = spreadsheet.Column{Name: name, Numeric: renderers[i].numeric}
+	}
+
+	orderBy, err := filters.Pagination.OrderBy(BillSortFields)
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.filtered(companyID, filters).
+		Clauses(orderBy).
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	next := func(bill *models.Bill) (bool, error) {
+		if !rows.Next() {
+			return false, rows.Err()
+		}
+		*bill = models.Bill{}
+		return true, s.db.ScanRows(rows, bill)
+	}
+
+	var bill models.Bill
+	more, err := next(&bill)
+	if err != nil {
+		return err
+	}
+
+	writer, err := spreadsheet.NewWriter(options.Format, w, columns)
+	if err != nil {
+		return err
+	}
+
+	values := make([]string, len(renderers))
+	for more {
+		for i, column := range renderers {
+			values[i] = column.value(&bill, names)
+		}
+		if err := writer.WriteRow(values); err != nil {
+			return err
+		}
+		if more, err = next(&bill); err != nil {
+			return err
+		}
+	}
+
+	return writer.Close()
+}
+
+func (s *BillService) exportNames(companyID uuid.UUID) (exportNames, error) {
+	names := exportNames{vendors: make(map[uuid.UUID]string), categories: make(map[uuid.UUID]string)}
+
+	var vendors []models.Vendor
+	if err := s.db.Unscoped().Select("id", "name").Where("company_id = ?", companyID).Find(&vendors).Error; err != nil {
+		return names, err
+	}
+	for _, vendor := range vendors {
+		names.vendors[vendor.ID] = vendor.Name
+	}
+
+	var categories []models.Category
+	if err := s.db.Unscoped().Select("id", "name").Where("company_id = ?", companyID).Find(&categories).Error; err != nil {
+		return names, err
+	}
+	for _, category := range categories {
+		names.categories[category.ID] = category.Name
+	}
+
+	return names, nil
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"github.com/dhani/bill-tracker-backend/internal/models"
+)
+
+// ErrBillNotYetCreated is returned when a bill is requested as of a time before it existed
+var ErrBillNotYetCreated = errors.New("bill did not exist at the requested time")
+
+// createActivity writes an activity log entry using the given connection
+func createActivity(tx *gorm.DB, billID uuid.UUID, userID *uuid.UUID, action models.ActivityAction, details string) error {
+	return createChangeActivity(tx, billID, userID, action, details, nil)
+}
+
+// createChangeActivity writes an activity log entry carrying a field-level diff
+func createChangeActivity(tx *gorm.DB, billID uuid.UUID, userID *uuid.UUID, action models.ActivityAction, details string, changes models.FieldChanges) error {
+	activity := models.BillActivity{
+		BillID:  billID,
+		UserID:  userID,
+		Action:  action,
+		Details: &details,
+		Changes: changes,
+	}
+	return tx.Create(&activity).Error
+}
+
+// billColumnValues returns the current value of every column that can be
+// changed through BillService.Update, keyed by column name
+func billColumnValues(bill *models.Bill) map[string]interface{} {
+	return map[string]interface{}{
+		"title":               bill.Title,
+		"vendor_id":           bill.VendorID,
+		"category_id":         bill.CategoryID,
+		"invoice_number":      bill.InvoiceNumber,
+		"amount":              bill.Amount,
+		"amount_paid":         bill.AmountPaid,
+		"currency":            bill.Currency,
+		"due_date":            bill.DueDate,
+		"paid_date":           bill.PaidDate,
+		"status":              bill.Status,
+		"is_recurring":        bill.IsRecurring,
+		"recurring_frequency": bill.RecurringFrequency,
+		"recurring_day":       bill.RecurringDay,
+		"payment_method":      bill.PaymentMethod,
+		"notes":               bill.Notes,
+	}
+}
+
+// diffBill compares the bill's current values with an updates map and
+// returns one FieldChange per column whose value actually changes
+func diffBill(bill *models.Bill, updates map[string]interface{}) models.FieldChanges {
+	current := billColumnValues(bill)
+
+	fields := make([]string, 0, len(updates))
+	for field := range updates {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var changes models.FieldChanges
+	for _, field := range fields {
+		oldValue, newValue := current[field], updates[field]
+		if sameJSON(oldValue, newValue) {
+			continue
+		}
+		changes = append(changes, models.FieldChange{Field: field, Old: oldValue, New: newValue})
+	}
+	return changes
+}
+
+// removeChange drops the change for a field from a diff
+func removeChange(changes models.FieldChanges, field string) models.FieldChanges {
+	result := changes[:0]
+	for _, change := range changes {
+		if change.Field != field {
+			result = append(result, change)
+		}
+	}
+	return result
+}
+
+// changedFields lists the field names of a diff for human readable details
+func changedFields(changes models.FieldChanges) string {
+	fields := make([]string, len(changes))
+	for i, change := range changes {
+		fields[i] = change.Field
+	}
+	return strings.Join(fields, ", ")
+}
+
+func sameJSON(a, b interface{}) bool {
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(aJSON, bJSON)
+}
+
+// GetAsOf renders a bill as it looked at the given time by taking its
+// current state and undoing, newest first, every change logged after `at`
+func (s *BillService) GetAsOf(companyID, billID uuid.UUID, at time.Time) (map[string]interface{}, error) {
+	var bill models.Bill
+	if err := s.db.
+		Preload("LineItems", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position ASC")
+		}).
+		Where("company_id = ? AND id = ?", companyID, billID).
+		First(&bill).Error; err != nil {
+		return nil, ErrBillNotFound
+	}
+	if bill.CreatedAt.After(at) {
+		return nil, ErrBillNotYetCreated
+	}
+
+	var activities []models.BillActivity
+	if err := s.db.
+		Where("bill_id = ? AND created_at > ? AND changes IS NOT NULL", billID, at).
+		Order("created_at DESC").
+		Find(&activities).Error; err != nil {
+		return nil, err
+	}
+
+	snapshot, err := toJSONMap(bill)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range []string{"user", "vendor", "category", "attachments", "activities", "payments"} {
+		delete(snapshot, key)
+	}
+
+	for _, activity := range activities {
+		for i := len(activity.Changes) - 1; i >= 0; i-- {
+			change := activity.Changes[i]
+			snapshot[change.Field] = change.Old
+		}
+	}
+
+	// Recompute derived values from the reconstructed columns
+	amount, errAmount := decimal.NewFromString(toString(snapshot["amount"]))
+	amountPaid, errPaid := decimal.NewFromString(toString(snapshot["amount_paid"]))
+	if errAmount == nil && errPaid == nil {
+		snapshot["outstanding_balance"] = amount.Sub(amountPaid)
+	}
+	snapshot["as_of"] = at
+
+	return snapshot, nil
+}
+
+func toJSONMap(value interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]interface{})
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return "0"
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
@@ -0,0 +1,428 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"github.com/dhani/bill-tracker-backend/internal/models"
+	"github.com/dhani/bill-tracker-backend/internal/spreadsheet"
+)
+
+var (
+	ErrInvalidImportFile = errors.New("invalid import file")
+	ErrInvalidMapping    = errors.New("invalid column mapping")
+	ErrImportRowsInvalid = errors.New("import contains invalid rows")
+)
+
+// maxImportRows bounds a single import so it fits comfortably in one transaction
+const maxImportRows = 5000
+
+// importFields lists the bill fields a column can be mapped to
+var importFields = []string{
+	"title", "amount", "due_date", "currency", "vendor", "category",
+	"invoice_number", "status", "payment_method", "notes",
+}
+
+var requiredImportFields = []string{"title", "amount", "due_date"}
+
+type BillImportService struct {
+	db    *gorm.DB
+	bills *BillService
+}
+
+func NewBillImportService(db *gorm.DB) *BillImportService {
+	return &BillImportService{db: db, bills: NewBillService(db)}
+}
+
+// ImportOptions controls how an uploaded file is interpreted
+type ImportOptions struct {
+	// Mapping maps bill fields to column headers, e.g. {"amount": "Total"}.
+	// Fields left out are matched to a column of the same name.
+	Mapping       map[string]string `json:"mapping"`
+	DateFormat    string            `json:"date_format"`
+	Status        models.BillStatus `json:"status"`
+	CreateMissing bool              `json:"create_missing"`
+	DryRun        bool              `json:"dry_run"`
+}
+
+// ImportRowError describes why a row cannot be imported. Row numbers are
+// 1-based and count the header row, matching what spreadsheet users see.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportResult summarizes an import or dry run
+type ImportResult struct {
+	DryRun            bool             `json:"dry_run"`
+	TotalRows         int              `json:"total_rows"`
+	ValidRows         int              `json:"valid_rows"`
+	Created           int              `json:"created"`
+	CreatedVendors    []string         `json:"created_vendors"`
+	CreatedCategories []string         `json:"created_categories"`
+	Errors            []ImportRowError `json:"errors"`
+}
+
+// importRow is a validated row waiting to be inserted
+type importRow struct {
+	input    CreateBillInput
+	vendor   string
+	category string
+}
+
+// Import validates every row of a CSV or XLSX file and, unless this is a dry
+// run, creates all bills in a single transaction. Nothing is written when any
+// row is invalid; the result then lists every problem found.
+func (s *BillImportService) Import(companyID, userID uuid.UUID, filename string, r io.Reader, options ImportOptions) (*ImportResult, error) {
+	if err := requireVerifiedEmail(s.db, userID, models.EmailVerificationPolicy.BlocksBillCreation); err != nil {
+		return nil, err
+	}
+
+	rows, err := spreadsheet.Read(filename, r, maxImportRows+1, func(header []string) int {
+		// Only mapped columns are kept; a bad mapping is reported below
+		columns, err := resolveColumns(header, options.Mapping)
+		if err != nil {
+			return 0
+		}
+		width := 0
+		for _, i := range columns {
+			width = max(width, i+1)
+		}
+		return width
+	})
+	if errors.Is(err, spreadsheet.ErrTooManyRows) {
+		return nil, fmt.Errorf("%w: at most %d rows can be imported at once", ErrInvalidImportFile, maxImportRows)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+	}
+	if len(rows) < 2 {
+		return nil, fmt.Errorf("%w: expected a header row and at least one bill", ErrInvalidImportFile)
+	}
+
+	columns, err := resolveColumns(rows[0], options.Mapping)
+	if err != nil {
+		return nil, err
+	}
+	if options.DateFormat == "" {
+		options.DateFormat = "2006-01-02"
+	}
+
+	result := &ImportResult{
+		DryRun:            options.DryRun,
+		CreatedVendors:    []string{},
+		CreatedCategories: []string{},
+		Errors:            []ImportRowError{},
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		vendors, err := namedIDs(tx, &models.Vendor{}, companyID)
+		if err != nil {
+			return err
+		}
+		categories, err := namedIDs(tx, &models.Category{}, companyID)
+		if err != nil {
+			return err
+		}
+
+		missingVendors := make(map[string]string)
+		missingCategories := make(map[string]string)
+		invoiceRows := make(map[string]int)
+		var valid []importRow
+
+		for i, values := range rows[1:] {
+			rowNumber := i + 2
+			if isBlankRow(values) {
+				continue
+			}
+			result.TotalRows++
+
+			row, rowErrors := parseImportRow(rowNumber, values, columns, options)
+			for _, ref := range []struct {
+				name    string
+				field   string
+				known   map[string]uuid.UUID
+				missing map[string]string
+			}{
+				{row.vendor, "vendor", vendors, missingVendors},
+				{row.category, "category", categories, missingCategories},
+			} {
+				if ref.name == "" {
+					continue
+				}
+				key := strings.ToLower(ref.name)
+				if _, ok := ref.known[key]; ok {
+					continue
+				}
+				if !options.CreateMissing {
+					rowErrors = append(rowErrors, ImportRowError{Row: rowNumber, Field: ref.field,
+						Message: fmt.Sprintf("%s %q does not exist", ref.field, ref.name)})
+					continue
+				}
+				if _, ok := ref.missing[key]; !ok {
+					ref.missing[key] = ref.name
+				}
+			}
+
+			if invoice := row.input.InvoiceNumber; invoice != nil {
+				key := strings.ToLower(row.vendor) + "\x00" + *invoice
+				if first, ok := invoiceRows[key]; ok {
+					rowErrors = append(rowErrors, ImportRowError{Row: rowNumber, Field: "invoice_number",
+						Message: fmt.Sprintf("invoice number %q repeats row %d", *invoice, first)})
+				} else {
+					invoiceRows[key] = rowNumber
+					existing := models.Bill{CompanyID: companyID, InvoiceNumber: invoice}
+					if id, ok := vendors[strings.ToLower(row.vendor)]; ok {
+						existing.VendorID = &id
+					}
+					if row.vendor == "" || existing.VendorID != nil {
+						if err := checkInvoiceNumber(tx, &existing); errors.Is(err, ErrDuplicateInvoiceNumber) {
+							rowErrors = append(rowErrors, ImportRowError{Row: rowNumber, Field: "invoice_number",
+								Message: fmt.Sprintf("invoice number %q already exists for this vendor", *invoice)})
+						} else if err != nil {
+							return err
+						}
+					}
+				}
+			}
+
+			if len(rowErrors) > 0 {
+				result.Errors = append(result.Errors, rowErrors...)
+				continue
+			}
+			valid = append(valid, row)
+		}
+
+		result.ValidRows = len(valid)
+		result.CreatedVendors = sortedValues(missingVendors)
+		result.CreatedCategories = sortedValues(missingCategories)
+
+		if options.DryRun || len(result.Errors) > 0 {
+			return nil
+		}
+
+		for _, name := range result.CreatedVendors {
+			vendor := models.Vendor{CompanyID: companyID, Name: name}
+			if err := tx.Create(&vendor).Error; err != nil {
+				return err
+			}
+			vendors[strings.ToLower(name)] = vendor.ID
+		}
+		for _, name := range result.CreatedCategories {
+			category := models.Category{CompanyID: companyID, Name: name}
+			if err := tx.Create(&category).Error; err != nil {
+				return err
+			}
+			categories[strings.ToLower(name)] = category.ID
+		}
+
+		for _, row := range valid {
+			if row.vendor != "" {
+				id := vendors[strings.ToLower(row.vendor)]
+				row.input.VendorID = &id
+			}
+			if row.category != "" {
+				id := categories[strings.ToLower(row.category)]
+				row.input.CategoryID = &id
+			}
+
+			bill, err := newBill(tx, companyID, userID, row.input)
+			if err != nil {
+				return err
+			}
+			if err := s.bills.insert(tx, bill, nil, "Bill imported"); err != nil {
+				return err
+			}
+			result.Created++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !options.DryRun && len(result.Errors) > 0 {
+		return result, ErrImportRowsInvalid
+	}
+	return result, nil
+}
+
+// resolveColumns maps each bill field to its column index in the header row
+func resolveColumns(header []string, mapping map[string]string) (map[string]int, error) {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	known := make(map[string]bool, len(importFields))
+	for _, field := range importFields {
+		known[field] = true
+	}
+	for field := range mapping {
+		if !known[field] {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidMapping, field)
+		}
+	}
+
+	columns := make(map[string]int)
+	for _, field := range importFields {
+		column, mapped := mapping[field]
+		if !mapped {
+			column = field
+		}
+		if i, ok := index[strings.ToLower(strings.TrimSpace(column))]; ok {
+			columns[field] = i
+		} else if mapped {
+			return nil, fmt.Errorf("%w: column %q for %s not found", ErrInvalidMapping, column, field)
+		}
+	}
+
+	for _, field := range requiredImportFields {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("%w: no column mapped to required field %s", ErrInvalidMapping, field)
+		}
+	}
+	return columns, nil
+}
+
+// parseImportRow converts one row into bill input, collecting every field error
+func parseImportRow(rowNumber int, values []string, columns map[string]int, options ImportOptions) (importRow, []ImportRowError) {
+	var row importRow
+	var rowErrors []ImportRowError
+	fail := func(field, message string) {
+		rowErrors = append(rowErrors, ImportRowError{Row: rowNumber, Field: field, Message: message})
+	}
+	value := func(field string) string {
+		i, ok := columns[field]
+		if !ok || i >= len(values) {
+			return ""
+		}
+		return strings.TrimSpace(values[i])
+	}
+	optional := func(field string) *string {
+		if v := value(field); v != "" {
+			return &v
+		}
+		return nil
+	}
+
+	row.input.Title = value("title")
+	if row.input.Title == "" {
+		fail("title", "title is required")
+	}
+
+	amount, err := decimal.NewFromString(strings.ReplaceAll(value("amount"), ",", ""))
+	if err != nil {
+		fail("amount", fmt.Sprintf("invalid amount %q", value("amount")))
+	} else if !amount.IsPositive() {
+		fail("amount", "amount must be positive")
+	}
+	row.input.Amount = amount
+
+	dueDate, err := parseImportDate(value("due_date"), options.DateFormat)
+	if err != nil {
+		fail("due_date", err.Error())
+	}
+	row.input.DueDate = dueDate
+
+	row.input.Currency = value("currency")
+	if row.input.Currency != "" {
+		if _, err := normalizeCurrency(row.input.Currency); err != nil {
+			fail("currency", err.Error())
+		}
+	}
+
+	row.input.Status = options.Status
+	if status := value("status"); status != "" {
+		row.input.Status = models.BillStatus(strings.ToLower(status))
+	}
+	if row.input.Status != "" && row.input.Status != models.StatusDraft && row.input.Status != models.StatusUnpaid {
+		fail("status", fmt.Sprintf("status must be %s or %s", models.StatusDraft, models.StatusUnpaid))
+	}
+
+	row.input.InvoiceNumber = optional("invoice_number")
+	row.input.PaymentMethod = optional("payment_method")
+	row.input.Notes = optional("notes")
+	row.vendor = value("vendor")
+	row.category = value("category")
+
+	// Checked here so a dry run catches what the columns would reject
+	for _, limit := range []struct {
+		field string
+		max   int
+	}{
+		{"title", 255},
+		{"invoice_number", 100},
+		{"payment_method", 100},
+		{"vendor", 255},
+		{"category", 100},
+	} {
+		if n := utf8.RuneCountInString(value(limit.field)); n > limit.max {
+			fail(limit.field, fmt.Sprintf("%s must be at most %d characters", limit.field, limit.max))
+		}
+	}
+
+	return row, rowErrors
+}
+
+// parseImportDate accepts the configured layout, ISO dates and Excel serial dates
+func parseImportDate(value, layout string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("due date is required")
+	}
+	for _, l := range []string{layout, "2006-01-02", time.RFC3339} {
+		if t, err := time.Parse(l, value); err == nil {
+			return t, nil
+		}
+	}
+	// XLSX stores dates as days since 1899-12-30
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && serial > 0 && serial < 2958466 {
+		return time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).AddDate(0, 0, int(serial)), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, expected format %s", value, layout)
+}
+
+// namedIDs indexes a company's vendors or categories by lower-cased name
+func namedIDs(tx *gorm.DB, model interface{}, companyID uuid.UUID) (map[string]uuid.UUID, error) {
+	var records []struct {
+		ID   uuid.UUID
+		Name string
+	}
+	if err := tx.Model(model).Select("id", "name").Where("company_id = ?", companyID).Scan(&records).Error; err != nil {
+		return nil, err
+	}
+	ids := make(map[string]uuid.UUID, len(records))
+	for _, record := range records {
+		ids[strings.ToLower(strings.TrimSpace(record.Name))] = record.ID
+	}
+	return ids, nil
+}
+
+func isBlankRow(values []string) bool {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedValues(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return values
+}
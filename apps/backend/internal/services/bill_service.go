@@ -1,7 +1,10 @@
 package services
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,21 +15,57 @@ import (
 	"github.com/dhani/bill-tracker-backend/internal/utils"
 )
 
+// ErrBillNotFound is returned when a bill does not exist in the company
+var ErrBillNotFound = errors.New("bill not found")
+
+// ErrAmountBelowPaid is returned when a bill amount is lowered below what was already paid
+var ErrAmountBelowPaid = errors.New("amount cannot be less than the amount already paid")
+
 type BillService struct {
-	db *gorm.DB
+	db        *gorm.DB
+	payments  *PaymentService
+	approvals *ApprovalService
 }
 
 func NewBillService(db *gorm.DB) *BillService {
-	return &BillService{db: db}
+	return &BillService{db: db, payments: NewPaymentService(db), approvals: NewApprovalService(db)}
 }
 
 // BillFilters holds query filters
 type BillFilters struct {
-	Status string
-	Search string
+	Conditions []utils.Condition
+	Search     string
 	utils.Pagination
 }
 
+// BillSortFields lists the fields bills can be sorted by
+var BillSortFields = utils.SortFields{
+	"created_at":     "created_at",
+	"updated_at":     "updated_at",
+	"due_date":       "due_date",
+	"paid_date":      "paid_date",
+	"amount":         "amount",
+	"title":          "title",
+	"status":         "status",
+	"currency":       "currency",
+	"invoice_number": "invoice_number",
+}
+
+// BillFilterFields lists the fields bills can be filtered by
+var BillFilterFields = utils.FilterFields{
+	"status": {Column: "status", Type: utils.FilterString, Values: []string{
+		string(models.StatusDraft), string(models.StatusPendingApproval), string(models.StatusUnpaid),
+		string(models.StatusPartiallyPaid), string(models.StatusPaid), string(models.StatusOverdue),
+	}},
+	"amount":       {Column: "amount", Type: utils.FilterNumber},
+	"due_date":     {Column: "due_date", Type: utils.FilterDate},
+	"paid_date":    {Column: "paid_date", Type: utils.FilterDate},
+	"vendor_id":    {Column: "vendor_id", Type: utils.FilterUUID},
+	"category_id":  {Column: "category_id", Type: utils.FilterUUID},
+	"currency":     {Column: "currency", Type: utils.FilterString, Normalize: strings.ToUpper},
+	"is_recurring": {Column: "is_recurring", Type: utils.FilterBool},
+}
+
 // CreateBillInput holds data for creating a bill
 type CreateBillInput struct {
 	Title              string                    `json:"title" binding:"required"`
@@ -42,6 +81,8 @@ type CreateBillInput struct {
 	PaymentMethod      *string                   `json:"payment_method"`
 	Notes              *string                   `json:"notes"`
 	Status             models.BillStatus         `json:"status"`
+	LineItems          []LineItemInput           `json:"line_items"`
+	ConfirmDuplicate   bool                      `json:"confirm_duplicate"`
 }
 
 // UpdateBillInput holds data for updating a bill
@@ -59,6 +100,7 @@ type UpdateBillInput struct {
 	PaymentMethod      *string                    `json:"payment_method"`
 	Notes              *string                    `json:"notes"`
 	Status             *models.BillStatus         `json:"status"`
+	LineItems          *[]LineItemInput           `json:"line_items"`
 }
 
 // List retrieves bills with filters and pagination
@@ -66,30 +108,21 @@ func (s *BillService) List(companyID uuid.UUID, filters BillFilters) ([]models.B
 	var bills []models.Bill
 	var total int64
 
-	query := s.db.Model(&models.Bill{}).Where("company_id = ?", companyID)
-
-	// Apply status filter
-	if filters.Status != "" && filters.Status != "all" {
-		query = query.Where("status = ?", filters.Status)
-	}
+	query := s.filtered(companyID, filters)
 
-	// Apply search
-	if filters.Search != "" {
-		searchTerm := "%" + filters.Search + "%"
-		query = query.Where(
-			"title ILIKE ? OR invoice_number ILIKE ?",
-			searchTerm, searchTerm,
-		)
+	orderBy, err := filters.Pagination.OrderBy(BillSortFields)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	// Get total count
 	query.Count(&total)
 
 	// Apply pagination
-	err := query.
+	err = query.
 		Preload("Vendor").
 		Preload("Category").
-		Order(filters.Pagination.GetOrderClause()).
+		Clauses(orderBy).
 		Offset(filters.Pagination.GetOffset()).
 		Limit(filters.Pagination.PageSize).
 		Find(&bills).Error
@@ -97,6 +130,113 @@ func (s *BillService) List(companyID uuid.UUID, filters BillFilters) ([]models.B
 	return bills, total, err
 }
 
+// billCursorKeys lists the sort fields usable with cursors and how to read
+// them from a bill. Nullable columns are left out because NULLs cannot be
+// compared in a keyset condition.
+var billCursorKeys = map[string]func(*models.Bill) interface{}{
+	"created_at": func(b *models.Bill) interface{} { return b.CreatedAt },
+	"updated_at": func(b *models.Bill) interface{} { return b.UpdatedAt },
+	"due_date":   func(b *models.Bill) interface{} { return b.DueDate },
+	"amount":     func(b *models.Bill) interface{} { return b.Amount },
+	"title":      func(b *models.Bill) interface{} { return b.Title },
+	"status":     func(b *models.Bill) interface{} { return b.Status },
+	"currency":   func(b *models.Bill) interface{} { return b.Currency },
+}
+
+// decodeBillCursorValue parses a cursor's sort value into the column's Go type
+func decodeBillCursorValue(sort string, raw json.RawMessage) (interface{}, error) {
+	var err error
+	switch sort {
+	case "created_at", "updated_at", "due_date":
+		var t time.Time
+		err = json.Unmarshal(raw, &t)
+		if err == nil {
+			return t, nil
+		}
+	case "amount":
+		var d decimal.Decimal
+		err = json.Unmarshal(raw, &d)
+		if err == nil {
+			return d, nil
+		}
+	default:
+		var str string
+		err = json.Unmarshal(raw, &str)
+		if err == nil {
+			return str, nil
+		}
+	}
+	return nil, utils.ErrInvalidCursor
+}
+
+// ListByCursor retrieves a keyset page of bills. Unlike offset pages it stays
+// consistent while bills are created and does not count unless asked to.
+func (s *BillService) ListByCursor(companyID uuid.UUID, filters BillFilters) ([]models.Bill, utils.CursorPage, error) {
+	var page utils.CursorPage
+
+	cursor, err := filters.Pagination.DecodeCursor()
+	if err != nil {
+		return nil, page, err
+	}
+	column, ok := BillSortFields[filters.Pagination.Sort]
+	key, cursorable := billCursorKeys[filters.Pagination.Sort]
+	if !ok || !cursorable {
+		return nil, page, fmt.Errorf("%w %q for cursor pagination", utils.ErrInvalidSort, filters.Pagination.Sort)
+	}
+
+	var value interface{}
+	if cursor != nil {
+		if value, err = decodeBillCursorValue(cursor.Sort, cursor.Value); err != nil {
+			return nil, page, err
+		}
+	}
+
+	if filters.Pagination.IncludeTotal {
+		var total int64
+		if err := s.filtered(companyID, filters).Count(&total).Error; err != nil {
+			return nil, page, err
+		}
+		page.TotalItems = &total
+	}
+
+	var bills []models.Bill
+	query := utils.ApplyKeyset(s.filtered(companyID, filters), column, "id",
+		filters.Pagination.Order == "desc", cursor, value)
+	if err := query.
+		Preload("Vendor").
+		Preload("Category").
+		Limit(filters.Pagination.PageSize + 1).
+		Find(&bills).Error; err != nil {
+		return nil, page, err
+	}
+
+	bills, cursors, err := utils.KeysetPage(bills, filters.Pagination.PageSize, cursor,
+		filters.Pagination.Sort, filters.Pagination.Order,
+		func(b *models.Bill) (interface{}, uuid.UUID) { return key(b), b.ID })
+	if err != nil {
+		return nil, page, err
+	}
+	cursors.TotalItems = page.TotalItems
+	return bills, cursors, nil
+}
+
+// filtered scopes a bill query to the company and the list filters
+func (s *BillService) filtered(companyID uuid.UUID, filters BillFilters) *gorm.DB {
+	query := s.db.Model(&models.Bill{}).Where("company_id = ?", companyID)
+	query = utils.ApplyFilters(query, filters.Conditions)
+
+	// Apply search
+	if filters.Search != "" {
+		searchTerm := "%" + filters.Search + "%"
+		query = query.Where(
+			"(title ILIKE ? OR invoice_number ILIKE ?)",
+			searchTerm, searchTerm,
+		)
+	}
+
+	return query
+}
+
 // GetByID retrieves a single bill by ID
 func (s *BillService) GetByID(companyID, billID uuid.UUID) (*models.Bill, error) {
 	var bill models.Bill
@@ -104,6 +244,13 @@ func (s *BillService) GetByID(companyID, billID uuid.UUID) (*models.Bill, error)
 		Preload("Vendor").
 		Preload("Category").
 		Preload("Attachments").
+		Preload("LineItems", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position ASC")
+		}).
+		Preload("LineItems.Category").
+		Preload("Payments", func(db *gorm.DB) *gorm.DB {
+			return db.Order("paid_date ASC, created_at ASC")
+		}).
 		Preload("Activities", func(db *gorm.DB) *gorm.DB {
 			return db.Order("created_at DESC").Limit(10)
 		}).
@@ -119,17 +266,65 @@ func (s *BillService) GetByID(companyID, billID uuid.UUID) (*models.Bill, error)
 
 // Create creates a new bill
 func (s *BillService) Create(companyID, userID uuid.UUID, input CreateBillInput) (*models.Bill, error) {
+	if err := requireVerifiedEmail(s.db, userID, models.EmailVerificationPolicy.BlocksBillCreation); err != nil {
+		return nil, err
+	}
+
+	bill, err := newBill(s.db, companyID, userID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		details := "Bill created"
+		duplicates, err := findLikelyDuplicates(tx, bill)
+		if err != nil {
+			return err
+		}
+		if len(duplicates) > 0 {
+			if !input.ConfirmDuplicate {
+				return &DuplicateBillError{Err: ErrPossibleDuplicate, BillIDs: duplicates}
+			}
+			details = "Bill created (confirmed not a duplicate)"
+		}
+		return s.insert(tx, bill, input.LineItems, details)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return bill, nil
+}
+
+// newBill validates create input and builds the bill it describes
+func newBill(db *gorm.DB, companyID, userID uuid.UUID, input CreateBillInput) (*models.Bill, error) {
 	status := input.Status
 	if status == "" {
 		status = models.StatusDraft
 	}
+	if status != models.StatusDraft && status != models.StatusUnpaid {
+		return nil, fmt.Errorf("%w: new bills must be %s or %s", ErrInvalidStatus, models.StatusDraft, models.StatusUnpaid)
+	}
 
 	currency := input.Currency
 	if currency == "" {
-		currency = "USD"
+		// Bills are in the company's own currency unless stated otherwise
+		var company models.Company
+		if err := db.Select("base_currency").First(&company, "id = ?", companyID).Error; err != nil {
+			return nil, err
+		}
+		currency = company.BaseCurrency
+	}
+	currency, err := normalizeCurrency(currency)
+	if err != nil {
+		return nil, err
 	}
 
-	bill := models.Bill{
+	if err := ValidateRecurrence(input.IsRecurring, input.RecurringFrequency, input.RecurringDay); err != nil {
+		return nil, err
+	}
+
+	return &models.Bill{
 		CompanyID:          companyID,
 		UserID:             userID,
 		VendorID:           input.VendorID,
@@ -145,23 +340,57 @@ func (s *BillService) Create(companyID, userID uuid.UUID, input CreateBillInput)
 		RecurringDay:       input.RecurringDay,
 		PaymentMethod:      input.PaymentMethod,
 		Notes:              input.Notes,
+	}, nil
+}
+
+// insert stores a new bill with its line items and logs its creation
+func (s *BillService) insert(tx *gorm.DB, bill *models.Bill, lineItems []LineItemInput, details string) error {
+	if err := checkInvoiceNumber(tx, bill); err != nil {
+		return err
 	}
 
-	if err := s.db.Create(&bill).Error; err != nil {
-		return nil, err
+	// Payable bills above an approval threshold start in review
+	if bill.Status == models.StatusUnpaid {
+		required, err := s.approvals.RequiresApproval(tx, bill)
+		if err != nil {
+			return err
+		}
+		if required {
+			bill.Status = models.StatusPendingApproval
+			bill.ApprovalRound = 1
+			details += " and submitted for approval"
+		}
 	}
 
-	// Log activity
-	s.logActivity(bill.ID, &userID, models.ActionCreated, "Bill created")
+	if err := tx.Create(bill).Error; err != nil {
+		return err
+	}
 
-	return &bill, nil
+	if len(lineItems) > 0 {
+		items, total, err := buildLineItems(tx, bill.CompanyID, lineItems)
+		if err != nil {
+			return err
+		}
+		if err := reconcileLineItems(total, bill.Amount); err != nil {
+			return err
+		}
+		if err := replaceLineItems(tx, bill.ID, items); err != nil {
+			return err
+		}
+		bill.LineItems = items
+	}
+
+	if err := createActivity(tx, bill.ID, &bill.UserID, models.ActionCreated, details); err != nil {
+		return err
+	}
+	return enqueueBillEvent(tx, models.EventBillCreated, bill.ID, BillEventData{})
 }
 
 // Update updates an existing bill
 func (s *BillService) Update(companyID, billID uuid.UUID, userID uuid.UUID, input UpdateBillInput) (*models.Bill, error) {
 	var bill models.Bill
 	if err := s.db.Where("company_id = ? AND id = ?", companyID, billID).First(&bill).Error; err != nil {
-		return nil, errors.New("bill not found")
+		return nil, ErrBillNotFound
 	}
 
 	updates := make(map[string]interface{})
@@ -179,10 +408,17 @@ func (s *BillService) Update(companyID, billID uuid.UUID, userID uuid.UUID, inpu
 		updates["invoice_number"] = *input.InvoiceNumber
 	}
 	if input.Amount != nil {
+		if input.Amount.LessThan(bill.AmountPaid) {
+			return nil, ErrAmountBelowPaid
+		}
 		updates["amount"] = *input.Amount
 	}
 	if input.Currency != nil {
-		updates["currency"] = *input.Currency
+		currency, err := normalizeCurrency(*input.Currency)
+		if err != nil {
+			return nil, err
+		}
+		updates["currency"] = currency
 	}
 	if input.DueDate != nil {
 		updates["due_date"] = *input.DueDate
@@ -202,49 +438,362 @@ func (s *BillService) Update(companyID, billID uuid.UUID, userID uuid.UUID, inpu
 	if input.Notes != nil {
 		updates["notes"] = *input.Notes
 	}
-	if input.Status != nil {
-		updates["status"] = *input.Status
+	if input.Status != nil && *input.Status != bill.Status {
+		if err := validateManualTransition(&bill, *input.Status); err != nil {
+			return nil, err
+		}
 	}
 
-	if err := s.db.Model(&bill).Updates(updates).Error; err != nil {
+	isRecurring, frequency, day := bill.IsRecurring, bill.RecurringFrequency, bill.RecurringDay
+	if input.IsRecurring != nil {
+		isRecurring = *input.IsRecurring
+	}
+	if input.RecurringFrequency != nil {
+		frequency = input.RecurringFrequency
+	}
+	if input.RecurringDay != nil {
+		day = input.RecurringDay
+	}
+	if err := ValidateRecurrence(isRecurring, frequency, day); err != nil {
 		return nil, err
 	}
 
-	// Log activity
-	s.logActivity(bill.ID, &userID, models.ActionUpdated, "Bill updated")
+	changes := diffBill(&bill, updates)
+	currencyChanged := updates["currency"] != nil && updates["currency"] != bill.Currency
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if input.InvoiceNumber != nil || input.VendorID != nil {
+			candidate := bill
+			if input.InvoiceNumber != nil {
+				candidate.InvoiceNumber = input.InvoiceNumber
+			}
+			if input.VendorID != nil {
+				candidate.VendorID = input.VendorID
+			}
+			if err := checkInvoiceNumber(tx, &candidate); err != nil {
+				return err
+			}
+		}
+
+		lineItemChange, err := s.updateLineItems(tx, &bill, input)
+		if err != nil {
+			return err
+		}
+		if lineItemChange != nil {
+			changes = append(changes, *lineItemChange)
+		}
+
+		if len(changes) > 0 {
+			if len(updates) > 0 {
+				if err := tx.Model(&bill).Updates(updates).Error; err != nil {
+					return err
+				}
+			}
+			details := "Bill updated: " + changedFields(changes)
+			if err := createChangeActivity(tx, bill.ID, &userID, models.ActionUpdated, details, changes); err != nil {
+				return err
+			}
+			if err := enqueueBillEvent(tx, models.EventBillUpdated, bill.ID, BillEventData{Changes: changes}); err != nil {
+				return err
+			}
+		}
+
+		if input.Amount != nil {
+			if err := tx.First(&bill, "id = ?", bill.ID).Error; err != nil {
+				return err
+			}
+			if err := s.settleAmountChange(tx, &bill, &userID); err != nil {
+				return err
+			}
+		}
+
+		if currencyChanged {
+			if err := tx.First(&bill, "id = ?", bill.ID).Error; err != nil {
+				return err
+			}
+			if err := s.restartApproval(tx, &bill, &userID); err != nil {
+				return err
+			}
+		}
+
+		if input.Status != nil && *input.Status != bill.Status {
+			if err := tx.First(&bill, "id = ?", bill.ID).Error; err != nil {
+				return err
+			}
+			return s.applyManualTransition(tx, &bill, &userID, *input.Status, "")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	return s.GetByID(companyID, billID)
 }
 
+// restartApproval puts a bill whose currency changed back in front of its
+// approvers, since approvals given for the old currency no longer count: a
+// bill in review starts a new round, and a payable one without payments is
+// submitted again when its amount now requires approval
+func (s *BillService) restartApproval(tx *gorm.DB, bill *models.Bill, userID *uuid.UUID) error {
+	switch {
+	case bill.Status == models.StatusPendingApproval:
+		if err := tx.Model(bill).Update("approval_round", bill.ApprovalRound+1).Error; err != nil {
+			return err
+		}
+		return createActivity(tx, bill.ID, userID, models.ActionUpdated, "Approval restarted: currency changed")
+	case (bill.Status == models.StatusUnpaid || bill.Status == models.StatusOverdue) && !bill.AmountPaid.IsPositive():
+		required, err := s.approvals.RequiresApproval(tx, bill)
+		if err != nil || !required {
+			return err
+		}
+		return s.approvals.Submit(tx, bill, userID)
+	}
+	return nil
+}
+
+// settleAmountChange brings a payable bill's status in line with its new
+// amount: a paid bill whose amount was raised is partially paid again, and
+// one lowered to what was already paid is settled
+func (s *BillService) settleAmountChange(tx *gorm.DB, bill *models.Bill, userID *uuid.UUID) error {
+	from := bill.Status
+	to := from
+	updates := make(map[string]interface{})
+
+	switch {
+	case from == models.StatusDraft || from == models.StatusPendingApproval:
+		return nil
+	case bill.AmountPaid.IsPositive() && bill.AmountPaid.GreaterThanOrEqual(bill.Amount):
+		to = models.StatusPaid
+		if bill.PaidDate == nil {
+			updates["paid_date"] = time.Now()
+		}
+	case from == models.StatusPaid && bill.AmountPaid.IsPositive():
+		to = models.StatusPartiallyPaid
+		updates["paid_date"] = nil
+	case from == models.StatusPaid:
+		to = models.StatusUnpaid
+		updates["paid_date"] = nil
+	}
+	if to == from {
+		return nil
+	}
+
+	// Driven by the ledger, so paid may move back, unlike manual transitions
+	updates["status"] = to
+	if err := tx.Model(bill).Updates(updates).Error; err != nil {
+		return err
+	}
+	bill.Status = to
+	if err := recordTransition(tx, bill.ID, userID, from, to, "amount changed"); err != nil {
+		return err
+	}
+
+	if to == models.StatusPaid {
+		_, err := s.payments.recurring.GenerateNext(tx, bill)
+		return err
+	}
+	return nil
+}
+
+// updateLineItems replaces the bill's line items when requested and makes sure
+// the stored (or new) line items still reconcile with the resulting amount
+func (s *BillService) updateLineItems(tx *gorm.DB, bill *models.Bill, input UpdateBillInput) (*models.FieldChange, error) {
+	amount := bill.Amount
+	if input.Amount != nil {
+		amount = *input.Amount
+	}
+
+	if input.LineItems == nil {
+		if input.Amount == nil {
+			return nil, nil
+		}
+		total, hasItems, err := lineItemsTotal(tx, bill.ID)
+		if err != nil || !hasItems {
+			return nil, err
+		}
+		return nil, reconcileLineItems(total, amount)
+	}
+
+	items, total, err := buildLineItems(tx, bill.CompanyID, *input.LineItems)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) > 0 {
+		if err := reconcileLineItems(total, amount); err != nil {
+			return nil, err
+		}
+	}
+
+	var previous []models.BillLineItem
+	if err := tx.Where("bill_id = ?", bill.ID).Order("position ASC").Find(&previous).Error; err != nil {
+		return nil, err
+	}
+	if err := replaceLineItems(tx, bill.ID, items); err != nil {
+		return nil, err
+	}
+
+	return &models.FieldChange{Field: "line_items", Old: previous, New: items}, nil
+}
+
+// TransitionInput holds data for an explicit status transition
+type TransitionInput struct {
+	Status models.BillStatus `json:"status" binding:"required"`
+	Note   string            `json:"note"`
+}
+
+// Transition moves a bill to a new status according to the state machine.
+// Moving to paid settles the outstanding balance through the payment ledger.
+func (s *BillService) Transition(companyID, billID, userID uuid.UUID, input TransitionInput) (*models.Bill, error) {
+	var bill models.Bill
+	if err := s.db.Where("company_id = ? AND id = ?", companyID, billID).First(&bill).Error; err != nil {
+		return nil, ErrBillNotFound
+	}
+
+	if input.Status == models.StatusPaid {
+		if err := validateTransition(bill.Status, models.StatusPaid); err != nil {
+			return nil, err
+		}
+		return s.MarkAsPaid(companyID, billID, userID, time.Now())
+	}
+
+	if err := validateManualTransition(&bill, input.Status); err != nil {
+		return nil, err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return s.applyManualTransition(tx, &bill, &userID, input.Status, input.Note)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetByID(companyID, billID)
+}
+
+// validateManualTransition checks a status change requested directly by a
+// client. Paid states are only reachable by recording payments, so that the
+// payment ledger and the bill status cannot drift apart.
+func validateManualTransition(bill *models.Bill, to models.BillStatus) error {
+	if err := validateTransition(bill.Status, to); err != nil {
+		return err
+	}
+	if to == models.StatusPaid || to == models.StatusPartiallyPaid {
+		return fmt.Errorf("%w: record a payment to move a bill to %s", ErrInvalidTransition, to)
+	}
+	if bill.Status == models.StatusPendingApproval {
+		return fmt.Errorf("%w: approve or reject the bill to move it out of review", ErrInvalidTransition)
+	}
+	if to == models.StatusUnpaid && bill.AmountPaid.IsPositive() {
+		return fmt.Errorf("%w: bill already has payments recorded", ErrInvalidTransition)
+	}
+	return nil
+}
+
+// applyManualTransition performs a validated client-requested status change.
+// Drafts that become payable are routed through review when an approval
+// policy applies to their amount; submitting a draft that needs no approval
+// makes it payable right away.
+func (s *BillService) applyManualTransition(tx *gorm.DB, bill *models.Bill, userID *uuid.UUID, to models.BillStatus, note string) error {
+	submitting := to == models.StatusPendingApproval ||
+		(bill.Status == models.StatusDraft && to == models.StatusUnpaid)
+
+	if submitting {
+		required, err := s.approvals.RequiresApproval(tx, bill)
+		if err != nil {
+			return err
+		}
+		if required {
+			return s.approvals.Submit(tx, bill, userID)
+		}
+		if bill.Status != models.StatusDraft {
+			return fmt.Errorf("%w: bill does not require approval", ErrInvalidTransition)
+		}
+		to = models.StatusUnpaid
+	}
+
+	return transitionStatus(tx, bill, userID, to, note)
+}
+
 // Delete soft-deletes a bill
 func (s *BillService) Delete(companyID, billID uuid.UUID) error {
-	result := s.db.Where("company_id = ? AND id = ?", companyID, billID).Delete(&models.Bill{})
-	if result.RowsAffected == 0 {
-		return errors.New("bill not found")
-	}
-	return result.Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("company_id = ? AND id = ?", companyID, billID).Delete(&models.Bill{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrBillNotFound
+		}
+		return enqueueBillEvent(tx, models.EventBillDeleted, billID, BillEventData{})
+	})
 }
 
-// MarkAsPaid marks a bill as paid
+// MarkAsPaid settles the bill's outstanding balance with a single payment
 func (s *BillService) MarkAsPaid(companyID, billID uuid.UUID, userID uuid.UUID, paidDate time.Time) (*models.Bill, error) {
 	var bill models.Bill
 	if err := s.db.Where("company_id = ? AND id = ?", companyID, billID).First(&bill).Error; err != nil {
-		return nil, errors.New("bill not found")
+		return nil, ErrBillNotFound
 	}
 
-	if err := s.db.Model(&bill).Updates(map[string]interface{}{
-		"status":    models.StatusPaid,
-		"paid_date": paidDate,
-	}).Error; err != nil {
+	_, err := s.payments.Record(companyID, billID, userID, RecordPaymentInput{
+		Amount:   bill.Outstanding(),
+		PaidDate: &paidDate,
+		Method:   bill.PaymentMethod,
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	// Log activity
-	s.logActivity(bill.ID, &userID, models.ActionStatusChanged, "Bill marked as paid")
-
 	return s.GetByID(companyID, billID)
 }
 
+// ListActivities retrieves a keyset page of a bill's activity log, newest first
+func (s *BillService) ListActivities(companyID, billID uuid.UUID, pagination utils.Pagination) ([]models.BillActivity, utils.CursorPage, error) {
+	var page utils.CursorPage
+
+	cursor, err := pagination.DecodeCursor()
+	if err != nil {
+		return nil, page, err
+	}
+	var value interface{}
+	if cursor != nil {
+		var createdAt time.Time
+		if cursor.Sort != "created_at" || json.Unmarshal(cursor.Value, &createdAt) != nil {
+			return nil, page, utils.ErrInvalidCursor
+		}
+		value = createdAt
+	}
+
+	query := s.db.Model(&models.BillActivity{}).
+		Joins("JOIN bills ON bills.id = bill_activities.bill_id").
+		Where("bills.company_id = ? AND bill_activities.bill_id = ?", companyID, billID)
+
+	if pagination.IncludeTotal {
+		var total int64
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return nil, page, err
+		}
+		page.TotalItems = &total
+	}
+
+	var activities []models.BillActivity
+	if err := utils.ApplyKeyset(query, "bill_activities.created_at", "bill_activities.id", true, cursor, value).
+		Preload("User").
+		Limit(pagination.PageSize + 1).
+		Find(&activities).Error; err != nil {
+		return nil, page, err
+	}
+
+	activities, cursors, err := utils.KeysetPage(activities, pagination.PageSize, cursor, "created_at", "desc",
+		func(a *models.BillActivity) (interface{}, uuid.UUID) { return a.CreatedAt, a.ID })
+	if err != nil {
+		return nil, page, err
+	}
+	cursors.TotalItems = page.TotalItems
+	return activities, cursors, nil
+}
+
 // GetActivities retrieves activity log for a bill
 func (s *BillService) GetActivities(companyID, billID uuid.UUID) ([]models.BillActivity, error) {
 	var activities []models.BillActivity
@@ -257,14 +806,3 @@ func (s *BillService) GetActivities(companyID, billID uuid.UUID) ([]models.BillA
 
 	return activities, err
 }
-
-// logActivity creates an activity log entry
-func (s *BillService) logActivity(billID uuid.UUID, userID *uuid.UUID, action models.ActivityAction, details string) {
-	activity := models.BillActivity{
-		BillID:  billID,
-		UserID:  userID,
-		Action:  action,
-		Details: &details,
-	}
-	s.db.Create(&activity)
-}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/dhani/bill-tracker-backend/internal/models"
+)
+
+var (
+	ErrInvalidStatus     = errors.New("invalid bill status")
+	ErrInvalidTransition = errors.New("invalid status transition")
+)
+
+// validateTransition checks a status change against the bill state machine
+func validateTransition(from, to models.BillStatus) error {
+	if !to.IsValid() {
+		return fmt.Errorf("%w: %q", ErrInvalidStatus, to)
+	}
+	if !from.CanTransitionTo(to) {
+		return fmt.Errorf("%w: cannot move a bill from %s to %s", ErrInvalidTransition, from, to)
+	}
+	return nil
+}
+
+// recordTransition logs a status change activity carrying the from/to states
+func recordTransition(tx *gorm.DB, billID uuid.UUID, userID *uuid.UUID, from, to models.BillStatus, note string) error {
+	details := fmt.Sprintf("Status changed from %s to %s", from, to)
+	if note != "" {
+		details += ": " + note
+	}
+
+	activity := models.BillActivity{
+		BillID:     billID,
+		UserID:     userID,
+		Action:     models.ActionStatusChanged,
+		Details:    &details,
+		FromStatus: &from,
+		ToStatus:   &to,
+		Changes:    models.FieldChanges{{Field: "status", Old: from, New: to}},
+	}
+	if err := tx.Create(&activity).Error; err != nil {
+		return err
+	}
+
+	data := BillEventData{FromStatus: &from, ToStatus: &to}
+	if err := enqueueBillEvent(tx, models.EventBillStatusChanged, billID, data); err != nil {
+		return err
+	}
+	if to == models.StatusPaid {
+		return enqueueBillEvent(tx, models.EventBillPaid, billID, data)
+	}
+	return nil
+}
+
+// transitionStatus validates and applies a status change inside tx
+func transitionStatus(tx *gorm.DB, bill *models.Bill, userID *uuid.UUID, to models.BillStatus, note string) error {
+	from := bill.Status
+	if err := validateTransition(from, to); err != nil {
+		return err
+	}
+
+	if err := tx.Model(bill).Update("status", to).Error; err != nil {
+		return err
+	}
+	bill.Status = to
+
+	return recordTransition(tx, bill.ID, userID, from, to, note)
+}
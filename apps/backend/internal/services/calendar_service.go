@@ -0,0 +1,247 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/dhani/bill-tracker-backend/internal/ical"
+	"github.com/dhani/bill-tracker-backend/internal/models"
+)
+
+// ErrCalendarNotFound is returned for unknown or rotated calendar tokens
+var ErrCalendarNotFound = errors.New("calendar not found")
+
+type CalendarService struct {
+	db          *gorm.DB
+	recurring   *RecurringService
+	frontendURL string
+}
+
+func NewCalendarService(db *gorm.DB, frontendURL string) *CalendarService {
+	return &CalendarService{db: db, recurring: NewRecurringService(db), frontendURL: frontendURL}
+}
+
+// CalendarFilters narrows a feed to some categories or vendors. Alarms are
+// reminders given in days before the due date.
+type CalendarFilters struct {
+	CategoryIDs []uuid.UUID
+	VendorIDs   []uuid.UUID
+	AlarmDays   []int
+}
+
+// Token returns the user's calendar token, creating it on first use
+func (s *CalendarService) Token(userID uuid.UUID) (string, error) {
+	var user models.User
+	if err := s.db.Select("id", "calendar_token").First(&user, "id = ?", userID).Error; err != nil {
+		return "", err
+	}
+	if user.CalendarToken != nil {
+		return *user.CalendarToken, nil
+	}
+	return s.RotateToken(userID)
+}
+
+// RotateToken replaces the user's calendar token; subscriptions using the
+// old feed URL stop working
+func (s *CalendarService) RotateToken(userID uuid.UUID) (string, error) {
+	token, err := newCalendarToken()
+	if err != nil {
+		return "", err
+	}
+	if err := s.db.Model(&models.User{}).Where("id = ?", userID).Update("calendar_token", token).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Feed renders the bills still to be paid in the token owner's company.
+// A recurring series is shown as one repeating event starting at its latest
+// open occurrence; older open occurrences are shown on their own.
+func (s *CalendarService) Feed(token string, filters CalendarFilters) (*ical.Calendar, error) {
+	if token == "" {
+		return nil, ErrCalendarNotFound
+	}
+	var user models.User
+	if err := s.db.Preload("Company").Where("calendar_token = ?", token).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCalendarNotFound
+		}
+		return nil, err
+	}
+
+	query := s.db.
+		Preload("Vendor").
+		Preload("Category").
+		Where("company_id = ? AND status IN ?", user.CompanyID, []models.BillStatus{
+			models.StatusPendingApproval, models.StatusUnpaid, models.StatusPartiallyPaid, models.StatusOverdue,
+		})
+	if len(filters.CategoryIDs) > 0 {
+		query = query.Where("category_id IN ?", filters.CategoryIDs)
+	}
+	if len(filters.VendorIDs) > 0 {
+		query = query.Where("vendor_id IN ?", filters.VendorIDs)
+	}
+
+	var bills []models.Bill
+	if err := query.Order("due_date ASC").Find(&bills).Error; err != nil {
+		return nil, err
+	}
+
+	latest, err := s.latestOccurrences(bills)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remind at 9:00 on the day, rather than at midnight
+	alarms := make([]time.Duration, 0, len(filters.AlarmDays))
+	for _, days := range filters.AlarmDays {
+		alarms = append(alarms, 9*time.Hour-time.Duration(days)*24*time.Hour)
+	}
+
+	now := time.Now()
+	calendar := &ical.Calendar{
+		ProdID:          "-//Bill Tracker//Bill due dates//EN",
+		Name:            user.Company.Name + " bills",
+		RefreshInterval: time.Hour,
+		Events:          make([]ical.Event, 0, len(bills)),
+	}
+	for i := range bills {
+		bill := &bills[i]
+		event := ical.Event{
+			UID:         bill.ID.String() + "@bill-tracker",
+			Stamp:       now,
+			Date:        bill.DueDate,
+			Summary:     calendarSummary(bill),
+			Description: s.calendarDescription(bill),
+			URL:         strings.TrimRight(s.frontendURL, "/") + "/bills/" + bill.ID.String(),
+			Alarms:      alarms,
+		}
+		if bill.Category != nil {
+			event.Categories = []string{bill.Category.Name}
+		}
+		if latest[bill.ID] {
+			rule, err := s.rrule(bill)
+			if err != nil {
+				return nil, err
+			}
+			event.RRule = rule
+		}
+		calendar.Events = append(calendar.Events, event)
+	}
+	return calendar, nil
+}
+
+// latestOccurrences reports which recurring bills are the latest occurrence
+// of their series, i.e. the ones the next occurrences will follow
+func (s *CalendarService) latestOccurrences(bills []models.Bill) (map[uuid.UUID]bool, error) {
+	var ids []uuid.UUID
+	for _, bill := range bills {
+		if bill.IsRecurring && bill.RecurringFrequency != nil {
+			ids = append(ids, bill.ID)
+		}
+	}
+	latest := make(map[uuid.UUID]bool)
+	if len(ids) == 0 {
+		return latest, nil
+	}
+
+	var latestIDs []uuid.UUID
+	err := s.db.Model(&models.Bill{}).
+		Where("id IN ?", ids).
+		Where(`NOT EXISTS (
+			SELECT 1 FROM bills later
+			WHERE later.parent_bill_id = COALESCE(bills.parent_bill_id, bills.id)
+			AND later.due_date > bills.due_date
+			AND later.deleted_at IS NULL
+		)`).
+		Pluck("id", &latestIDs).Error
+	for _, id := range latestIDs {
+		latest[id] = true
+	}
+	return latest, err
+}
+
+// rrule expresses a bill's recurrence the way NextDueDate computes it.
+// Days of the month beyond 28 are written as "the last of 28..day" so short
+// months fall on their last day instead of being skipped. Bills saved before
+// recurring_day was validated may hold a day out of range; they get no rule.
+func (s *CalendarService) rrule(bill *models.Bill) (string, error) {
+	switch *bill.RecurringFrequency {
+	case models.FrequencyWeekly:
+		if bill.RecurringDay == nil {
+			return "FREQ=WEEKLY", nil
+		}
+		weekdays := []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+		if *bill.RecurringDay < 0 || *bill.RecurringDay >= len(weekdays) {
+			return "", nil
+		}
+		return "FREQ=WEEKLY;BYDAY=" + weekdays[*bill.RecurringDay], nil
+
+	case models.FrequencyMonthly, models.FrequencyYearly:
+		day := 0
+		if bill.RecurringDay != nil {
+			day = *bill.RecurringDay
+		} else {
+			anchor, err := s.recurring.anchorDay(s.db, bill)
+			if err != nil {
+				return "", err
+			}
+			day = anchor
+		}
+		if day < 1 || day > 31 {
+			return "", nil
+		}
+
+		rule := "FREQ=MONTHLY"
+		if *bill.RecurringFrequency == models.FrequencyYearly {
+			rule = fmt.Sprintf("FREQ=YEARLY;BYMONTH=%d", int(bill.DueDate.Month()))
+		}
+		if day <= 28 {
+			return fmt.Sprintf("%s;BYMONTHDAY=%d", rule, day), nil
+		}
+		days := make([]string, 0, day-27)
+		for d := 28; d <= day; d++ {
+			days = append(days, fmt.Sprint(d))
+		}
+		return fmt.Sprintf("%s;BYMONTHDAY=%s;BYSETPOS=-1", rule, strings.Join(days, ",")), nil
+	}
+	return "", fmt.Errorf("%w: unknown recurring_frequency %q", ErrInvalidRecurrence, *bill.RecurringFrequency)
+}
+
+func calendarSummary(bill *models.Bill) string {
+	summary := fmt.Sprintf("%s: %s %s due", bill.Title, bill.Outstanding().StringFixed(2), bill.Currency)
+	if bill.Vendor != nil {
+		summary = fmt.Sprintf("%s (%s): %s %s due", bill.Title, bill.Vendor.Name, bill.Outstanding().StringFixed(2), bill.Currency)
+	}
+	return summary
+}
+
+func (s *CalendarService) calendarDescription(bill *models.Bill) string {
+	lines := []string{
+		fmt.Sprintf("Amount: %s %s", bill.Amount.StringFixed(2), bill.Currency),
+		fmt.Sprintf("Outstanding: %s %s", bill.Outstanding().StringFixed(2), bill.Currency),
+		"Status: " + strings.ReplaceAll(string(bill.Status), "_", " "),
+	}
+	if bill.Vendor != nil {
+		lines = append(lines, "Vendor: "+bill.Vendor.Name)
+	}
+	if bill.InvoiceNumber != nil && *bill.InvoiceNumber != "" {
+		lines = append(lines, "Invoice: "+*bill.InvoiceNumber)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func newCalendarToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/dhani/bill-tracker-backend/internal/models"
+)
+
+type CompanyService struct {
+	db *gorm.DB
+}
+
+func NewCompanyService(db *gorm.DB) *CompanyService {
+	return &CompanyService{db: db}
+}
+
+// UpdateCompanyInput holds company settings update data
+type UpdateCompanyInput struct {
+	Name         *string `json:"name"`
+	Timezone     *string `json:"timezone"`
+	BaseCurrency *string `json:"base_currency"`
+	// EmailVerification is what unverified users are kept from: off, bills or login
+	EmailVerification *models.EmailVerificationPolicy `json:"email_verification"`
+}
+
+// Get retrieves a company by ID
+func (s *CompanyService) Get(companyID uuid.UUID) (*models.Company, error) {
+	var company models.Company
+	if err := s.db.First(&company, "id = ?", companyID).Error; err != nil {
+		return nil, err
+	}
+	return &company, nil
+}
+
+// Update updates company settings
+func (s *CompanyService) Update(companyID uuid.UUID, input UpdateCompanyInput) (*models.Company, error) {
+	company, err := s.Get(companyID)
+	if err != nil {
+		return nil, errors.New("company not found")
+	}
+
+	updates := make(map[string]interface{})
+	if input.Name != nil {
+		updates["name"] = *input.Name
+	}
+	if input.Timezone != nil {
+		if _, err := time.LoadLocation(*input.Timezone); err != nil || *input.Timezone == "" {
+			return nil, errors.New("invalid timezone")
+		}
+		updates["timezone"] = *input.Timezone
+	}
+	if input.BaseCurrency != nil {
+		baseCurrency, err := normalizeCurrency(*input.BaseCurrency)
+		if err != nil {
+			return nil, err
+		}
+		updates["base_currency"] = baseCurrency
+	}
+	if input.EmailVerification != nil {
+		if !input.EmailVerification.IsValid() {
+			return nil, errors.New("email_verification must be off, bills or login")
+		}
+		updates["email_verification"] = *input.EmailVerification
+	}
+
+	if err := s.db.Model(company).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	return s.Get(companyID)
+}
+
+// RotateInboundToken gives the company a new inbound email address
+func (s *CompanyService) RotateInboundToken(companyID uuid.UUID) (*models.Company, error) {
+	if err := s.db.Model(&models.Company{}).Where("id = ?", companyID).
+		Update("inbound_token", models.NewInboundToken()).Error; err != nil {
+		return nil, err
+	}
+	return s.Get(companyID)
+}
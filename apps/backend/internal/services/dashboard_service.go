@@ -1,6 +1,7 @@
 package services
 
 import (
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,20 +12,23 @@ import (
 )
 
 type DashboardService struct {
-	db *gorm.DB
+	db    *gorm.DB
+	rates *ExchangeRateService
 }
 
 func NewDashboardService(db *gorm.DB) *DashboardService {
-	return &DashboardService{db: db}
+	return &DashboardService{db: db, rates: NewExchangeRateService(db)}
 }
 
-// DashboardStats holds KPI data
+// DashboardStats holds KPI data, expressed in the company's base currency
 type DashboardStats struct {
+	Currency             string          `json:"currency"`
 	TotalExpense         decimal.Decimal `json:"total_expense"`
 	PaidThisMonth        decimal.Decimal `json:"paid_this_month"`
 	UnpaidAmount         decimal.Decimal `json:"unpaid_amount"`
 	OverdueBillsCount    int64           `json:"overdue_bills_count"`
 	ExpenseChangePercent float64         `json:"expense_change_percent"`
+	MissingRates         []string        `json:"missing_rates,omitempty"`
 }
 
 // MonthlyExpense holds monthly expense data
@@ -41,70 +45,139 @@ type CategoryExpense struct {
 	Percentage   float64         `json:"percentage"`
 }
 
+// amountGroup is a partial sum in a single currency on a single day,
+// optionally keyed by month or category, ready for conversion
+type amountGroup struct {
+	Key      string
+	Name     string
+	Currency string
+	Day      time.Time
+	Amount   decimal.Decimal
+}
+
+// converter converts grouped sums into the base currency and remembers
+// which rates were missing so callers can report incomplete totals
+type converter struct {
+	table   *RateTable
+	missing map[string]struct{}
+}
+
+func (c *converter) convert(group amountGroup) decimal.Decimal {
+	amount, err := c.table.Convert(group.Amount, group.Currency, group.Day)
+	if err != nil {
+		c.missing[group.Currency+"/"+c.table.Base+"@"+group.Day.Format("2006-01-02")] = struct{}{}
+		return decimal.Zero
+	}
+	return amount
+}
+
+func (c *converter) sum(groups []amountGroup) decimal.Decimal {
+	total := decimal.Zero
+	for _, group := range groups {
+		total = total.Add(c.convert(group))
+	}
+	return total
+}
+
+func (c *converter) missingRates() []string {
+	rates := make([]string, 0, len(c.missing))
+	for rate := range c.missing {
+		rates = append(rates, rate)
+	}
+	sort.Strings(rates)
+	return rates
+}
+
+// newConverter prepares to convert the given groups, loading only the rates
+// for their currencies and days
+func (s *DashboardService) newConverter(companyID uuid.UUID, groupSets ...[]amountGroup) (*converter, error) {
+	seen := make(map[string]bool)
+	var currencies []string
+	var from, to time.Time
+	for _, groups := range groupSets {
+		for _, group := range groups {
+			if !seen[group.Currency] {
+				seen[group.Currency] = true
+				currencies = append(currencies, group.Currency)
+			}
+			if from.IsZero() || group.Day.Before(from) {
+				from = group.Day
+			}
+			if group.Day.After(to) {
+				to = group.Day
+			}
+		}
+	}
+
+	table, err := s.rates.LoadRateTable(companyID, currencies, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return &converter{table: table, missing: make(map[string]struct{})}, nil
+}
+
 // GetStats retrieves dashboard KPI statistics
 func (s *DashboardService) GetStats(companyID uuid.UUID) (*DashboardStats, error) {
 	stats := &DashboardStats{}
 
-	// Total expense (all paid bills)
-	var totalExpense struct {
-		Total decimal.Decimal
+	// Total expense (all recorded payments, converted on their paid date)
+	var totalExpense []amountGroup
+	if err := s.paymentsByDay(companyID).Scan(&totalExpense).Error; err != nil {
+		return nil, err
 	}
-	s.db.Model(&models.Bill{}).
-		Select("COALESCE(SUM(amount), 0) as total").
-		Where("company_id = ? AND status = ?", companyID, models.StatusPaid).
-		Scan(&totalExpense)
-	stats.TotalExpense = totalExpense.Total
 
 	// Paid this month
 	startOfMonth := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -time.Now().Day()+1)
-	var paidThisMonth struct {
-		Total decimal.Decimal
+	var paidThisMonth []amountGroup
+	if err := s.paymentsByDay(companyID).
+		Where("payments.paid_date >= ?", startOfMonth).
+		Scan(&paidThisMonth).Error; err != nil {
+		return nil, err
 	}
-	s.db.Model(&models.Bill{}).
-		Select("COALESCE(SUM(amount), 0) as total").
-		Where("company_id = ? AND status = ? AND paid_date >= ?", companyID, models.StatusPaid, startOfMonth).
-		Scan(&paidThisMonth)
-	stats.PaidThisMonth = paidThisMonth.Total
 
-	// Unpaid amount
-	var unpaidAmount struct {
-		Total decimal.Decimal
+	// Unpaid amount (bill amounts less the payments made against them, converted on the due date)
+	var unpaidAmount []amountGroup
+	if err := s.db.Model(&models.Bill{}).
+		Select("bills.currency, bills.due_date as day, SUM(bills.amount - COALESCE(paid.total, 0)) as amount").
+		Joins("LEFT JOIN (SELECT bill_id, SUM(amount) as total FROM payments GROUP BY bill_id) paid ON paid.bill_id = bills.id").
+		Where("bills.company_id = ? AND bills.status IN ?", companyID,
+			[]models.BillStatus{models.StatusUnpaid, models.StatusPartiallyPaid, models.StatusOverdue}).
+		Group("bills.currency, bills.due_date").
+		Scan(&unpaidAmount).Error; err != nil {
+		return nil, err
 	}
-	s.db.Model(&models.Bill{}).
-		Select("COALESCE(SUM(amount), 0) as total").
-		Where("company_id = ? AND status IN (?, ?)", companyID, models.StatusUnpaid, models.StatusOverdue).
-		Scan(&unpaidAmount)
-	stats.UnpaidAmount = unpaidAmount.Total
 
-	// Overdue bills count
+	// Overdue bills count (status is kept current by the overdue sweeper)
 	s.db.Model(&models.Bill{}).
 		Where("company_id = ? AND status = ?", companyID, models.StatusOverdue).
 		Count(&stats.OverdueBillsCount)
 
-	// Also count bills that are past due but not marked overdue
-	var additionalOverdue int64
-	s.db.Model(&models.Bill{}).
-		Where("company_id = ? AND status = ? AND due_date < ?", companyID, models.StatusUnpaid, time.Now()).
-		Count(&additionalOverdue)
-	stats.OverdueBillsCount += additionalOverdue
-
 	// Expense change percent (compare last month to this month)
 	startOfLastMonth := startOfMonth.AddDate(0, -1, 0)
-	var lastMonthTotal struct {
-		Total decimal.Decimal
+	var lastMonth []amountGroup
+	if err := s.paymentsByDay(companyID).
+		Where("payments.paid_date >= ? AND payments.paid_date < ?", startOfLastMonth, startOfMonth).
+		Scan(&lastMonth).Error; err != nil {
+		return nil, err
 	}
-	s.db.Model(&models.Bill{}).
-		Select("COALESCE(SUM(amount), 0) as total").
-		Where("company_id = ? AND status = ? AND paid_date >= ? AND paid_date < ?",
-			companyID, models.StatusPaid, startOfLastMonth, startOfMonth).
-		Scan(&lastMonthTotal)
-
-	if !lastMonthTotal.Total.IsZero() {
-		change := stats.PaidThisMonth.Sub(lastMonthTotal.Total)
-		changePercent, _ := change.Div(lastMonthTotal.Total).Mul(decimal.NewFromInt(100)).Float64()
+
+	conv, err := s.newConverter(companyID, totalExpense, paidThisMonth, unpaidAmount, lastMonth)
+	if err != nil {
+		return nil, err
+	}
+	stats.Currency = conv.table.Base
+	stats.TotalExpense = conv.sum(totalExpense)
+	stats.PaidThisMonth = conv.sum(paidThisMonth)
+	stats.UnpaidAmount = conv.sum(unpaidAmount)
+	lastMonthTotal := conv.sum(lastMonth)
+
+	if !lastMonthTotal.IsZero() {
+		change := stats.PaidThisMonth.Sub(lastMonthTotal)
+		changePercent, _ := change.Div(lastMonthTotal).Mul(decimal.NewFromInt(100)).Float64()
 		stats.ExpenseChangePercent = changePercent
 	}
 
+	stats.MissingRates = conv.missingRates()
 	return stats, nil
 }
 
@@ -114,64 +187,86 @@ func (s *DashboardService) GetExpensesByMonth(companyID uuid.UUID, months int) (
 		months = 12
 	}
 
-	var results []MonthlyExpense
-
 	startDate := time.Now().AddDate(0, -months+1, 0).Truncate(24 * time.Hour)
 	startDate = startDate.AddDate(0, 0, -startDate.Day()+1)
 
-	rows, err := s.db.Model(&models.Bill{}).
-		Select("TO_CHAR(paid_date, 'YYYY-MM') as month, COALESCE(SUM(amount), 0) as amount").
-		Where("company_id = ? AND status = ? AND paid_date >= ?", companyID, models.StatusPaid, startDate).
-		Group("TO_CHAR(paid_date, 'YYYY-MM')").
-		Order("month ASC").
-		Rows()
+	var groups []amountGroup
+	err := s.payments(companyID).
+		Select("TO_CHAR(payments.paid_date, 'YYYY-MM') as key, bills.currency, payments.paid_date as day, SUM(payments.amount) as amount").
+		Where("payments.paid_date >= ?", startDate).
+		Group("TO_CHAR(payments.paid_date, 'YYYY-MM'), bills.currency, payments.paid_date").
+		Scan(&groups).Error
+	if err != nil {
+		return nil, err
+	}
 
+	conv, err := s.newConverter(companyID, groups)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var expense MonthlyExpense
-		if err := rows.Scan(&expense.Month, &expense.Amount); err != nil {
-			return nil, err
-		}
-		results = append(results, expense)
+	totals := make(map[string]decimal.Decimal)
+	for _, group := range groups {
+		totals[group.Key] = totals[group.Key].Add(conv.convert(group))
 	}
 
+	var results []MonthlyExpense
+	for month, amount := range totals {
+		results = append(results, MonthlyExpense{Month: month, Amount: amount})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Month < results[j].Month
+	})
+
 	return results, nil
 }
 
 // GetExpensesByCategory retrieves category expense breakdown
 func (s *DashboardService) GetExpensesByCategory(companyID uuid.UUID) ([]CategoryExpense, error) {
-	var results []CategoryExpense
-
-	rows, err := s.db.Model(&models.Bill{}).
-		Select("bills.category_id, categories.name as category_name, COALESCE(SUM(bills.amount), 0) as amount").
-		Joins("LEFT JOIN categories ON categories.id = bills.category_id").
-		Where("bills.company_id = ? AND bills.status = ?", companyID, models.StatusPaid).
-		Group("bills.category_id, categories.name").
-		Order("amount DESC").
-		Rows()
+	// Payments on bills with line items are split across the line items'
+	// categories in proportion to each line's share of the bill amount
+	var groups []amountGroup
+	err := s.payments(companyID).
+		Select("COALESCE(CAST(COALESCE(li.category_id, bills.category_id) AS text), '') as key, " +
+			"COALESCE(categories.name, 'Uncategorized') as name, bills.currency, payments.paid_date as day, " +
+			"SUM(CASE WHEN li.id IS NULL THEN payments.amount " +
+			"ELSE payments.amount * li.total / NULLIF(bills.amount, 0) END) as amount").
+		Joins("LEFT JOIN bill_line_items li ON li.bill_id = bills.id").
+		Joins("LEFT JOIN categories ON categories.id = COALESCE(li.category_id, bills.category_id)").
+		Group("COALESCE(li.category_id, bills.category_id), categories.name, bills.currency, payments.paid_date").
+		Scan(&groups).Error
+	if err != nil {
+		return nil, err
+	}
 
+	conv, err := s.newConverter(companyID, groups)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
 	var total decimal.Decimal
 	var rawResults []CategoryExpense
+	index := make(map[string]int)
 
-	for rows.Next() {
-		var expense CategoryExpense
-		if err := rows.Scan(&expense.CategoryID, &expense.CategoryName, &expense.Amount); err != nil {
-			return nil, err
+	for _, group := range groups {
+		amount := conv.convert(group)
+		i, ok := index[group.Key]
+		if !ok {
+			categoryID, _ := uuid.Parse(group.Key)
+			rawResults = append(rawResults, CategoryExpense{CategoryID: categoryID, CategoryName: group.Name})
+			i = len(rawResults) - 1
+			index[group.Key] = i
 		}
-		rawResults = append(rawResults, expense)
-		total = total.Add(expense.Amount)
+		rawResults[i].Amount = rawResults[i].Amount.Add(amount)
+		total = total.Add(amount)
 	}
 
+	sort.Slice(rawResults, func(i, j int) bool {
+		return rawResults[i].Amount.GreaterThan(rawResults[j].Amount)
+	})
+
 	// Calculate percentages
+	var results []CategoryExpense
 	for _, r := range rawResults {
 		pct := float64(0)
 		if !total.IsZero() {
@@ -188,3 +283,17 @@ func (s *DashboardService) GetExpensesByCategory(companyID uuid.UUID) ([]Categor
 
 	return results, nil
 }
+
+// payments scopes a query to the company's payments on bills that still exist
+func (s *DashboardService) payments(companyID uuid.UUID) *gorm.DB {
+	return s.db.Model(&models.Payment{}).
+		Joins("JOIN bills ON bills.id = payments.bill_id AND bills.deleted_at IS NULL").
+		Where("payments.company_id = ?", companyID)
+}
+
+// paymentsByDay sums payments per bill currency and paid date
+func (s *DashboardService) paymentsByDay(companyID uuid.UUID) *gorm.DB {
+	return s.payments(companyID).
+		Select("bills.currency, payments.paid_date as day, SUM(payments.amount) as amount").
+		Group("bills.currency, payments.paid_date")
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"github.com/dhani/bill-tracker-backend/internal/einvoice"
+	"github.com/dhani/bill-tracker-backend/internal/models"
+)
+
+// EInvoiceService records structured e-invoices (UBL, ZUGFeRD, Factur-X) as draft bills
+type EInvoiceService struct {
+	db          *gorm.DB
+	bills       *BillService
+	attachments *AttachmentService
+}
+
+func NewEInvoiceService(db *gorm.DB, attachments *AttachmentService) *EInvoiceService {
+	return &EInvoiceService{db: db, bills: NewBillService(db), attachments: attachments}
+}
+
+// EInvoiceResult describes the bill created from an e-invoice
+type EInvoiceResult struct {
+	Format        einvoice.Format        `json:"format"`
+	Bill          *models.Bill           `json:"bill"`
+	VendorCreated bool                   `json:"vendor_created"`
+	Attachment    *models.BillAttachment `json:"attachment"`
+	Warnings      []string               `json:"warnings"`
+}
+
+// Ingest parses an e-invoice and records it as a draft bill. The seller is
+// matched to a vendor by tax ID, then by name, and created when neither
+// matches. The original document is stored as the bill's attachment.
+func (s *EInvoiceService) Ingest(ctx context.Context, companyID, userID uuid.UUID, filename string, r io.Reader) (*EInvoiceResult, error) {
+	if err := requireVerifiedEmail(s.db, userID, models.EmailVerificationPolicy.BlocksBillCreation); err != nil {
+		return nil, err
+	}
+
+	file, err := s.attachments.spool(r)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	inv, err := einvoice.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	input, warnings := eInvoiceBillInput(inv)
+	result := &EInvoiceResult{Format: inv.Format, Warnings: warnings}
+
+	key, err := s.attachments.store(ctx, companyID, file)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		vendor, created, err := matchVendor(tx, companyID, inv.Supplier)
+		if err != nil {
+			return err
+		}
+		input.VendorID = &vendor.ID
+		result.VendorCreated = created
+
+		bill, err := newBill(tx, companyID, userID, input)
+		if err != nil {
+			return err
+		}
+		details := fmt.Sprintf("Bill created from %s e-invoice %s", strings.ToUpper(string(inv.Format)), sanitizeFilename(filename))
+		if err := s.bills.insert(tx, bill, input.LineItems, details); err != nil {
+			return err
+		}
+		bill.Vendor = vendor
+
+		attachment, _, err := s.attachments.attach(ctx, tx, companyID, bill.ID, userID, filename, file)
+		if err != nil {
+			return err
+		}
+		result.Bill = bill
+		result.Attachment = attachment
+		return nil
+	})
+	if err != nil {
+		s.attachments.discard(ctx, key)
+		return nil, err
+	}
+
+	s.attachments.signer.Sign(companyID, result.Attachment)
+	return result, nil
+}
+
+// eInvoiceBillInput maps an e-invoice to draft bill input, with warnings
+// about anything that could not be taken over as is
+func eInvoiceBillInput(inv *einvoice.Invoice) (CreateBillInput, []string) {
+	warnings := []string{}
+
+	dueDate := inv.IssueDate
+	if inv.DueDate != nil {
+		dueDate = *inv.DueDate
+	} else {
+		warnings = append(warnings, "The invoice has no due date; the issue date was used")
+	}
+
+	lineItems, ok := eInvoiceLineItems(inv)
+	if !ok {
+		warnings = append(warnings,
+			"Line items were not imported because they do not add up to the amount due (document-level allowances, charges or prepayments)")
+	}
+
+	input := CreateBillInput{
+		Title:         truncate(fmt.Sprintf("%s invoice %s", inv.Supplier.Name, inv.Number), 255),
+		InvoiceNumber: &inv.Number,
+		Amount:        inv.Payable,
+		Currency:      inv.Currency,
+		DueDate:       dueDate,
+		Status:        models.StatusDraft,
+		LineItems:     lineItems,
+	}
+	if inv.Note != "" {
+		input.Notes = &inv.Note
+	}
+	return input, warnings
+}
+
+// eInvoiceLineItems converts invoice lines to line items carrying their VAT.
+// Lines are only usable when they add up to the amount due; per-line tax
+// rounding may be off by a cent per line, which is absorbed by the last line.
+func eInvoiceLineItems(inv *einvoice.Invoice) ([]LineItemInput, bool) {
+	if len(inv.Lines) == 0 {
+		return nil, true
+	}
+
+	items := make([]LineItemInput, len(inv.Lines))
+	total := decimal.Zero
+	hundred := decimal.NewFromInt(100)
+	for i, line := range inv.Lines {
+		if line.NetAmount.IsNegative() || line.TaxPercent.IsNegative() {
+			return nil, false
+		}
+
+		// Keep quantity and price when they reproduce the line amount at
+		// cent precision; otherwise record the line as a single unit
+		quantity, price := line.Quantity, line.UnitPrice
+		if !price.Equal(price.Round(2)) || !quantity.Mul(price).Round(2).Equal(line.NetAmount.Round(2)) {
+			quantity, price = decimal.NewFromInt(1), line.NetAmount.Round(2)
+		}
+		tax := line.NetAmount.Mul(line.TaxPercent).Div(hundred).Round(2)
+
+		items[i] = LineItemInput{
+			Description: truncate(line.Description, 500),
+			Quantity:    &quantity,
+			UnitPrice:   price,
+			TaxAmount:   tax,
+		}
+		total = total.Add(quantity.Mul(price).Add(tax).Round(2))
+	}
+
+	diff := inv.Payable.Round(2).Sub(total)
+	tolerance := decimal.New(int64(len(items)), -2)
+	if diff.Abs().GreaterThan(tolerance) {
+		return nil, false
+	}
+	last := &items[len(items)-1]
+	last.TaxAmount = last.TaxAmount.Add(diff)
+	if last.TaxAmount.IsNegative() {
+		return nil, false
+	}
+	return items, true
+}
+
+// matchVendor finds the vendor for an invoice seller, creating it when needed
+func matchVendor(tx *gorm.DB, companyID uuid.UUID, party einvoice.Party) (*models.Vendor, bool, error) {
+	taxID := normalizeTaxID(&party.TaxID)
+	if taxID != nil && utf8.RuneCountInString(*taxID) > maxTaxIDLength {
+		return nil, false, fmt.Errorf("%w: seller tax ID is longer than %d characters", einvoice.ErrInvalidInvoice, maxTaxIDLength)
+	}
+
+	var vendor models.Vendor
+	if taxID != nil {
+		err := tx.Where("company_id = ? AND tax_id = ?", companyID, *taxID).First(&vendor).Error
+		if err == nil {
+			return &vendor, false, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, err
+		}
+	}
+
+	err := tx.Where("company_id = ? AND LOWER(TRIM(name)) = ?", companyID, strings.ToLower(strings.TrimSpace(party.Name))).
+		First(&vendor).Error
+	if err == nil {
+		// Remember the tax ID so the next invoice matches on it
+		if vendor.TaxID == nil && taxID != nil {
+			if err := tx.Model(&vendor).Update("tax_id", *taxID).Error; err != nil {
+				return nil, false, err
+			}
+		}
+		return &vendor, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	vendor = models.Vendor{
+		CompanyID: companyID,
+		Name:      truncate(party.Name, 255),
+		TaxID:     taxID,
+	}
+	if party.Email != "" {
+		vendor.ContactEmail = &party.Email
+	}
+	if party.Address != "" {
+		vendor.Address = &party.Address
+	}
+	if err := tx.Create(&vendor).Error; err != nil {
+		return nil, false, err
+	}
+	return &vendor, true, nil
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
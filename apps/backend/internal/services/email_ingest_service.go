@@ -0,0 +1,328 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"github.com/dhani/bill-tracker-backend/internal/einvoice"
+	"github.com/dhani/bill-tracker-backend/internal/models"
+	"github.com/dhani/bill-tracker-backend/internal/smtpd"
+)
+
+// maxEmailNotes bounds how much of an email body is copied into bill notes
+const maxEmailNotes = 4000
+
+// EmailIngestService turns mail sent to a company's inbound address into
+// draft bills. It implements smtpd.Handler.
+//
+// Anyone who knows the address can create drafts, so the token in the
+// address should be treated like a password; drafts still need a person to
+// complete and submit them. The From: header can be forged, so drafts are
+// owned by the company's admin and the sender is only noted on the bill.
+type EmailIngestService struct {
+	db          *gorm.DB
+	bills       *BillService
+	attachments *AttachmentService
+	prefix      string
+	domain      string
+}
+
+func NewEmailIngestService(db *gorm.DB, attachments *AttachmentService, prefix, domain string) *EmailIngestService {
+	return &EmailIngestService{
+		db:          db,
+		bills:       NewBillService(db),
+		attachments: attachments,
+		prefix:      strings.ToLower(prefix),
+		domain:      strings.ToLower(domain),
+	}
+}
+
+var errUnknownMailbox = &smtpd.Error{Code: 550, Message: "5.1.1 Mailbox unavailable"}
+
+// Recipient accepts addresses of the form <prefix>+<token>@<domain>
+func (s *EmailIngestService) Recipient(ctx context.Context, address string) error {
+	if _, err := s.company(ctx, address); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Deliver creates one draft bill per company the message was addressed to.
+// A failure is reported to the sender, who then retries, only while no bill
+// has been created; after that it is logged so the retry does not create
+// the other companies' bills again.
+func (s *EmailIngestService) Deliver(ctx context.Context, envelope smtpd.Envelope, data []byte) error {
+	email, err := parseEmail(data)
+	if err != nil {
+		return &smtpd.Error{Code: 554, Message: "5.6.0 " + err.Error()}
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	created := 0
+	for _, recipient := range envelope.To {
+		company, err := s.company(ctx, recipient)
+		if err != nil || seen[company.ID] {
+			continue
+		}
+		seen[company.ID] = true
+
+		bill, err := s.createBill(ctx, company, envelope.From, email)
+		var duplicate *DuplicateBillError
+		if errors.As(err, &duplicate) {
+			// The same invoice was sent twice; accept it so the sender stops retrying
+			log.Printf("Ignored email to %s: %v", recipient, err)
+			continue
+		}
+		if err != nil {
+			if created == 0 {
+				if errors.Is(err, ErrEmailVerificationRequired) {
+					// Retrying does not help until an admin verifies their address
+					return &smtpd.Error{Code: 550, Message: "5.7.1 " + err.Error()}
+				}
+				return err
+			}
+			log.Printf("Failed to create bill from email to %s: %v", recipient, err)
+			continue
+		}
+		created++
+		log.Printf("Created draft bill %s from email to %s", bill.ID, recipient)
+	}
+	return nil
+}
+
+// company resolves an inbound address to its company
+func (s *EmailIngestService) company(ctx context.Context, address string) (*models.Company, error) {
+	local, domain, ok := strings.Cut(strings.ToLower(address), "@")
+	if !ok || domain != s.domain {
+		return nil, errUnknownMailbox
+	}
+	prefix, token, ok := strings.Cut(local, "+")
+	if !ok || prefix != s.prefix || token == "" {
+		return nil, errUnknownMailbox
+	}
+
+	var company models.Company
+	if err := s.db.WithContext(ctx).Where("inbound_token = ?", token).First(&company).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errUnknownMailbox
+		}
+		return nil, err
+	}
+	return &company, nil
+}
+
+// createBill drafts a bill from the email. An attached e-invoice supplies
+// the amount, dates, invoice number and vendor; otherwise the subject becomes
+// the title and the vendor is guessed from the sender's domain.
+func (s *EmailIngestService) createBill(ctx context.Context, company *models.Company, envelopeFrom string, email *inboundEmail) (*models.Bill, error) {
+	sender := envelopeFrom
+	if email.From != nil {
+		sender = email.From.Address
+	}
+
+	// Store the documents first; the transaction only records them
+	var files []*spooledFile
+	var filenames, keys []string
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+	for _, attachment := range email.Attachments {
+		file, err := s.attachments.spool(bytes.NewReader(attachment.Data))
+		if errors.Is(err, ErrUnsupportedFileType) || errors.Is(err, ErrAttachmentTooLarge) {
+			// Keep the bill; signatures, calendar invites and the like are not documents
+			log.Printf("Skipped attachment %q of email from %s: %v", attachment.Filename, sender, err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+		filenames = append(filenames, attachment.Filename)
+
+		key, err := s.attachments.store(ctx, company.ID, file)
+		if err != nil {
+			s.discard(ctx, keys)
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	var bill *models.Bill
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		userID, err := s.owner(tx, company)
+		if err != nil {
+			return err
+		}
+
+		input, vendor, err := s.billInput(tx, company, sender, email)
+		if err != nil {
+			return err
+		}
+		if vendor != nil {
+			input.VendorID = &vendor.ID
+		}
+
+		bill, err = newBill(tx, company.ID, userID, input)
+		if err != nil {
+			return err
+		}
+		if err := s.bills.insert(tx, bill, input.LineItems, "Bill created from email from "+sender); err != nil {
+			return err
+		}
+		bill.Vendor = vendor
+
+		for i, file := range files {
+			stored, _, err := s.attachments.attach(ctx, tx, company.ID, bill.ID, userID, filenames[i], file)
+			if err != nil {
+				return err
+			}
+			bill.Attachments = append(bill.Attachments, *stored)
+		}
+		return nil
+	})
+	if err != nil {
+		s.discard(ctx, keys)
+		return nil, err
+	}
+	return bill, nil
+}
+
+// discard removes stored documents of an email whose bill was not created
+func (s *EmailIngestService) discard(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		s.attachments.discard(ctx, key)
+	}
+}
+
+// billInput drafts the bill input and picks the vendor for an email
+func (s *EmailIngestService) billInput(tx *gorm.DB, company *models.Company, sender string, email *inboundEmail) (CreateBillInput, *models.Vendor, error) {
+	for _, attachment := range email.Attachments {
+		inv, err := einvoice.Parse(attachment.Data)
+		if err != nil {
+			continue
+		}
+		input, _ := eInvoiceBillInput(inv)
+		vendor, _, err := matchVendor(tx, company.ID, inv.Supplier)
+		return input, vendor, err
+	}
+
+	title := strings.TrimSpace(email.Subject)
+	for _, prefix := range []string{"fwd:", "fw:", "wg:", "tr:"} {
+		if len(title) >= len(prefix) && strings.EqualFold(title[:len(prefix)], prefix) {
+			title = strings.TrimSpace(title[len(prefix):])
+		}
+	}
+	if title == "" {
+		title = "Email from " + sender
+	}
+
+	notes := "Received by email from " + sender
+	if email.Text != "" {
+		notes += "\n\n" + truncate(email.Text, maxEmailNotes)
+	}
+
+	// The amount is unknown until someone reads the documents; the due date
+	// defaults to the day the email was sent
+	sent := email.Date.In(company.Location())
+	input := CreateBillInput{
+		Title:    truncate(title, 255),
+		Amount:   decimal.Zero,
+		Currency: company.BaseCurrency,
+		DueDate:  time.Date(sent.Year(), sent.Month(), sent.Day(), 0, 0, 0, 0, time.UTC),
+		Status:   models.StatusDraft,
+		Notes:    &notes,
+	}
+
+	// Forwarded messages name the vendor; the sender is then usually a colleague
+	candidates := make([]string, 0, len(email.Forwarded)+1)
+	for _, addr := range email.Forwarded {
+		candidates = append(candidates, addr.Address)
+	}
+	candidates = append(candidates, sender)
+
+	vendor, err := s.vendorByDomain(tx, company.ID, candidates)
+	return input, vendor, err
+}
+
+// vendorByDomain returns the first vendor whose website or contact email
+// shares a domain with one of the addresses, in order
+func (s *EmailIngestService) vendorByDomain(tx *gorm.DB, companyID uuid.UUID, addresses []string) (*models.Vendor, error) {
+	var vendors []models.Vendor
+	if err := tx.Where("company_id = ? AND (website IS NOT NULL OR contact_email IS NOT NULL)", companyID).
+		Order("created_at ASC").
+		Find(&vendors).Error; err != nil {
+		return nil, err
+	}
+
+	for _, address := range addresses {
+		domain := emailDomain(address)
+		if domain == "" {
+			continue
+		}
+		for i := range vendors {
+			if vendorDomainMatches(&vendors[i], domain) {
+				return &vendors[i], nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// vendorDomainMatches compares domains ignoring "www." and allowing mail
+// from a subdomain, e.g. billing.acme.com for a vendor at acme.com
+func vendorDomainMatches(vendor *models.Vendor, domain string) bool {
+	var domains []string
+	if vendor.ContactEmail != nil {
+		domains = append(domains, emailDomain(*vendor.ContactEmail))
+	}
+	if vendor.Website != nil {
+		website := strings.TrimSpace(*vendor.Website)
+		if !strings.Contains(website, "://") {
+			website = "https://" + website
+		}
+		if u, err := url.Parse(website); err == nil {
+			domains = append(domains, strings.ToLower(u.Hostname()))
+		}
+	}
+
+	for _, candidate := range domains {
+		candidate = strings.TrimPrefix(candidate, "www.")
+		if candidate != "" && (domain == candidate || strings.HasSuffix(domain, "."+candidate)) {
+			return true
+		}
+	}
+	return false
+}
+
+// owner picks the user a bill from email is recorded for: the company's
+// longest-standing admin, who must have verified their address when the
+// company's policy keeps unverified users from creating bills. The sender is
+// not trusted to name a user.
+func (s *EmailIngestService) owner(tx *gorm.DB, company *models.Company) (uuid.UUID, error) {
+	query := tx.Where("company_id = ? AND role = ?", company.ID, models.RoleAdmin)
+	if company.EmailVerification.BlocksBillCreation() {
+		query = query.Where("email_verified = ?", true)
+	}
+
+	var user models.User
+	err := query.Order("created_at ASC").First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if company.EmailVerification.BlocksBillCreation() {
+			return uuid.Nil, fmt.Errorf("company %s has no verified admin to own emailed bills: %w", company.ID, ErrEmailVerificationRequired)
+		}
+		return uuid.Nil, fmt.Errorf("company %s has no admin to own emailed bills", company.ID)
+	}
+	return user.ID, err
+}
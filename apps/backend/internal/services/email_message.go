@@ -0,0 +1,221 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var ErrInvalidEmail = errors.New("invalid email message")
+
+const (
+	maxMIMEDepth       = 10
+	maxEmailAttachment = 20
+)
+
+// inboundEmail is the part of a received message used to draft a bill
+type inboundEmail struct {
+	From        *mail.Address
+	Subject     string
+	Date        time.Time
+	Text        string
+	Attachments []emailAttachment
+	// Forwarded holds the senders of messages forwarded inline or attached,
+	// which are usually the vendor rather than the person forwarding
+	Forwarded []*mail.Address
+}
+
+type emailAttachment struct {
+	Filename string
+	Data     []byte
+}
+
+var (
+	wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+	htmlTags    = regexp.MustCompile(`(?s)<(script|style)[^>]*>.*?</(script|style)>|<[^>]+>`)
+	// forwardedFrom finds the sender line of an inline forwarded message
+	forwardedFrom = regexp.MustCompile(`(?mi)^[> ]*(?:From|Von|De|Van):\s*(.+)$`)
+)
+
+// parseEmail reads a MIME message, collecting its text, attachments and the
+// senders of any forwarded messages
+func parseEmail(data []byte) (*inboundEmail, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEmail, err)
+	}
+
+	email := &inboundEmail{Subject: decodeHeader(msg.Header.Get("Subject"))}
+	if from, err := mail.ParseAddress(decodeHeader(msg.Header.Get("From"))); err == nil {
+		email.From = from
+	}
+	if date, err := msg.Header.Date(); err == nil {
+		email.Date = date
+	} else {
+		email.Date = time.Now()
+	}
+
+	if err := email.walk(textproto.MIMEHeader(msg.Header), msg.Body, 0); err != nil {
+		return nil, err
+	}
+
+	for _, match := range forwardedFrom.FindAllStringSubmatch(email.Text, -1) {
+		if addr, err := mail.ParseAddress(strings.TrimSpace(match[1])); err == nil {
+			email.Forwarded = append(email.Forwarded, addr)
+		}
+	}
+	return email, nil
+}
+
+// walk visits a MIME entity and its children
+func (e *inboundEmail) walk(header textproto.MIMEHeader, body io.Reader, depth int) error {
+	if depth > maxMIMEDepth {
+		return fmt.Errorf("%w: MIME structure nested too deeply", ErrInvalidEmail)
+	}
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "application/octet-stream", nil
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidEmail, err)
+			}
+			if err := e.walk(part.Header, part, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	content, err := io.ReadAll(decodeTransfer(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidEmail, err)
+	}
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := decodeHeader(dispositionParams["filename"])
+	if filename == "" {
+		filename = decodeHeader(params["name"])
+	}
+
+	switch {
+	case mediaType == "message/rfc822":
+		// A message forwarded as an attachment: its sender is the vendor and
+		// its own attachments are the documents we are after
+		inner, err := mail.ReadMessage(bytes.NewReader(content))
+		if err != nil {
+			return nil
+		}
+		if from, err := mail.ParseAddress(decodeHeader(inner.Header.Get("From"))); err == nil {
+			e.Forwarded = append(e.Forwarded, from)
+		}
+		return e.walk(textproto.MIMEHeader(inner.Header), inner.Body, depth+1)
+	case filename == "" && disposition != "attachment" && mediaType == "text/plain":
+		e.appendText(decodeCharset(params["charset"], content))
+	case filename == "" && disposition != "attachment" && mediaType == "text/html":
+		// Only used when the message has no plain text alternative
+		if e.Text == "" {
+			e.appendText(htmlToText(decodeCharset(params["charset"], content)))
+		}
+	case disposition == "inline" && header.Get("Content-Id") != "":
+		// Images embedded in an HTML body, such as signature logos
+	case len(content) > 0 && len(e.Attachments) < maxEmailAttachment:
+		if filename == "" {
+			filename = "attachment"
+		}
+		e.Attachments = append(e.Attachments, emailAttachment{Filename: filename, Data: content})
+	}
+	return nil
+}
+
+func (e *inboundEmail) appendText(text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	if e.Text != "" {
+		e.Text += "\n\n"
+	}
+	e.Text += text
+}
+
+func decodeTransfer(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	default:
+		return body
+	}
+}
+
+// decodeHeader decodes RFC 2047 encoded words, keeping the raw value on error
+func decodeHeader(value string) string {
+	decoded, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// charsetReader supports the charsets mail clients commonly use besides
+// UTF-8; other charsets fall back to the raw bytes
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	content, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	return strings.NewReader(decodeCharset(charset, content)), nil
+}
+
+func decodeCharset(charset string, content []byte) string {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "windows-1252", "us-ascii", "ascii":
+		// Latin-1 bytes map one to one onto the first 256 code points;
+		// windows-1252 differs only in rarely used punctuation
+		runes := make([]rune, len(content))
+		for i, b := range content {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	default:
+		return strings.ToValidUTF8(string(content), "�")
+	}
+}
+
+func htmlToText(body string) string {
+	body = strings.NewReplacer("<br>", "\n", "<br/>", "\n", "<br />", "\n", "</p>", "\n", "</div>", "\n", "</tr>", "\n").Replace(body)
+	return html.UnescapeString(htmlTags.ReplaceAllString(body, ""))
+}
+
+// emailDomain returns the lower-cased domain of an address
+func emailDomain(address string) string {
+	_, domain, ok := strings.Cut(address, "@")
+	if !ok {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(domain))
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/dhani/bill-tracker-backend/internal/mailer"
+	"github.com/dhani/bill-tracker-backend/internal/models"
+)
+
+var (
+	ErrInvalidVerificationToken  = errors.New("invalid email verification link")
+	ErrVerificationTokenExpired  = errors.New("email verification link has expired")
+	ErrVerificationRateLimited   = errors.New("a verification email was sent recently, please wait before requesting another")
+	ErrEmailVerificationRequired = errors.New("please verify your email address first")
+)
+
+// EmailVerificationService emails users a link proving they own their
+// address. Tokens are signed rather than stored: a token carries the user
+// and an expiry and is signed over the address it was sent to, so it stops
+// working if the address changes.
+type EmailVerificationService struct {
+	db          *gorm.DB
+	mailer      mailer.Mailer
+	key         []byte
+	ttl         time.Duration
+	cooldown    time.Duration
+	dailyLimit  int64
+	resends     *rateLimiter
+	frontendURL string
+}
+
+// NewEmailVerificationService creates the service. An address gets at most
+// dailyLimit links a day, and a client IP may ask for ipLimit resends an hour.
+func NewEmailVerificationService(db *gorm.DB, mailer mailer.Mailer, secret string, ttl, cooldown time.Duration, dailyLimit, ipLimit int64, frontendURL string) *EmailVerificationService {
+	return &EmailVerificationService{
+		db:          db,
+		mailer:      mailer,
+		key:         hmacSHA256([]byte(secret), "email-verification"),
+		ttl:         ttl,
+		cooldown:    cooldown,
+		dailyLimit:  dailyLimit,
+		resends:     newRateLimiter(ipLimit, time.Hour),
+		frontendURL: frontendURL,
+	}
+}
+
+// ResendVerificationInput holds the address to send a new link to
+type ResendVerificationInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// Send emails a verification link to an unverified user. At most one email
+// is sent per cooldown period, and no more than the daily limit in a day.
+func (s *EmailVerificationService) Send(ctx context.Context, user *models.User) error {
+	if user.EmailVerified {
+		return nil
+	}
+
+	now := time.Now()
+	newDay := "(verification_window_start IS NULL OR verification_window_start <= ?)"
+	dayAgo := now.Add(-24 * time.Hour)
+	result := s.db.Model(&models.User{}).
+		Where("id = ? AND (verification_sent_at IS NULL OR verification_sent_at <= ?)", user.ID, now.Add(-s.cooldown)).
+		Where("("+newDay+" OR verifications_sent < ?)", dayAgo, s.dailyLimit).
+		Updates(map[string]interface{}{
+			"verification_sent_at":      now,
+			"verification_window_start": gorm.Expr("CASE WHEN "+newDay+" THEN ? ELSE verification_window_start END", dayAgo, now),
+			"verifications_sent":        gorm.Expr("CASE WHEN "+newDay+" THEN 1 ELSE verifications_sent + 1 END", dayAgo),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVerificationRateLimited
+	}
+
+	link := s.frontendURL + "/verify-email?" + url.Values{"token": {s.token(user, now.Add(s.ttl))}}.Encode()
+	hours := int(s.ttl.Hours())
+
+	msg := &mailer.Message{
+		To:      []*mail.Address{{Name: user.Name, Address: user.Email}},
+		Subject: "Verify your email address",
+		Text: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening this link:\n\n%s\n\n"+
+			"The link expires in %d hours. If you did not create an account, you can ignore this email.\n",
+			user.Name, link, hours),
+		HTML: fmt.Sprintf(`<p>Hi %s,</p><p>Please confirm your email address:</p>`+
+			`<p><a href="%s">Verify email address</a></p>`+
+			`<p>The link expires in %d hours. If you did not create an account, you can ignore this email.</p>`,
+			html.EscapeString(user.Name), html.EscapeString(link), hours),
+	}
+	return s.mailer.Send(ctx, msg)
+}
+
+// Resend sends a new link to the user with the given address on behalf of
+// the client at ip. Unknown and already verified addresses are ignored.
+func (s *EmailVerificationService) Resend(ctx context.Context, input ResendVerificationInput, ip string) error {
+	if !s.resends.allow(ip, time.Now()) {
+		return ErrVerificationRateLimited
+	}
+
+	var user models.User
+	err := s.db.Where("LOWER(email) = ?", strings.ToLower(strings.TrimSpace(input.Email))).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.Send(ctx, &user)
+}
+
+// Verify marks the user a token was issued to as verified
+func (s *EmailVerificationService) Verify(token string) (*models.User, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidVerificationToken
+	}
+	userID, err := uuid.Parse(parts[0])
+	if err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	var user models.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	expected := s.signature(&user, expires)
+	if !hmac.Equal([]byte(parts[2]), []byte(expected)) {
+		return nil, ErrInvalidVerificationToken
+	}
+	if time.Now().Unix() > expires {
+		return nil, ErrVerificationTokenExpired
+	}
+
+	if !user.EmailVerified {
+		if err := s.db.Model(&user).Update("email_verified", true).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &user, nil
+}
+
+func (s *EmailVerificationService) token(user *models.User, expires time.Time) string {
+	return fmt.Sprintf("%s.%d.%s", user.ID, expires.Unix(), s.signature(user, expires.Unix()))
+}
+
+func (s *EmailVerificationService) signature(user *models.User, expires int64) string {
+	return hex.EncodeToString(hmacSHA256(s.key, fmt.Sprintf("%s\n%s\n%d", user.ID, strings.ToLower(user.Email), expires)))
+}
+
+// requireVerifiedEmail returns ErrEmailVerificationRequired when the user
+// has not verified their address and their company's policy blocks the action
+func requireVerifiedEmail(db *gorm.DB, userID uuid.UUID, blocked func(models.EmailVerificationPolicy) bool) error {
+	var row struct {
+		EmailVerified     bool
+		EmailVerification models.EmailVerificationPolicy
+	}
+	err := db.Table("users").
+		Select("users.email_verified, companies.email_verification").
+		Joins("JOIN companies ON companies.id = users.company_id").
+		Where("users.id = ?", userID).
+		Scan(&row).Error
+	if err != nil {
+		return err
+	}
+	if !row.EmailVerified && blocked(row.EmailVerification) {
+		return ErrEmailVerificationRequired
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/dhani/bill-tracker-backend/internal/currency"
+	"github.com/dhani/bill-tracker-backend/internal/models"
+)
+
+var (
+	ErrInvalidCurrency = errors.New("invalid currency code")
+	ErrRateNotFound    = errors.New("exchange rate not found")
+	ErrInvalidRateFile = errors.New("invalid exchange rate file")
+)
+
+type ExchangeRateService struct {
+	db *gorm.DB
+}
+
+func NewExchangeRateService(db *gorm.DB) *ExchangeRateService {
+	return &ExchangeRateService{db: db}
+}
+
+// CreateExchangeRateInput holds data for a manually entered rate
+type CreateExchangeRateInput struct {
+	Base          string          `json:"base" binding:"required"`
+	Quote         string          `json:"quote" binding:"required"`
+	Rate          decimal.Decimal `json:"rate" binding:"required"`
+	EffectiveDate time.Time       `json:"effective_date" binding:"required"`
+}
+
+// ExchangeRateFilters holds list filters
+type ExchangeRateFilters struct {
+	Base  string
+	Quote string
+}
+
+// normalizeCurrency validates an ISO 4217 code and returns it upper-cased
+func normalizeCurrency(code string) (string, error) {
+	code = currency.Normalize(code)
+	if !currency.IsValid(code) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidCurrency, code)
+	}
+	return code, nil
+}
+
+// List retrieves stored rates, newest first
+func (s *ExchangeRateService) List(companyID uuid.UUID, filters ExchangeRateFilters) ([]models.ExchangeRate, error) {
+	query := s.db.Where("company_id = ?", companyID)
+	if filters.Base != "" {
+		query = query.Where("base = ?", currency.Normalize(filters.Base))
+	}
+	if filters.Quote != "" {
+		query = query.Where("quote = ?", currency.Normalize(filters.Quote))
+	}
+
+	var rates []models.ExchangeRate
+	err := query.Order("effective_date DESC, base ASC, quote ASC").Limit(1000).Find(&rates).Error
+	return rates, err
+}
+
+// Create stores a single rate, replacing any rate for the same pair and date
+func (s *ExchangeRateService) Create(companyID uuid.UUID, input CreateExchangeRateInput) (*models.ExchangeRate, error) {
+	base, err := normalizeCurrency(input.Base)
+	if err != nil {
+		return nil, err
+	}
+	quote, err := normalizeCurrency(input.Quote)
+	if err != nil {
+		return nil, err
+	}
+	if !input.Rate.IsPositive() {
+		return nil, fmt.Errorf("%w: rate must be positive", ErrInvalidRateFile)
+	}
+
+	source := "manual"
+	rates := []models.ExchangeRate{{
+		CompanyID:     companyID,
+		Base:          base,
+		Quote:         quote,
+		Rate:          input.Rate,
+		EffectiveDate: input.EffectiveDate,
+		Source:        &source,
+	}}
+	if err := s.upsert(s.db, rates); err != nil {
+		return nil, err
+	}
+	return &rates[0], nil
+}
+
+// Import loads rates from a CSV file (date,base,quote,rate) or an ECB
+// reference rate XML file. The format is picked from the filename extension.
+func (s *ExchangeRateService) Import(companyID uuid.UUID, filename string, r io.Reader) (int, error) {
+	var (
+		parsed []currency.Rate
+		err    error
+		source string
+	)
+
+	switch strings.ToLower(filename[strings.LastIndex(filename, ".")+1:]) {
+	case "csv":
+		parsed, err = currency.ParseCSV(r)
+		source = "csv"
+	case "xml":
+		parsed, err = currency.ParseECB(r)
+		source = "ecb"
+	default:
+		return 0, fmt.Errorf("%w: expected a .csv or .xml file", ErrInvalidRateFile)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidRateFile, err)
+	}
+
+	rates := make([]models.ExchangeRate, len(parsed))
+	for i, rate := range parsed {
+		rates[i] = models.ExchangeRate{
+			CompanyID:     companyID,
+			Base:          rate.Base,
+			Quote:         rate.Quote,
+			Rate:          rate.Rate,
+			EffectiveDate: rate.EffectiveDate,
+			Source:        &source,
+		}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		return s.upsert(tx, rates)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(rates), nil
+}
+
+func (s *ExchangeRateService) upsert(tx *gorm.DB, rates []models.ExchangeRate) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "company_id"}, {Name: "base"}, {Name: "quote"}, {Name: "effective_date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "source", "updated_at"}),
+	}).CreateInBatches(rates, 500).Error
+}
+
+// ratePoint is a rate effective from a given day
+type ratePoint struct {
+	date time.Time
+	rate decimal.Decimal
+}
+
+// RateTable converts amounts into a company's base currency using the rate
+// in effect on a given day. It is loaded once per report, with only the rates
+// the report can use, so aggregates over many dates do not query the database
+// per conversion.
+type RateTable struct {
+	Base  string
+	pairs map[[2]string][]ratePoint
+}
+
+// LoadRateTable loads the rates needed to convert amounts in the given
+// currencies dated between from and to into the company's base currency
+func (s *ExchangeRateService) LoadRateTable(companyID uuid.UUID, currencies []string, from, to time.Time) (*RateTable, error) {
+	return loadRateTable(s.db, companyID, currencies, from, to)
+}
+
+// loadRateTable loads only the rates needed to convert the given currencies
+// into the company's base currency between from and to: rates of pairs
+// involving one of them (pivot rates included) effective in the range, and
+// the latest earlier rate of each pair, which is still in effect at from.
+func loadRateTable(db *gorm.DB, companyID uuid.UUID, currencies []string, from, to time.Time) (*RateTable, error) {
+	var company models.Company
+	if err := db.Select("id", "base_currency").First(&company, "id = ?", companyID).Error; err != nil {
+		return nil, err
+	}
+	table := &RateTable{Base: company.BaseCurrency, pairs: make(map[[2]string][]ratePoint)}
+	if table.Base == "" {
+		table.Base = "USD"
+	}
+
+	codes := []string{table.Base}
+	for _, code := range currencies {
+		if code = currency.Normalize(code); code != table.Base {
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) == 1 {
+		return table, nil
+	}
+
+	const day = "2006-01-02"
+	var rates []models.ExchangeRate
+	err := db.
+		Where("company_id = ? AND (base IN ? OR quote IN ?) AND effective_date <= ?", companyID, codes, codes, to.Format(day)).
+		Where("effective_date >= ? OR (base, quote, effective_date) IN (?)", from.Format(day),
+			db.Model(&models.ExchangeRate{}).
+				Select("base, quote, MAX(effective_date)").
+				Where("company_id = ? AND (base IN ? OR quote IN ?) AND effective_date < ?", companyID, codes, codes, from.Format(day)).
+				Group("base, quote")).
+		Order("effective_date ASC").
+		Find(&rates).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, rate := range rates {
+		key := [2]string{rate.Base, rate.Quote}
+		table.pairs[key] = append(table.pairs[key], ratePoint{date: rate.EffectiveDate, rate: rate.Rate})
+	}
+	return table, nil
+}
+
+// baseAmount converts a bill's amount into its company's base currency at
+// the rate in effect on the bill's due date
+func baseAmount(db *gorm.DB, bill *models.Bill) (decimal.Decimal, error) {
+	table, err := loadRateTable(db, bill.CompanyID, []string{bill.Currency}, bill.DueDate, bill.DueDate)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return table.Convert(bill.Amount, bill.Currency, bill.DueDate)
+}
+
+// Convert converts amount from the given currency into the table's base
+// currency using the latest rate effective on or before date. Direct, inverse
+// and cross rates through a common currency (e.g. ECB's EUR) are supported.
+func (t *RateTable) Convert(amount decimal.Decimal, from string, date time.Time) (decimal.Decimal, error) {
+	rate, err := t.Rate(from, t.Base, date)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return amount.Mul(rate).Round(2), nil
+}
+
+// Rate returns how many units of `to` one unit of `from` buys on date
+func (t *RateTable) Rate(from, to string, date time.Time) (decimal.Decimal, error) {
+	from, to = currency.Normalize(from), currency.Normalize(to)
+	if from == to {
+		return decimal.NewFromInt(1), nil
+	}
+
+	if rate, ok := t.lookup(from, to, date); ok {
+		return rate, nil
+	}
+
+	// Cross rate: pivot -> from and pivot -> to, as published by central banks
+	pivots := make([]string, 0)
+	for key := range t.pairs {
+		if key[1] == from || key[0] == from {
+			pivots = append(pivots, key[0], key[1])
+		}
+	}
+	sort.Strings(pivots)
+	for _, pivot := range pivots {
+		if pivot == from || pivot == to {
+			continue
+		}
+		fromRate, okFrom := t.lookup(pivot, from, date)
+		toRate, okTo := t.lookup(pivot, to, date)
+		if okFrom && okTo {
+			return toRate.DivRound(fromRate, 10), nil
+		}
+	}
+
+	return decimal.Zero, fmt.Errorf("%w: %s to %s on %s", ErrRateNotFound, from, to, date.Format("2006-01-02"))
+}
+
+// lookup finds a direct or inverse rate effective on date
+func (t *RateTable) lookup(from, to string, date time.Time) (decimal.Decimal, bool) {
+	if rate, ok := effectiveRate(t.pairs[[2]string{from, to}], date); ok {
+		return rate, true
+	}
+	if rate, ok := effectiveRate(t.pairs[[2]string{to, from}], date); ok {
+		return decimal.NewFromInt(1).DivRound(rate, 10), true
+	}
+	return decimal.Zero, false
+}
+
+// effectiveRate returns the latest point on or before date from a date-sorted slice
+func effectiveRate(points []ratePoint, date time.Time) (decimal.Decimal, bool) {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	i := sort.Search(len(points), func(i int) bool {
+		return points[i].date.After(day)
+	})
+	if i == 0 {
+		return decimal.Zero, false
+	}
+	return points[i-1].rate, true
+}
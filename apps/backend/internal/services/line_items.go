@@ -0,0 +1,117 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"github.com/dhani/bill-tracker-backend/internal/models"
+)
+
+var (
+	ErrLineItemsMismatch = errors.New("line item totals do not match bill amount")
+	ErrInvalidLineItem   = errors.New("invalid line item")
+)
+
+// LineItemInput holds data for a single bill line item
+type LineItemInput struct {
+	Description string           `json:"description" binding:"required"`
+	CategoryID  *uuid.UUID       `json:"category_id"`
+	Quantity    *decimal.Decimal `json:"quantity"`
+	UnitPrice   decimal.Decimal  `json:"unit_price" binding:"required"`
+	TaxAmount   decimal.Decimal  `json:"tax_amount"`
+}
+
+// buildLineItems validates line item input and computes each line's total.
+// Categories must belong to the company.
+func buildLineItems(tx *gorm.DB, companyID uuid.UUID, inputs []LineItemInput) ([]models.BillLineItem, decimal.Decimal, error) {
+	items := make([]models.BillLineItem, len(inputs))
+	total := decimal.Zero
+	categoryIDs := make(map[uuid.UUID]struct{})
+
+	for i, input := range inputs {
+		quantity := decimal.NewFromInt(1)
+		if input.Quantity != nil {
+			quantity = *input.Quantity
+		}
+		if !quantity.IsPositive() {
+			return nil, decimal.Zero, fmt.Errorf("%w: line %d quantity must be positive", ErrInvalidLineItem, i+1)
+		}
+		if input.UnitPrice.IsNegative() || input.TaxAmount.IsNegative() {
+			return nil, decimal.Zero, fmt.Errorf("%w: line %d amounts cannot be negative", ErrInvalidLineItem, i+1)
+		}
+
+		items[i] = models.BillLineItem{
+			CategoryID:  input.CategoryID,
+			Position:    i + 1,
+			Description: input.Description,
+			Quantity:    quantity,
+			UnitPrice:   input.UnitPrice,
+			TaxAmount:   input.TaxAmount,
+		}
+		items[i].Total = items[i].ComputeTotal()
+		total = total.Add(items[i].Total)
+
+		if input.CategoryID != nil {
+			categoryIDs[*input.CategoryID] = struct{}{}
+		}
+	}
+
+	if len(categoryIDs) > 0 {
+		ids := make([]uuid.UUID, 0, len(categoryIDs))
+		for id := range categoryIDs {
+			ids = append(ids, id)
+		}
+		var count int64
+		if err := tx.Model(&models.Category{}).
+			Where("company_id = ? AND id IN ?", companyID, ids).
+			Count(&count).Error; err != nil {
+			return nil, decimal.Zero, err
+		}
+		if count != int64(len(ids)) {
+			return nil, decimal.Zero, fmt.Errorf("%w: unknown category", ErrInvalidLineItem)
+		}
+	}
+
+	return items, total, nil
+}
+
+// reconcileLineItems checks that line items add up to the bill amount
+func reconcileLineItems(total, amount decimal.Decimal) error {
+	if !total.Equal(amount.Round(2)) {
+		return fmt.Errorf("%w: line items total %s, bill amount %s",
+			ErrLineItemsMismatch, total.StringFixed(2), amount.StringFixed(2))
+	}
+	return nil
+}
+
+// replaceLineItems swaps a bill's line items for a new set
+func replaceLineItems(tx *gorm.DB, billID uuid.UUID, items []models.BillLineItem) error {
+	if err := tx.Where("bill_id = ?", billID).Delete(&models.BillLineItem{}).Error; err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	for i := range items {
+		items[i].ID = uuid.Nil
+		items[i].BillID = billID
+	}
+	return tx.Create(&items).Error
+}
+
+// lineItemsTotal sums the stored line items of a bill; ok is false when it has none
+func lineItemsTotal(tx *gorm.DB, billID uuid.UUID) (decimal.Decimal, bool, error) {
+	var result struct {
+		Count int64
+		Total decimal.Decimal
+	}
+	err := tx.Model(&models.BillLineItem{}).
+		Select("COUNT(*) as count, COALESCE(SUM(total), 0) as total").
+		Where("bill_id = ?", billID).
+		Scan(&result).Error
+	return result.Total, result.Count > 0, err
+}
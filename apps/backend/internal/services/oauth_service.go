@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/dhani/bill-tracker-backend/internal/middleware"
+	"github.com/dhani/bill-tracker-backend/internal/models"
+	"github.com/dhani/bill-tracker-backend/internal/oidc"
+)
+
+// ErrEmailNotVerified is returned when an identity provider has not verified
+// the email address it asserts, so it cannot be trusted to pick an account
+var ErrEmailNotVerified = errors.New("email address is not verified by the identity provider")
+
+// IdentityProvider signs users in with an external provider using the
+// authorization code flow. *oidc.Provider implements it.
+type IdentityProvider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*oidc.Identity, error)
+}
+
+// SignInWithIdentity logs in the user linked to an external identity. An
+// unlinked identity is linked to the user with the same verified email, or
+// gets a new user and company on first sign-in.
+func (s *AuthService) SignInWithIdentity(provider string, identity *oidc.Identity, meta SessionMeta) (*AuthResponse, error) {
+	var user models.User
+	claimed := false
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var link models.UserIdentity
+		err := tx.Where("provider = ? AND subject = ?", provider, identity.Subject).First(&link).Error
+		if err == nil {
+			if err := tx.First(&user, "id = ?", link.UserID).Error; err != nil {
+				return err
+			}
+			return requireNoSSO(tx, user.CompanyID)
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if !identity.EmailVerified || identity.Email == "" {
+			return ErrEmailNotVerified
+		}
+
+		err = tx.Where("LOWER(email) = ?", identity.Email).First(&user).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if enforced, err := ssoEnforcedForEmail(tx, identity.Email); err != nil {
+				return err
+			} else if enforced {
+				return ErrSSORequired
+			}
+			if err := createIdentityUser(tx, identity, &user); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			if err := requireNoSSO(tx, user.CompanyID); err != nil {
+				return err
+			}
+		}
+
+		if !user.EmailVerified {
+			// The provider has just proven ownership of the address
+			claimed = true
+			if err := claimUnverifiedUser(tx, &user); err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&models.UserIdentity{
+			UserID:   user.ID,
+			Provider: provider,
+			Subject:  identity.Subject,
+			Email:    identity.Email,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if claimed {
+		middleware.ForgetUserSessions(user.ID, uuid.Nil)
+	}
+
+	return s.createSession(&user, provider, meta)
+}
+
+// claimUnverifiedUser hands an account whose address was never verified to
+// the person who has just proven they own it. Whoever registered it may not
+// have, so the password they chose and their sessions are removed.
+func claimUnverifiedUser(tx *gorm.DB, user *models.User) error {
+	err := tx.Model(user).Updates(map[string]interface{}{
+		"email_verified": true,
+		"password_hash":  "",
+	}).Error
+	if err != nil {
+		return err
+	}
+	return tx.Where("user_id = ?", user.ID).Delete(&models.Session{}).Error
+}
+
+// requireNoSSO rejects sign-in for users of a company that enforces SSO
+func requireNoSSO(tx *gorm.DB, companyID uuid.UUID) error {
+	enforced, err := ssoEnforced(tx, companyID)
+	if err != nil {
+		return err
+	}
+	if enforced {
+		return ErrSSORequired
+	}
+	return nil
+}
+
+// createIdentityUser creates a passwordless admin and their company for a
+// first sign-in with an external identity
+func createIdentityUser(tx *gorm.DB, identity *oidc.Identity, user *models.User) error {
+	name := strings.TrimSpace(identity.Name)
+	if name == "" {
+		name, _, _ = strings.Cut(identity.Email, "@")
+	}
+
+	company := models.Company{
+		Name: name + "'s Company",
+	}
+	if err := tx.Create(&company).Error; err != nil {
+		return err
+	}
+
+	*user = models.User{
+		CompanyID:     company.ID,
+		Name:          name,
+		Email:         identity.Email,
+		Role:          models.RoleAdmin, // First user is admin
+		EmailVerified: true,
+	}
+	if identity.Picture != "" {
+		user.AvatarURL = &identity.Picture
+	}
+	return tx.Create(user).Error
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/dhani/bill-tracker-backend/internal/models"
+)
+
+type OverdueService struct {
+	db *gorm.DB
+}
+
+func NewOverdueService(db *gorm.DB) *OverdueService {
+	return &OverdueService{db: db}
+}
+
+// SweepOverdue moves unpaid and partially paid bills whose due date has ended in the company's
+// time zone to overdue, logging a status change activity for each bill.
+// Rows are locked with SKIP LOCKED so concurrent sweepers never double-log.
+func (s *OverdueService) SweepOverdue(now time.Time) (int, error) {
+	var companies []models.Company
+	if err := s.db.Find(&companies).Error; err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, company := range companies {
+		count, err := s.sweepCompany(&company, now)
+		if err != nil {
+			return total, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+func (s *OverdueService) sweepCompany(company *models.Company, now time.Time) (int, error) {
+	// A bill is overdue once its due date has fully passed in local time
+	today := now.In(company.Location()).Format("2006-01-02")
+	count := 0
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var bills []models.Bill
+		err := tx.Select("id", "status").
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("company_id = ? AND status IN ? AND due_date < ?", company.ID,
+				[]models.BillStatus{models.StatusUnpaid, models.StatusPartiallyPaid}, today).
+			Find(&bills).Error
+		if err != nil || len(bills) == 0 {
+			return err
+		}
+
+		billIDs := make([]uuid.UUID, len(bills))
+		for i, bill := range bills {
+			billIDs[i] = bill.ID
+		}
+
+		if err := tx.Model(&models.Bill{}).
+			Where("id IN ?", billIDs).
+			Update("status", models.StatusOverdue).Error; err != nil {
+			return err
+		}
+
+		for _, bill := range bills {
+			if err := recordTransition(tx, bill.ID, nil, bill.Status, models.StatusOverdue, "due date passed"); err != nil {
+				return err
+			}
+		}
+
+		count = len(bills)
+		return nil
+	})
+
+	return count, err
+}
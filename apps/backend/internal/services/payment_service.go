@@ -0,0 +1,175 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/dhani/bill-tracker-backend/internal/models"
+)
+
+var (
+	ErrInvalidPaymentAmount = errors.New("payment amount must be greater than zero")
+	ErrOverpayment          = errors.New("payment exceeds outstanding balance")
+	ErrBillAlreadyPaid      = errors.New("bill is already paid")
+)
+
+type PaymentService struct {
+	db        *gorm.DB
+	recurring *RecurringService
+	approvals *ApprovalService
+}
+
+func NewPaymentService(db *gorm.DB) *PaymentService {
+	return &PaymentService{db: db, recurring: NewRecurringService(db), approvals: NewApprovalService(db)}
+}
+
+// RecordPaymentInput holds data for recording a payment
+type RecordPaymentInput struct {
+	Amount    decimal.Decimal `json:"amount" binding:"required"`
+	PaidDate  *time.Time      `json:"paid_date"`
+	Method    *string         `json:"method"`
+	Reference *string         `json:"reference"`
+	Notes     *string         `json:"notes"`
+}
+
+// List retrieves the payment ledger for a bill
+func (s *PaymentService) List(companyID, billID uuid.UUID) ([]models.Payment, error) {
+	var payments []models.Payment
+	err := s.db.
+		Preload("User").
+		Where("company_id = ? AND bill_id = ?", companyID, billID).
+		Order("paid_date ASC, created_at ASC").
+		Find(&payments).Error
+
+	return payments, err
+}
+
+// Record adds a payment to a bill and updates its paid amount and status.
+// The bill row is locked for the duration of the transaction so concurrent
+// payments cannot together exceed the outstanding balance.
+func (s *PaymentService) Record(companyID, billID, userID uuid.UUID, input RecordPaymentInput) (*models.Payment, error) {
+	var payment models.Payment
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		payment, err = s.record(tx, companyID, billID, &userID, input)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &payment, nil
+}
+
+func (s *PaymentService) record(tx *gorm.DB, companyID, billID uuid.UUID, userID *uuid.UUID, input RecordPaymentInput) (models.Payment, error) {
+	var bill models.Bill
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("company_id = ? AND id = ?", companyID, billID).
+		First(&bill).Error; err != nil {
+		return models.Payment{}, ErrBillNotFound
+	}
+
+	outstanding := bill.Outstanding()
+	if bill.Status == models.StatusPaid || !outstanding.IsPositive() {
+		return models.Payment{}, ErrBillAlreadyPaid
+	}
+	if bill.Status == models.StatusPendingApproval {
+		return models.Payment{}, ErrApprovalRequired
+	}
+	if !input.Amount.IsPositive() {
+		return models.Payment{}, ErrInvalidPaymentAmount
+	}
+	if input.Amount.GreaterThan(outstanding) {
+		return models.Payment{}, fmt.Errorf("%w (outstanding %s %s)", ErrOverpayment, outstanding.StringFixed(2), bill.Currency)
+	}
+
+	// Bills raised above a threshold after review still need sign-off
+	if approved, err := s.approvals.Satisfied(tx, &bill); err != nil {
+		return models.Payment{}, err
+	} else if !approved {
+		return models.Payment{}, ErrApprovalRequired
+	}
+
+	paidDate := time.Now()
+	if input.PaidDate != nil {
+		paidDate = *input.PaidDate
+	}
+
+	payment := models.Payment{
+		CompanyID: companyID,
+		BillID:    bill.ID,
+		UserID:    userID,
+		Amount:    input.Amount,
+		PaidDate:  paidDate,
+		Method:    input.Method,
+		Reference: input.Reference,
+		Notes:     input.Notes,
+	}
+
+	amountPaid := bill.AmountPaid.Add(input.Amount)
+	status := bill.Status
+	updates := map[string]interface{}{"amount_paid": amountPaid}
+
+	switch {
+	case amountPaid.GreaterThanOrEqual(bill.Amount):
+		status = models.StatusPaid
+		updates["paid_date"] = paidDate
+		if input.Method != nil {
+			updates["payment_method"] = *input.Method
+		}
+	case bill.Status != models.StatusOverdue:
+		// Overdue bills stay overdue until settled in full
+		status = models.StatusPartiallyPaid
+	}
+
+	if status != bill.Status {
+		if err := validateTransition(bill.Status, status); err != nil {
+			return models.Payment{}, err
+		}
+		updates["status"] = status
+	}
+
+	if err := tx.Create(&payment).Error; err != nil {
+		return models.Payment{}, err
+	}
+
+	// The status change is logged separately by the transition activity
+	previousStatus := bill.Status
+	changes := removeChange(diffBill(&bill, updates), "status")
+
+	if err := tx.Model(&bill).Updates(updates).Error; err != nil {
+		return models.Payment{}, err
+	}
+
+	details := fmt.Sprintf("Payment of %s %s recorded, outstanding %s %s",
+		input.Amount.StringFixed(2), bill.Currency, bill.Amount.Sub(amountPaid).StringFixed(2), bill.Currency)
+	if err := createChangeActivity(tx, bill.ID, userID, models.ActionPaymentRecorded, details, changes); err != nil {
+		return models.Payment{}, err
+	}
+	if err := enqueueBillEvent(tx, models.EventBillPaymentRecorded, bill.ID, BillEventData{Changes: changes, Payment: &payment}); err != nil {
+		return models.Payment{}, err
+	}
+
+	if status != previousStatus {
+		if err := recordTransition(tx, bill.ID, userID, previousStatus, status, ""); err != nil {
+			return models.Payment{}, err
+		}
+	}
+
+	// A fully paid recurring bill materializes its next occurrence
+	if status == models.StatusPaid {
+		bill.Status = status
+		if _, err := s.recurring.GenerateNext(tx, &bill); err != nil {
+			return models.Payment{}, err
+		}
+	}
+
+	return payment, nil
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+var errPrivateAddress = errors.New("URL resolves to a private or reserved address")
+
+// blockedNetworks are address ranges outbound requests to user-supplied
+// URLs must not reach: loopback, private, shared (CGNAT), link-local,
+// multicast and other reserved ranges
+var blockedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.0.2.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"198.51.100.0/24",
+	"203.0.113.0/24",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"64:ff9b::/96",
+	"100::/64",
+	"2001:db8::/32",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+)
+
+// denyPrivateAddress is a net.Dialer Control function refusing connections
+// to blocked networks. It sees the resolved address, so DNS names pointing
+// inside are caught too.
+func denyPrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return errPrivateAddress
+	}
+	for _, blocked := range blockedNetworks {
+		if blocked.Contains(ip) {
+			return errPrivateAddress
+		}
+	}
+	return nil
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// maxRateLimiterKeys bounds a limiter's memory; expired windows are dropped
+// when it is full, and everything if that is not enough
+const maxRateLimiterKeys = 10000
+
+// rateLimiter allows limit events per key in fixed windows. It keeps its
+// counts in process memory, so each replica enforces the limit on its own.
+type rateLimiter struct {
+	mu      sync.Mutex
+	limit   int64
+	window  time.Duration
+	entries map[string]rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int64
+}
+
+func newRateLimiter(limit int64, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window, entries: make(map[string]rateWindow)}
+}
+
+// allow records an event for key and reports whether it is within the limit
+func (l *rateLimiter) allow(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.entries[key]
+	if !ok || now.Sub(entry.start) >= l.window {
+		if !ok && len(l.entries) >= maxRateLimiterKeys {
+			l.prune(now)
+		}
+		entry = rateWindow{start: now}
+	}
+	if entry.count >= l.limit {
+		return false
+	}
+	entry.count++
+	l.entries[key] = entry
+	return true
+}
+
+func (l *rateLimiter) prune(now time.Time) {
+	for key, entry := range l.entries {
+		if now.Sub(entry.start) >= l.window {
+			delete(l.entries, key)
+		}
+	}
+	if len(l.entries) >= maxRateLimiterKeys {
+		l.entries = make(map[string]rateWindow)
+	}
+}
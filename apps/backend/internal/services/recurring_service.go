@@ -0,0 +1,248 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/dhani/bill-tracker-backend/internal/models"
+)
+
+// ErrInvalidRecurrence is returned when a bill's recurrence settings are inconsistent
+var ErrInvalidRecurrence = errors.New("invalid recurrence settings")
+
+// maxCatchUpOccurrences bounds how many missed occurrences a single run materializes per series
+const maxCatchUpOccurrences = 24
+
+type RecurringService struct {
+	db        *gorm.DB
+	approvals *ApprovalService
+}
+
+func NewRecurringService(db *gorm.DB) *RecurringService {
+	return &RecurringService{db: db, approvals: NewApprovalService(db)}
+}
+
+// ValidateRecurrence checks that frequency and day are set consistently.
+// For weekly bills RecurringDay is a weekday (0 = Sunday ... 6 = Saturday),
+// for monthly and yearly bills it is a day of the month (1-31).
+func ValidateRecurrence(isRecurring bool, frequency *models.RecurringFrequency, day *int) error {
+	if !isRecurring {
+		return nil
+	}
+	if frequency == nil {
+		return fmt.Errorf("%w: recurring_frequency is required for recurring bills", ErrInvalidRecurrence)
+	}
+
+	switch *frequency {
+	case models.FrequencyWeekly:
+		if day != nil && (*day < 0 || *day > 6) {
+			return fmt.Errorf("%w: recurring_day must be a weekday between 0 (Sunday) and 6 (Saturday)", ErrInvalidRecurrence)
+		}
+	case models.FrequencyMonthly, models.FrequencyYearly:
+		if day != nil && (*day < 1 || *day > 31) {
+			return fmt.Errorf("%w: recurring_day must be between 1 and 31", ErrInvalidRecurrence)
+		}
+	default:
+		return fmt.Errorf("%w: unknown recurring_frequency %q", ErrInvalidRecurrence, *frequency)
+	}
+	return nil
+}
+
+// NextDueDate computes the due date following `from` for the given recurrence.
+// anchorDay is the preferred day of the month for monthly/yearly bills; months
+// shorter than anchorDay are clamped to their last day, so a bill anchored on the
+// 31st falls on Feb 28 (or 29 in leap years) and returns to the 31st in March.
+func NextDueDate(from time.Time, frequency models.RecurringFrequency, recurringDay *int, anchorDay int) (time.Time, error) {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch frequency {
+	case models.FrequencyWeekly:
+		if recurringDay == nil {
+			return from.AddDate(0, 0, 7), nil
+		}
+		delta := (*recurringDay - int(from.Weekday()) + 7) % 7
+		if delta == 0 {
+			delta = 7
+		}
+		return from.AddDate(0, 0, delta), nil
+
+	case models.FrequencyMonthly:
+		day := anchorDay
+		if recurringDay != nil {
+			day = *recurringDay
+		}
+		year, month := from.Year(), from.Month()+1
+		if month > time.December {
+			year, month = year+1, time.January
+		}
+		return clampedDate(year, month, day), nil
+
+	case models.FrequencyYearly:
+		day := anchorDay
+		if recurringDay != nil {
+			day = *recurringDay
+		}
+		return clampedDate(from.Year()+1, from.Month(), day), nil
+	}
+
+	return time.Time{}, fmt.Errorf("%w: unknown recurring_frequency %q", ErrInvalidRecurrence, frequency)
+}
+
+// clampedDate builds a date, using the last day of the month when day overflows it
+func clampedDate(year int, month time.Month, day int) time.Time {
+	lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	if day < 1 {
+		day = 1
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// GenerateNext materializes the occurrence that follows bill in its series.
+// It is idempotent: the (parent_bill_id, due_date) unique index makes a second
+// attempt for the same occurrence a no-op, so it is safe to call from several
+// replicas or after a restart. Returns nil when nothing was created.
+func (s *RecurringService) GenerateNext(tx *gorm.DB, bill *models.Bill) (*models.Bill, error) {
+	if !bill.IsRecurring || bill.RecurringFrequency == nil {
+		return nil, nil
+	}
+
+	anchorDay, err := s.anchorDay(tx, bill)
+	if err != nil {
+		return nil, err
+	}
+
+	dueDate, err := NextDueDate(bill.DueDate, *bill.RecurringFrequency, bill.RecurringDay, anchorDay)
+	if err != nil {
+		return nil, err
+	}
+
+	seriesID := bill.SeriesID()
+	next := models.Bill{
+		CompanyID:          bill.CompanyID,
+		UserID:             bill.UserID,
+		ParentBillID:       &seriesID,
+		VendorID:           bill.VendorID,
+		CategoryID:         bill.CategoryID,
+		Title:              bill.Title,
+		Amount:             bill.Amount,
+		Currency:           bill.Currency,
+		DueDate:            dueDate,
+		Status:             models.StatusUnpaid,
+		IsRecurring:        true,
+		RecurringFrequency: bill.RecurringFrequency,
+		RecurringDay:       bill.RecurringDay,
+		PaymentMethod:      bill.PaymentMethod,
+		Notes:              bill.Notes,
+	}
+
+	// Occurrences go through the same approval policy as bills created by hand
+	details := fmt.Sprintf("Recurring bill generated from series %s", seriesID)
+	required, err := s.approvals.RequiresApproval(tx, &next)
+	if err != nil {
+		return nil, err
+	}
+	if required {
+		next.Status = models.StatusPendingApproval
+		next.ApprovalRound = 1
+		details += " and submitted for approval"
+	}
+
+	// Only an occurrence already generated for the date is skipped; any other
+	// conflict, e.g. on the invoice number, is an error
+	result := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "parent_bill_id"}, {Name: "due_date"}},
+		DoNothing: true,
+	}).Create(&next)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	var items []models.BillLineItem
+	if err := tx.Where("bill_id = ?", bill.ID).Order("position ASC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	if err := replaceLineItems(tx, next.ID, items); err != nil {
+		return nil, err
+	}
+
+	if err := createActivity(tx, next.ID, nil, models.ActionCreated, details); err != nil {
+		return nil, err
+	}
+	if err := enqueueBillEvent(tx, models.EventBillCreated, next.ID, BillEventData{}); err != nil {
+		return nil, err
+	}
+
+	return &next, nil
+}
+
+// GenerateDue materializes the next occurrence for every recurring series whose
+// latest bill is paid or has reached its due date. Missed occurrences (e.g. after
+// downtime) are caught up, bounded by maxCatchUpOccurrences per run.
+func (s *RecurringService) GenerateDue(now time.Time) (int, error) {
+	created := 0
+
+	for i := 0; i < maxCatchUpOccurrences; i++ {
+		var bills []models.Bill
+		err := s.db.
+			Where("is_recurring = ? AND recurring_frequency IS NOT NULL", true).
+			Where("status = ? OR due_date <= ?", models.StatusPaid, now).
+			Where(`NOT EXISTS (
+				SELECT 1 FROM bills later
+				WHERE later.parent_bill_id = COALESCE(bills.parent_bill_id, bills.id)
+				AND later.due_date > bills.due_date
+			)`).
+			Find(&bills).Error
+		if err != nil {
+			return created, err
+		}
+
+		createdThisPass := 0
+		for j := range bills {
+			err := s.db.Transaction(func(tx *gorm.DB) error {
+				next, err := s.GenerateNext(tx, &bills[j])
+				if next != nil {
+					createdThisPass++
+				}
+				return err
+			})
+			if err != nil {
+				return created, err
+			}
+		}
+
+		created += createdThisPass
+		if createdThisPass == 0 {
+			break
+		}
+	}
+
+	return created, nil
+}
+
+// anchorDay returns the day of month the series was started on, so that
+// clamped months (e.g. Feb 28) do not permanently shift later occurrences
+func (s *RecurringService) anchorDay(tx *gorm.DB, bill *models.Bill) (int, error) {
+	if bill.ParentBillID == nil {
+		return bill.DueDate.Day(), nil
+	}
+
+	var root models.Bill
+	err := tx.Unscoped().Select("due_date").Where("id = ?", *bill.ParentBillID).First(&root).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return bill.DueDate.Day(), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return root.DueDate.Day(), nil
+}
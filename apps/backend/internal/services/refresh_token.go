@@ -0,0 +1,49 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// A refresh token is "<session id>.<secret>". Only the SHA-256 of the secret
+// is stored, so a database leak yields no usable tokens, while the session ID
+// lets a presented token be matched against its family even after rotation.
+
+// newRefreshToken returns a fresh refresh token for a session and its hash
+func newRefreshToken(sessionID uuid.UUID) (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(b)
+	return sessionID.String() + "." + secret, hashRefreshSecret(secret), nil
+}
+
+// parseRefreshToken splits a refresh token into its session ID and secret hash
+func parseRefreshToken(token string) (uuid.UUID, string, error) {
+	id, secret, ok := strings.Cut(token, ".")
+	if !ok || secret == "" {
+		return uuid.Nil, "", ErrInvalidRefreshToken
+	}
+	sessionID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, "", ErrInvalidRefreshToken
+	}
+	return sessionID, hashRefreshSecret(secret), nil
+}
+
+func hashRefreshSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// refreshHashMatches compares a presented hash with a stored one in constant time
+func refreshHashMatches(stored *string, presented string) bool {
+	return stored != nil && subtle.ConstantTimeCompare([]byte(*stored), []byte(presented)) == 1
+}
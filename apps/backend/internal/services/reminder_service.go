@@ -0,0 +1,351 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/dhani/bill-tracker-backend/internal/mailer"
+	"github.com/dhani/bill-tracker-backend/internal/models"
+)
+
+var (
+	ErrInvalidReminderRule  = errors.New("invalid reminder rule")
+	ErrReminderRuleNotFound = errors.New("reminder rule not found")
+)
+
+// maxReminderDays bounds how far from the due date a rule may fire
+const maxReminderDays = 365
+
+// maxReminderAttempts is how often a reminder that reached no one is tried
+const maxReminderAttempts = 5
+
+type ReminderService struct {
+	db          *gorm.DB
+	mailer      mailer.Mailer
+	frontendURL string
+}
+
+func NewReminderService(db *gorm.DB, mailer mailer.Mailer, frontendURL string) *ReminderService {
+	return &ReminderService{db: db, mailer: mailer, frontendURL: frontendURL}
+}
+
+// ReminderRuleInput holds data for creating or updating a reminder rule.
+// Templates use Go template syntax with ReminderData; leave them empty to
+// use the built-in wording.
+type ReminderRuleInput struct {
+	Name            string                 `json:"name" binding:"required"`
+	Trigger         models.ReminderTrigger `json:"trigger" binding:"required"`
+	Days            int                    `json:"days"`
+	NotifyAdmins    bool                   `json:"notify_admins"`
+	Enabled         *bool                  `json:"enabled"`
+	SubjectTemplate *string                `json:"subject_template"`
+	TextTemplate    *string                `json:"text_template"`
+	HTMLTemplate    *string                `json:"html_template"`
+}
+
+// ListRules retrieves a company's reminder rules
+func (s *ReminderService) ListRules(companyID uuid.UUID) ([]models.ReminderRule, error) {
+	var rules []models.ReminderRule
+	err := s.db.Where("company_id = ?", companyID).Order("created_at ASC").Find(&rules).Error
+	return rules, err
+}
+
+// CreateRule adds a reminder rule
+func (s *ReminderService) CreateRule(companyID uuid.UUID, input ReminderRuleInput) (*models.ReminderRule, error) {
+	rule := models.ReminderRule{CompanyID: companyID, Enabled: true}
+	applyReminderRuleInput(&rule, input)
+	if err := validateReminderRule(&rule); err != nil {
+		return nil, err
+	}
+
+	// Select every column so a disabled rule is not saved with the column default
+	if err := s.db.Select("*").Create(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// UpdateRule replaces a reminder rule's settings. Bills already reminded
+// under the rule are not reminded again.
+func (s *ReminderService) UpdateRule(companyID, ruleID uuid.UUID, input ReminderRuleInput) (*models.ReminderRule, error) {
+	var rule models.ReminderRule
+	if err := s.db.Where("company_id = ? AND id = ?", companyID, ruleID).First(&rule).Error; err != nil {
+		return nil, ErrReminderRuleNotFound
+	}
+
+	applyReminderRuleInput(&rule, input)
+	if err := validateReminderRule(&rule); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(&rule).Updates(map[string]interface{}{
+		"name":             rule.Name,
+		"trigger":          rule.Trigger,
+		"days":             rule.Days,
+		"notify_admins":    rule.NotifyAdmins,
+		"enabled":          rule.Enabled,
+		"subject_template": rule.SubjectTemplate,
+		"text_template":    rule.TextTemplate,
+		"html_template":    rule.HTMLTemplate,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// DeleteRule removes a reminder rule; its delivery log is removed with it
+func (s *ReminderService) DeleteRule(companyID, ruleID uuid.UUID) error {
+	result := s.db.Where("company_id = ? AND id = ?", companyID, ruleID).Delete(&models.ReminderRule{})
+	if result.RowsAffected == 0 {
+		return ErrReminderRuleNotFound
+	}
+	return result.Error
+}
+
+func applyReminderRuleInput(rule *models.ReminderRule, input ReminderRuleInput) {
+	rule.Name = strings.TrimSpace(input.Name)
+	rule.Trigger = input.Trigger
+	rule.Days = input.Days
+	rule.NotifyAdmins = input.NotifyAdmins
+	if input.Enabled != nil {
+		rule.Enabled = *input.Enabled
+	}
+	rule.SubjectTemplate = input.SubjectTemplate
+	rule.TextTemplate = input.TextTemplate
+	rule.HTMLTemplate = input.HTMLTemplate
+}
+
+// validateReminderRule checks the settings and renders the templates against
+// a sample bill, so mistakes surface when saving rather than when sending
+func validateReminderRule(rule *models.ReminderRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidReminderRule)
+	}
+	if rule.Trigger != models.TriggerBeforeDue && rule.Trigger != models.TriggerOverdue {
+		return fmt.Errorf("%w: unknown trigger %q", ErrInvalidReminderRule, rule.Trigger)
+	}
+	if rule.Days < 0 || rule.Days > maxReminderDays {
+		return fmt.Errorf("%w: days must be between 0 and %d", ErrInvalidReminderRule, maxReminderDays)
+	}
+
+	templates, err := parseReminderTemplates(rule)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidReminderRule, err)
+	}
+	if _, err := templates.render(sampleReminderData(rule)); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidReminderRule, err)
+	}
+	return nil
+}
+
+// SendDue sends every reminder that has become due, returning how many
+// bills were reminded about. A failing rule or bill does not hold up the
+// others; their errors are returned together.
+func (s *ReminderService) SendDue(ctx context.Context, now time.Time) (int, error) {
+	var rules []models.ReminderRule
+	if err := s.db.WithContext(ctx).Preload("Company").Where("enabled = ?", true).Order("created_at ASC, id ASC").Find(&rules).Error; err != nil {
+		return 0, err
+	}
+
+	total := 0
+	var errs []error
+	for i := range rules {
+		count, err := s.sendRule(ctx, &rules[i], now)
+		total += count
+		if err != nil {
+			errs = append(errs, fmt.Errorf("reminder rule %s: %w", rules[i].ID, err))
+		}
+	}
+	return total, errors.Join(errs...)
+}
+
+func (s *ReminderService) sendRule(ctx context.Context, rule *models.ReminderRule, now time.Time) (int, error) {
+	templates, err := parseReminderTemplates(rule)
+	if err != nil {
+		return 0, err
+	}
+
+	bills, today, err := s.dueBills(ctx, rule, now)
+	if err != nil || len(bills) == 0 {
+		return 0, err
+	}
+
+	count := 0
+	var errs []error
+	for i := range bills {
+		sent, err := s.remind(ctx, rule, templates, &bills[i], now, today)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("bill %s: %w", bills[i].ID, err))
+			continue
+		}
+		if sent {
+			count++
+		}
+	}
+	return count, errors.Join(errs...)
+}
+
+// dueBills finds the bills a rule should remind about today, in the
+// company's time zone. Reminders whose day came before the rule existed are
+// skipped so that creating a rule does not mail the whole backlog.
+func (s *ReminderService) dueBills(ctx context.Context, rule *models.ReminderRule, now time.Time) ([]models.Bill, time.Time, error) {
+	loc := rule.Company.Location()
+	local := now.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+	created := rule.CreatedAt.In(loc)
+	createdDay := time.Date(created.Year(), created.Month(), created.Day(), 0, 0, 0, 0, time.UTC)
+
+	query := s.db.WithContext(ctx).
+		Preload("Vendor").
+		Where("company_id = ?", rule.CompanyID).
+		Where("NOT EXISTS (SELECT 1 FROM reminder_deliveries d WHERE d.rule_id = ? AND d.bill_id = bills.id AND (d.retry_at IS NULL OR d.retry_at > ?))", rule.ID, now)
+
+	const day = "2006-01-02"
+	switch rule.Trigger {
+	case models.TriggerBeforeDue:
+		// Fires from Days before the due date until the due date itself
+		query = query.
+			Where("status IN ?", []models.BillStatus{models.StatusUnpaid, models.StatusPartiallyPaid}).
+			Where("due_date >= ? AND due_date <= ?", today.Format(day), today.AddDate(0, 0, rule.Days).Format(day)).
+			Where("due_date >= ?", createdDay.AddDate(0, 0, rule.Days).Format(day))
+	case models.TriggerOverdue:
+		// Fires once the bill is Days overdue; a bill is overdue the day after its due date
+		days := max(rule.Days, 1)
+		query = query.
+			Where("status IN ?", []models.BillStatus{models.StatusUnpaid, models.StatusPartiallyPaid, models.StatusOverdue}).
+			Where("due_date <= ?", today.AddDate(0, 0, -days).Format(day)).
+			Where("due_date >= ?", createdDay.AddDate(0, 0, -days).Format(day))
+	default:
+		return nil, today, nil
+	}
+
+	var bills []models.Bill
+	err := query.Order("due_date ASC").Find(&bills).Error
+	return bills, today, err
+}
+
+// remind emails the bill's owner, and the admins when the rule asks for it.
+// The delivery is claimed and committed before sending, so no database
+// connection is held while mail goes out: a concurrent sweep sees the claim
+// and skips the bill. When every send fails the delivery is retried by the
+// next sweeps, up to maxReminderAttempts attempts in all.
+func (s *ReminderService) remind(ctx context.Context, rule *models.ReminderRule, templates *reminderTemplates, bill *models.Bill, now, today time.Time) (bool, error) {
+	delivery := models.ReminderDelivery{RuleID: rule.ID, BillID: bill.ID, SentAt: now, Attempts: 1}
+	var recipients []models.User
+	claimed := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// A failed delivery that is due for a retry is claimed again
+		result := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "rule_id"}, {Name: "bill_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"attempts": gorm.Expr("reminder_deliveries.attempts + 1"),
+				"sent_at":  now,
+				"retry_at": nil,
+			}),
+			Where: clause.Where{Exprs: []clause.Expression{
+				clause.Expr{SQL: "reminder_deliveries.retry_at IS NOT NULL AND reminder_deliveries.retry_at <= ?", Vars: []interface{}{now}},
+			}},
+		}).Create(&delivery)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		claimed = true
+
+		// The upsert leaves the struct as inserted; read the stored row
+		if err := tx.Where("rule_id = ? AND bill_id = ?", rule.ID, bill.ID).First(&delivery).Error; err != nil {
+			return err
+		}
+		var err error
+		recipients, err = s.recipients(tx, rule, bill)
+		return err
+	})
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	var delivered []string
+	var sendErr error
+	for i := range recipients {
+		data := newReminderData(rule, &rule.Company, bill, &recipients[i], today, s.frontendURL)
+		msg, err := templates.render(data)
+		if err != nil {
+			log.Printf("Failed to render reminder for bill %s: %v", bill.ID, err)
+			sendErr = err
+			break
+		}
+		msg.To = []*mail.Address{{Name: recipients[i].Name, Address: recipients[i].Email}}
+
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			log.Printf("Failed to send reminder for bill %s to %s: %v", bill.ID, recipients[i].Email, err)
+			sendErr = err
+			continue
+		}
+		delivered = append(delivered, recipients[i].Email)
+	}
+	if len(delivered) == 0 && sendErr != nil {
+		return false, s.recordFailure(ctx, rule, bill, &delivery, sendErr)
+	}
+
+	details := fmt.Sprintf("Reminder %q sent to %s", rule.Name, strings.Join(delivered, ", "))
+	if len(delivered) == 0 {
+		details = fmt.Sprintf("Reminder %q had no one to notify", rule.Name)
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&delivery).Update("recipients", strings.Join(delivered, ", ")).Error; err != nil {
+			return err
+		}
+		return createActivity(tx, bill.ID, nil, models.ActionPaymentReminderSent, details)
+	})
+	return len(delivered) > 0, err
+}
+
+// recordFailure notes a delivery that reached no one. It is retried by the
+// next sweep until it has had maxReminderAttempts attempts; then the claim is
+// kept so that one unreachable address cannot hold up every sweep.
+func (s *ReminderService) recordFailure(ctx context.Context, rule *models.ReminderRule, bill *models.Bill, delivery *models.ReminderDelivery, sendErr error) error {
+	message := sendErr.Error()
+	updates := map[string]interface{}{"error": message}
+	giveUp := delivery.Attempts >= maxReminderAttempts
+	if !giveUp {
+		updates["retry_at"] = time.Now()
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(delivery).Updates(updates).Error; err != nil {
+			return err
+		}
+		if !giveUp {
+			return nil
+		}
+		details := fmt.Sprintf("Reminder %q could not be sent after %d attempts: %s", rule.Name, delivery.Attempts, message)
+		return createActivity(tx, bill.ID, nil, models.ActionPaymentReminderSent, details)
+	})
+	if err != nil {
+		return errors.Join(sendErr, err)
+	}
+	return sendErr
+}
+
+// recipients returns the users to remind: the bill's owner and, for rules
+// that ask for it, the company's admins
+func (s *ReminderService) recipients(tx *gorm.DB, rule *models.ReminderRule, bill *models.Bill) ([]models.User, error) {
+	query := tx.Where("company_id = ?", rule.CompanyID)
+	if rule.NotifyAdmins {
+		query = query.Where("(id = ? OR role = ?)", bill.UserID, models.RoleAdmin)
+	} else {
+		query = query.Where("id = ?", bill.UserID)
+	}
+
+	var users []models.User
+	err := query.Order("created_at ASC").Find(&users).Error
+	return users, err
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/dhani/bill-tracker-backend/internal/mailer"
+	"github.com/dhani/bill-tracker-backend/internal/models"
+)
+
+// Built-in reminder templates, used for any template a rule leaves empty
+const (
+	defaultReminderSubject = `{{if .IsOverdue}}Overdue: {{else}}Due {{if eq .DaysUntilDue 0}}today{{else}}in {{.DaysUntilDue}} day{{if ne .DaysUntilDue 1}}s{{end}}{{end}}: {{end}}{{.Title}}{{if .Vendor}} ({{.Vendor}}){{end}}`
+
+	defaultReminderText = `Hi {{.RecipientName}},
+
+{{if .IsOverdue -}}
+The bill "{{.Title}}" was due on {{.DueDate}} and is {{.DaysOverdue}} day{{if ne .DaysOverdue 1}}s{{end}} overdue.
+{{- else -}}
+The bill "{{.Title}}" is due on {{.DueDate}}.
+{{- end}}
+
+{{if .Vendor}}Vendor:      {{.Vendor}}
+{{end}}{{if .InvoiceNumber}}Invoice:     {{.InvoiceNumber}}
+{{end}}Outstanding: {{.Outstanding}} {{.Currency}}
+
+{{.URL}}
+
+This reminder was sent by {{.CompanyName}}'s bill tracker ({{.RuleName}}).
+`
+
+	defaultReminderHTML = `<p>Hi {{.RecipientName}},</p>
+{{if .IsOverdue}}<p>The bill <strong>{{.Title}}</strong> was due on {{.DueDate}} and is <strong>{{.DaysOverdue}} day{{if ne .DaysOverdue 1}}s{{end}} overdue</strong>.</p>
+{{else}}<p>The bill <strong>{{.Title}}</strong> is due on {{.DueDate}}.</p>
+{{end}}<table>
+{{if .Vendor}}<tr><td>Vendor</td><td>{{.Vendor}}</td></tr>
+{{end}}{{if .InvoiceNumber}}<tr><td>Invoice</td><td>{{.InvoiceNumber}}</td></tr>
+{{end}}<tr><td>Outstanding</td><td>{{.Outstanding}} {{.Currency}}</td></tr>
+</table>
+<p><a href="{{.URL}}">View the bill</a></p>
+<p style="color:#888">This reminder was sent by {{.CompanyName}}'s bill tracker ({{.RuleName}}).</p>
+`
+)
+
+// ReminderData is the data reminder templates are executed with
+type ReminderData struct {
+	RuleName      string
+	CompanyName   string
+	RecipientName string
+	Title         string
+	Vendor        string
+	InvoiceNumber string
+	Amount        string
+	Outstanding   string
+	Currency      string
+	DueDate       string
+	DaysUntilDue  int
+	DaysOverdue   int
+	IsOverdue     bool
+	URL           string
+	Bill          *models.Bill
+}
+
+// reminderTemplates holds a rule's parsed templates
+type reminderTemplates struct {
+	subject *template.Template
+	text    *template.Template
+	html    *htmltemplate.Template
+}
+
+func templateOrDefault(custom *string, fallback string) string {
+	if custom == nil || strings.TrimSpace(*custom) == "" {
+		return fallback
+	}
+	return *custom
+}
+
+// parseReminderTemplates parses a rule's templates, substituting the
+// built-in ones for those left empty. Templates missing a key fail rather
+// than render "<no value>".
+func parseReminderTemplates(rule *models.ReminderRule) (*reminderTemplates, error) {
+	subject, err := template.New("subject").Option("missingkey=error").
+		Parse(templateOrDefault(rule.SubjectTemplate, defaultReminderSubject))
+	if err != nil {
+		return nil, err
+	}
+	text, err := template.New("text").Option("missingkey=error").
+		Parse(templateOrDefault(rule.TextTemplate, defaultReminderText))
+	if err != nil {
+		return nil, err
+	}
+	html, err := htmltemplate.New("html").Option("missingkey=error").
+		Parse(templateOrDefault(rule.HTMLTemplate, defaultReminderHTML))
+	if err != nil {
+		return nil, err
+	}
+	return &reminderTemplates{subject: subject, text: text, html: html}, nil
+}
+
+// render executes the templates into a message
+func (t *reminderTemplates) render(data *ReminderData) (*mailer.Message, error) {
+	var subject, text, html bytes.Buffer
+	if err := t.subject.Execute(&subject, data); err != nil {
+		return nil, err
+	}
+	if err := t.text.Execute(&text, data); err != nil {
+		return nil, err
+	}
+	if err := t.html.Execute(&html, data); err != nil {
+		return nil, err
+	}
+
+	// Header values cannot span lines
+	return &mailer.Message{
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// newReminderData describes a bill as seen on the company's local date today
+func newReminderData(rule *models.ReminderRule, company *models.Company, bill *models.Bill, recipient *models.User, today time.Time, frontendURL string) *ReminderData {
+	due := time.Date(bill.DueDate.Year(), bill.DueDate.Month(), bill.DueDate.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(due.Sub(today).Hours() / 24)
+
+	data := &ReminderData{
+		RuleName:      rule.Name,
+		CompanyName:   company.Name,
+		RecipientName: recipient.Name,
+		Title:         bill.Title,
+		Amount:        bill.Amount.StringFixed(2),
+		Outstanding:   bill.Outstanding().StringFixed(2),
+		Currency:      bill.Currency,
+		DueDate:       due.Format("January 2, 2006"),
+		URL:           strings.TrimRight(frontendURL, "/") + "/bills/" + bill.ID.String(),
+		Bill:          bill,
+	}
+	if days < 0 {
+		data.IsOverdue = true
+		data.DaysOverdue = -days
+	} else {
+		data.DaysUntilDue = days
+	}
+	if bill.Vendor != nil {
+		data.Vendor = bill.Vendor.Name
+	}
+	if bill.InvoiceNumber != nil {
+		data.InvoiceNumber = *bill.InvoiceNumber
+	}
+	return data
+}
+
+// sampleReminderData is used to check templates when a rule is saved
+func sampleReminderData(rule *models.ReminderRule) *ReminderData {
+	invoice := "INV-1001"
+	bill := &models.Bill{
+		ID:            uuid.New(),
+		Title:         "Office rent",
+		InvoiceNumber: &invoice,
+		Amount:        decimal.NewFromInt(1200),
+		Currency:      "USD",
+		DueDate:       time.Now().UTC().AddDate(0, 0, rule.Days),
+		Status:        models.StatusUnpaid,
+		Vendor:        &models.Vendor{Name: "Acme Properties"},
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	if rule.Trigger == models.TriggerOverdue {
+		bill.DueDate = today.AddDate(0, 0, -max(rule.Days, 1))
+		bill.Status = models.StatusOverdue
+	}
+	return newReminderData(rule, &models.Company{Name: "Example Inc."}, bill, &models.User{Name: "Alex"}, today, "https://example.com")
+}
@@ -0,0 +1,573 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/dhani/bill-tracker-backend/internal/config"
+	"github.com/dhani/bill-tracker-backend/internal/middleware"
+	"github.com/dhani/bill-tracker-backend/internal/models"
+	"github.com/dhani/bill-tracker-backend/internal/oidc"
+)
+
+var (
+	ErrSSONotConfigured    = errors.New("single sign-on is not set up for this email domain")
+	ErrSSOConfigNotFound   = errors.New("single sign-on is not configured")
+	ErrInvalidSSOConfig    = errors.New("invalid single sign-on configuration")
+	ErrSSORequired         = errors.New("your company requires signing in with single sign-on")
+	ErrSSODomainNotAllowed = errors.New("your email domain is not allowed to sign in to this company")
+	ErrSSOAccountConflict  = errors.New("this email address already belongs to another company")
+	ErrSSODomainUnverified = errors.New("domain ownership could not be verified")
+)
+
+// domainPattern matches a plain DNS name such as example.com
+var domainPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,}$`)
+
+// ssoDiscoveryTimeout bounds the issuer check made when settings are saved
+const ssoDiscoveryTimeout = 10 * time.Second
+
+// ssoVerificationPrefix names the DNS TXT record proving domain ownership:
+// _billtracker-verification.<domain> must contain the config's token
+const ssoVerificationPrefix = "_billtracker-verification."
+
+// publicMailDomains are shared mailbox providers no company can claim
+var publicMailDomains = map[string]bool{
+	"gmail.com": true, "googlemail.com": true, "outlook.com": true, "hotmail.com": true,
+	"live.com": true, "msn.com": true, "yahoo.com": true, "ymail.com": true,
+	"icloud.com": true, "me.com": true, "mac.com": true, "aol.com": true,
+	"proton.me": true, "protonmail.com": true, "gmx.com": true, "gmx.de": true,
+	"gmx.net": true, "web.de": true, "mail.com": true, "yandex.com": true,
+	"yandex.ru": true, "zoho.com": true, "fastmail.com": true, "hey.com": true,
+	"qq.com": true, "163.com": true, "126.com": true, "mail.ru": true,
+}
+
+type SSOService struct {
+	db          *gorm.DB
+	auth        *AuthService
+	redirectURL string
+	// lookupTXT resolves DNS TXT records for domain verification
+	lookupTXT func(ctx context.Context, name string) ([]string, error)
+	// client talks to company identity providers
+	client *http.Client
+
+	// Providers keep discovery and signing keys cached between sign-ins
+	mu        sync.Mutex
+	providers map[uuid.UUID]cachedProvider
+}
+
+type cachedProvider struct {
+	provider  *oidc.Provider
+	updatedAt time.Time
+}
+
+func NewSSOService(db *gorm.DB, auth *AuthService, redirectURL string) *SSOService {
+	// A local Keycloak or mock provider is allowed outside production
+	dialer := &net.Dialer{Timeout: ssoDiscoveryTimeout}
+	if config.AppConfig.Env == "production" {
+		dialer.Control = denyPrivateAddress
+	}
+
+	return &SSOService{
+		db:          db,
+		client:      &http.Client{Transport: &http.Transport{DialContext: dialer.DialContext}, Timeout: ssoDiscoveryTimeout},
+		auth:        auth,
+		redirectURL: redirectURL,
+		lookupTXT:   net.DefaultResolver.LookupTXT,
+		providers:   make(map[uuid.UUID]cachedProvider),
+	}
+}
+
+// SSOConfigInput holds a company's single sign-on settings
+type SSOConfigInput struct {
+	Issuer   string `json:"issuer" binding:"required"`
+	ClientID string `json:"client_id" binding:"required"`
+	// ClientSecret keeps the stored secret when omitted
+	ClientSecret   *string         `json:"client_secret"`
+	AllowedDomains []string        `json:"allowed_domains" binding:"required"`
+	DefaultRole    models.UserRole `json:"default_role"`
+	Enabled        *bool           `json:"enabled"`
+	Enforced       bool            `json:"enforced"`
+}
+
+// ssoProvider is the UserIdentity provider name for a company's identity provider
+func ssoProvider(companyID uuid.UUID) string {
+	return "sso:" + companyID.String()
+}
+
+// Get returns the company's single sign-on settings
+func (s *SSOService) Get(companyID uuid.UUID) (*models.SSOConfig, error) {
+	var cfg models.SSOConfig
+	if err := s.db.Where("company_id = ?", companyID).First(&cfg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSSOConfigNotFound
+		}
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Save creates or replaces the company's single sign-on settings. The issuer
+// must publish a discovery document, and enforcing SSO requires the admin to
+// have signed in with it once, so a broken setup cannot lock everyone out.
+// New domains stay unverified until VerifyDomains finds their TXT record.
+func (s *SSOService) Save(ctx context.Context, companyID, userID uuid.UUID, input SSOConfigInput) (*models.SSOConfig, error) {
+	issuer, err := normalizeIssuer(input.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	domains, err := normalizeDomains(input.AllowedDomains)
+	if err != nil {
+		return nil, err
+	}
+	if input.DefaultRole == "" {
+		input.DefaultRole = models.RoleMember
+	}
+	if input.DefaultRole != models.RoleAdmin && input.DefaultRole != models.RoleMember {
+		return nil, fmt.Errorf("%w: default_role must be admin or member", ErrInvalidSSOConfig)
+	}
+
+	existing, err := s.Get(companyID)
+	if err != nil && !errors.Is(err, ErrSSOConfigNotFound) {
+		return nil, err
+	}
+
+	cfg := models.SSOConfig{CompanyID: companyID, Enabled: true}
+	if existing != nil {
+		cfg = *existing
+	}
+	issuerChanged := cfg.Issuer != issuer
+	cfg.Issuer = issuer
+	cfg.ClientID = strings.TrimSpace(input.ClientID)
+	if input.ClientSecret != nil {
+		cfg.ClientSecret = *input.ClientSecret
+	}
+	cfg.AllowedDomains = domains
+	verified := models.DomainList{}
+	for _, domain := range cfg.VerifiedDomains {
+		if domains.Contains(domain) {
+			verified = append(verified, domain)
+		}
+	}
+	cfg.VerifiedDomains = verified
+	if cfg.VerificationToken == "" {
+		if cfg.VerificationToken, err = newDomainVerificationToken(); err != nil {
+			return nil, err
+		}
+	}
+	cfg.DefaultRole = input.DefaultRole
+	if input.Enabled != nil {
+		cfg.Enabled = *input.Enabled
+	}
+	enforcing := input.Enforced && !cfg.Enforced
+	cfg.Enforced = input.Enforced
+	if cfg.ClientSecret == "" {
+		return nil, fmt.Errorf("%w: client_secret is required", ErrInvalidSSOConfig)
+	}
+
+	if err := s.checkDomainsAvailable(companyID, domains); err != nil {
+		return nil, err
+	}
+
+	provider, err := s.newProvider(&cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSSOConfig, err)
+	}
+	discoverCtx, cancel := context.WithTimeout(ctx, ssoDiscoveryTimeout)
+	defer cancel()
+	if err := provider.Discover(discoverCtx); err != nil {
+		return nil, fmt.Errorf("%w: issuer discovery failed: %v", ErrInvalidSSOConfig, err)
+	}
+
+	if cfg.Enforced {
+		// Links to a previous issuer are dropped below and do not count
+		var linked int64
+		if !issuerChanged {
+			if err := s.db.Model(&models.UserIdentity{}).
+				Where("user_id = ? AND provider = ?", userID, ssoProvider(companyID)).
+				Count(&linked).Error; err != nil {
+				return nil, err
+			}
+		}
+		if linked == 0 {
+			return nil, fmt.Errorf("%w: sign in with single sign-on once before enforcing it", ErrInvalidSSOConfig)
+		}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Subjects are only unique per issuer, so links to another issuer are void
+		if issuerChanged {
+			if err := tx.Where("provider = ?", ssoProvider(companyID)).Delete(&models.UserIdentity{}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Save(&cfg).Error; err != nil {
+			return err
+		}
+		if enforcing {
+			return revokeNonSSOSessions(tx, companyID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if enforcing {
+		var userIDs []uuid.UUID
+		if err := s.db.Model(&models.User{}).Where("company_id = ?", companyID).Pluck("id", &userIDs).Error; err == nil {
+			for _, id := range userIDs {
+				middleware.ForgetUserSessions(id, uuid.Nil)
+			}
+		}
+	}
+
+	s.forgetProvider(cfg.ID)
+	return &cfg, nil
+}
+
+// VerifyDomains checks the TXT record of each unverified domain and marks
+// those publishing the company's token as verified. It returns
+// ErrSSODomainUnverified, along with the saved settings, when any remain
+// unverified.
+func (s *SSOService) VerifyDomains(ctx context.Context, companyID uuid.UUID) (*models.SSOConfig, error) {
+	cfg, err := s.Get(companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var failed []string
+	verified := append(models.DomainList{}, cfg.VerifiedDomains...)
+	for _, domain := range cfg.AllowedDomains {
+		if verified.Contains(domain) {
+			continue
+		}
+		ok, err := s.domainVerified(ctx, companyID, domain, cfg.VerificationToken)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			failed = append(failed, domain)
+			continue
+		}
+		verified = append(verified, domain)
+	}
+
+	if len(verified) != len(cfg.VerifiedDomains) {
+		if err := s.db.Model(cfg).Update("verified_domains", verified).Error; err != nil {
+			return nil, err
+		}
+		cfg.VerifiedDomains = verified
+	}
+	if len(failed) > 0 {
+		return cfg, fmt.Errorf("%w: no TXT record %s<domain> with the verification token was found for %s",
+			ErrSSODomainUnverified, ssoVerificationPrefix, strings.Join(failed, ", "))
+	}
+	return cfg, nil
+}
+
+// domainVerified reports whether the domain publishes token and is not
+// already verified by another company
+func (s *SSOService) domainVerified(ctx context.Context, companyID uuid.UUID, domain, token string) (bool, error) {
+	if token == "" {
+		return false, nil
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, ssoDiscoveryTimeout)
+	defer cancel()
+	records, err := s.lookupTXT(lookupCtx, ssoVerificationPrefix+domain)
+	if err != nil {
+		// Missing records and DNS failures both mean not verified for now
+		return false, nil
+	}
+
+	found := false
+	for _, record := range records {
+		if strings.TrimSpace(record) == token {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return false, nil
+	}
+
+	owner, err := configForDomain(s.db.Where("company_id <> ?", companyID), domain)
+	if err != nil {
+		return false, err
+	}
+	return owner == nil, nil
+}
+
+// Delete removes the company's single sign-on settings and identity links
+func (s *SSOService) Delete(companyID uuid.UUID) error {
+	cfg, err := s.Get(companyID)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("provider = ?", ssoProvider(companyID)).Delete(&models.UserIdentity{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(cfg).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.forgetProvider(cfg.ID)
+	return nil
+}
+
+// ConfigForEmail finds the enabled single sign-on settings covering the
+// domain of an email address
+func (s *SSOService) ConfigForEmail(email string) (*models.SSOConfig, error) {
+	_, domain, ok := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+	if !ok || domain == "" {
+		return nil, ErrSSONotConfigured
+	}
+
+	cfg, err := configForDomain(s.db.Where("enabled = ?", true), domain)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		return nil, ErrSSONotConfigured
+	}
+	return cfg, nil
+}
+
+// ConfigByID returns enabled single sign-on settings by ID
+func (s *SSOService) ConfigByID(id uuid.UUID) (*models.SSOConfig, error) {
+	var cfg models.SSOConfig
+	if err := s.db.Where("id = ? AND enabled = ?", id, true).First(&cfg).Error; err != nil {
+		return nil, ErrSSOConfigNotFound
+	}
+	return &cfg, nil
+}
+
+// Provider returns the identity provider for the settings
+func (s *SSOService) Provider(cfg *models.SSOConfig) (IdentityProvider, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cached, ok := s.providers[cfg.ID]; ok && cached.updatedAt.Equal(cfg.UpdatedAt) {
+		return cached.provider, nil
+	}
+	provider, err := s.newProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	s.providers[cfg.ID] = cachedProvider{provider: provider, updatedAt: cfg.UpdatedAt}
+	return provider, nil
+}
+
+// SignIn logs in the company user an identity belongs to. Users signing in
+// for the first time are created in the company with its default role; an
+// existing account with the same email is linked when it is in the company.
+func (s *SSOService) SignIn(cfg *models.SSOConfig, identity *oidc.Identity, meta SessionMeta) (*AuthResponse, error) {
+	provider := ssoProvider(cfg.CompanyID)
+	var user models.User
+	claimed := false
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var link models.UserIdentity
+		err := tx.Where("provider = ? AND subject = ?", provider, identity.Subject).First(&link).Error
+		if err == nil {
+			return tx.First(&user, "id = ? AND company_id = ?", link.UserID, cfg.CompanyID).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		// The company vouches for its identity provider, so the address is
+		// trusted as long as it is in one of the company's domains
+		_, domain, _ := strings.Cut(identity.Email, "@")
+		if domain == "" || !cfg.VerifiedDomains.Contains(domain) {
+			return ErrSSODomainNotAllowed
+		}
+
+		err = tx.Where("LOWER(email) = ?", identity.Email).First(&user).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			name := strings.TrimSpace(identity.Name)
+			if name == "" {
+				name, _, _ = strings.Cut(identity.Email, "@")
+			}
+			user = models.User{
+				CompanyID:     cfg.CompanyID,
+				Name:          name,
+				Email:         identity.Email,
+				Role:          cfg.DefaultRole,
+				EmailVerified: true,
+			}
+			if identity.Picture != "" {
+				user.AvatarURL = &identity.Picture
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case user.CompanyID != cfg.CompanyID:
+			return ErrSSOAccountConflict
+		case !user.EmailVerified:
+			claimed = true
+			if err := claimUnverifiedUser(tx, &user); err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&models.UserIdentity{
+			UserID:   user.ID,
+			Provider: provider,
+			Subject:  identity.Subject,
+			Email:    identity.Email,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if claimed {
+		middleware.ForgetUserSessions(user.ID, uuid.Nil)
+	}
+
+	return s.auth.createSession(&user, models.SignInSSO, meta)
+}
+
+// newProvider creates the company's identity provider. Its endpoints come
+// from an admin-supplied issuer, so in production they must use https and
+// may not resolve to private addresses.
+func (s *SSOService) newProvider(cfg *models.SSOConfig) (*oidc.Provider, error) {
+	return oidc.New(oidc.Options{
+		Issuer:       cfg.Issuer,
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  s.redirectURL,
+		HTTPClient:   s.client,
+		RequireHTTPS: config.AppConfig.Env == "production",
+	})
+}
+
+func (s *SSOService) forgetProvider(id uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.providers, id)
+}
+
+// checkDomainsAvailable rejects domains another company has verified
+func (s *SSOService) checkDomainsAvailable(companyID uuid.UUID, domains models.DomainList) error {
+	for _, domain := range domains {
+		owner, err := configForDomain(s.db.Where("company_id <> ?", companyID), domain)
+		if err != nil {
+			return err
+		}
+		if owner != nil {
+			return fmt.Errorf("%w: %s is already used by another company", ErrInvalidSSOConfig, domain)
+		}
+	}
+	return nil
+}
+
+// revokeNonSSOSessions signs out the company's users everywhere they did
+// not sign in with SSO, once it becomes required
+func revokeNonSSOSessions(tx *gorm.DB, companyID uuid.UUID) error {
+	return tx.Where("user_id IN (?) AND (sign_in_method IS NULL OR sign_in_method <> ?)",
+		tx.Model(&models.User{}).Select("id").Where("company_id = ?", companyID), models.SignInSSO).
+		Delete(&models.Session{}).Error
+}
+
+// ssoEnforced reports whether a company requires its users to sign in with SSO
+func ssoEnforced(db *gorm.DB, companyID uuid.UUID) (bool, error) {
+	var count int64
+	err := db.Model(&models.SSOConfig{}).
+		Where("company_id = ? AND enabled = ? AND enforced = ?", companyID, true, true).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ssoEnforcedForEmail reports whether a company enforcing SSO claims the
+// email's domain, so no account outside it may be created for the address
+func ssoEnforcedForEmail(db *gorm.DB, email string) (bool, error) {
+	_, domain, _ := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+	cfg, err := configForDomain(db.Where("enabled = ? AND enforced = ?", true, true), domain)
+	return cfg != nil, err
+}
+
+// configForDomain returns the settings with domain verified, or nil
+func configForDomain(db *gorm.DB, domain string) (*models.SSOConfig, error) {
+	contains, err := json.Marshal([]string{domain})
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg models.SSOConfig
+	err = db.Where("verified_domains @> ?::jsonb", string(contains)).First(&cfg).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// normalizeIssuer requires an https issuer; plain http is accepted outside
+// production so a local Keycloak or mock provider can be used
+func normalizeIssuer(raw string) (string, error) {
+	issuer := strings.TrimSuffix(strings.TrimSpace(raw), "/")
+	u, err := url.Parse(issuer)
+	if err != nil || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("%w: issuer must be an absolute URL", ErrInvalidSSOConfig)
+	}
+	if u.Scheme != "https" && (u.Scheme != "http" || config.AppConfig.Env == "production") {
+		return "", fmt.Errorf("%w: issuer must use https", ErrInvalidSSOConfig)
+	}
+	return issuer, nil
+}
+
+// normalizeDomains lowercases and validates email domains
+func normalizeDomains(raw []string) (models.DomainList, error) {
+	domains := models.DomainList{}
+	for _, d := range raw {
+		domain := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(d)), "@")
+		if domain == "" {
+			continue
+		}
+		if !domainPattern.MatchString(domain) {
+			return nil, fmt.Errorf("%w: %q is not a valid email domain", ErrInvalidSSOConfig, d)
+		}
+		if publicMailDomains[domain] {
+			return nil, fmt.Errorf("%w: %s is a public email provider and cannot be claimed", ErrInvalidSSOConfig, domain)
+		}
+		if !domains.Contains(domain) {
+			domains = append(domains, domain)
+		}
+	}
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("%w: at least one allowed domain is required", ErrInvalidSSOConfig)
+	}
+	return domains, nil
+}
+
+func newDomainVerificationToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "billtracker-verification=" + hex.EncodeToString(b), nil
+}
@@ -7,6 +7,7 @@ import (
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 
+	"github.com/dhani/bill-tracker-backend/internal/middleware"
 	"github.com/dhani/bill-tracker-backend/internal/models"
 )
 
@@ -58,12 +59,14 @@ func (s *UserService) UpdateProfile(userID uuid.UUID, input UpdateProfileInput)
 	if err := s.db.Model(&user).Updates(updates).Error; err != nil {
 		return nil, err
 	}
+	// Cached sessions carry the old profile
+	middleware.ForgetUserSessions(userID, uuid.Nil)
 
 	return s.GetProfile(userID)
 }
 
-// ChangePassword changes user password
-func (s *UserService) ChangePassword(userID uuid.UUID, input ChangePasswordInput) error {
+// ChangePassword changes user password and logs out the user's other sessions
+func (s *UserService) ChangePassword(userID, sessionID uuid.UUID, input ChangePasswordInput) error {
 	var user models.User
 	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
 		return errors.New("user not found")
@@ -80,5 +83,16 @@ func (s *UserService) ChangePassword(userID uuid.UUID, input ChangePasswordInput
 		return errors.New("failed to hash password")
 	}
 
-	return s.db.Model(&user).Update("password_hash", string(hashedPassword)).Error
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("password_hash", string(hashedPassword)).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ? AND id <> ?", userID, sessionID).Delete(&models.Session{}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	middleware.ForgetUserSessions(userID, sessionID)
+	return nil
 }
@@ -2,6 +2,8 @@ package services
 
 import (
 	"errors"
+	"strings"
+	"unicode"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -26,6 +28,7 @@ type CreateVendorInput struct {
 	ContactInfo  *string `json:"contact_info"`
 	Address      *string `json:"address"`
 	Location     *string `json:"location"`
+	TaxID        *string `json:"tax_id" binding:"omitempty,max=50"`
 }
 
 // UpdateVendorInput holds data for updating a vendor
@@ -37,6 +40,7 @@ type UpdateVendorInput struct {
 	ContactInfo  *string `json:"contact_info"`
 	Address      *string `json:"address"`
 	Location     *string `json:"location"`
+	TaxID        *string `json:"tax_id" binding:"omitempty,max=50"`
 }
 
 // List retrieves all vendors for a company
@@ -67,6 +71,7 @@ func (s *VendorService) Create(companyID uuid.UUID, input CreateVendorInput) (*m
 		ContactInfo:  input.ContactInfo,
 		Address:      input.Address,
 		Location:     input.Location,
+		TaxID:        normalizeTaxID(input.TaxID),
 	}
 
 	if err := s.db.Create(&vendor).Error; err != nil {
@@ -104,6 +109,9 @@ func (s *VendorService) Update(companyID, vendorID uuid.UUID, input UpdateVendor
 	if input.Location != nil {
 		updates["location"] = *input.Location
 	}
+	if input.TaxID != nil {
+		updates["tax_id"] = normalizeTaxID(input.TaxID)
+	}
 
 	if err := s.db.Model(&vendor).Updates(updates).Error; err != nil {
 		return nil, err
@@ -120,3 +128,25 @@ func (s *VendorService) Delete(companyID, vendorID uuid.UUID) error {
 	}
 	return result.Error
 }
+
+// maxTaxIDLength is the size of the vendors.tax_id column
+const maxTaxIDLength = 50
+
+// normalizeTaxID strips spacing and punctuation from a VAT or tax number and
+// upper-cases it, so "DE 123.456.789" and "de123456789" match. Blank IDs
+// become nil.
+func normalizeTaxID(taxID *string) *string {
+	if taxID == nil {
+		return nil
+	}
+	normalized := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, *taxID)
+	if normalized == "" {
+		return nil
+	}
+	return &normalized
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/dhani/bill-tracker-backend/internal/models"
+)
+
+const (
+	// webhookMaxAttempts is how often a delivery is tried before it fails
+	webhookMaxAttempts = 10
+	// webhookDisableAfter consecutive failed attempts disable a webhook
+	webhookDisableAfter = 20
+	// webhookRetryBase is the first retry delay; each retry doubles it
+	webhookRetryBase = time.Minute
+	webhookRetryMax  = 6 * time.Hour
+	// webhookBatchSize bounds the deliveries sent per run
+	webhookBatchSize = 100
+	// maxWebhookResponseLog is how much of a response body is kept
+	maxWebhookResponseLog = 2048
+)
+
+// WebhookDispatcher sends queued webhook deliveries
+type WebhookDispatcher struct {
+	db     *gorm.DB
+	client *http.Client
+	// lease is how long a claimed delivery is held before another
+	// dispatcher may retry it
+	lease time.Duration
+}
+
+// NewWebhookDispatcher creates a dispatcher. Unless allowPrivate is set,
+// connections to loopback, private and link-local addresses are refused so
+// webhooks cannot be pointed at internal services.
+func NewWebhookDispatcher(db *gorm.DB, timeout time.Duration, allowPrivate bool) *WebhookDispatcher {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = denyPrivateAddress
+	}
+
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          20,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	return &WebhookDispatcher{
+		db:    db,
+		lease: timeout + time.Minute,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+			// A redirect is reported as the response rather than followed
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// DeliverDue sends deliveries whose next attempt is due, returning how many
+// were attempted. Each delivery is claimed before it is sent, so several
+// dispatchers can run side by side.
+func (d *WebhookDispatcher) DeliverDue(ctx context.Context, now time.Time) (int, error) {
+	count := 0
+	for count < webhookBatchSize && ctx.Err() == nil {
+		attempted, err := d.deliverNext(ctx, now)
+		if err != nil || !attempted {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// deliverNext claims the next due delivery, sends it and records the
+// outcome. The row is only locked while it is claimed, not during the
+// request: the claim counts the attempt and pushes next_attempt_at past the
+// request timeout, so other dispatchers skip it, and a delivery whose
+// dispatcher dies mid-request is retried once the lease runs out.
+func (d *WebhookDispatcher) deliverNext(ctx context.Context, now time.Time) (bool, error) {
+	var webhook models.Webhook
+	var delivery models.WebhookDelivery
+	started := time.Now()
+
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "webhook_deliveries"}, Options: "SKIP LOCKED"}).
+			Joins("JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id AND webhooks.enabled = ?", true).
+			Where("webhook_deliveries.status = ? AND webhook_deliveries.next_attempt_at <= ?", models.DeliveryPending, now).
+			Order("webhook_deliveries.next_attempt_at ASC").
+			Take(&delivery).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.First(&webhook, "id = ?", delivery.WebhookID).Error; err != nil {
+			return err
+		}
+
+		delivery.Attempts++
+		return tx.Model(&delivery).Updates(map[string]interface{}{
+			"attempts":        delivery.Attempts,
+			"last_attempt_at": started,
+			"next_attempt_at": started.Add(d.lease),
+		}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	d.attempt(ctx, &webhook, &delivery, started)
+	return true, nil
+}
+
+// attempt posts the delivery once and records the outcome, scheduling a
+// retry with exponential backoff or giving up after the last attempt
+func (d *WebhookDispatcher) attempt(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery, started time.Time) {
+	status, body, sendErr := d.send(ctx, webhook, delivery)
+	duration := time.Since(started).Milliseconds()
+
+	updates := map[string]interface{}{
+		"duration_ms":     duration,
+		"response_status": nil,
+		"response_body":   nil,
+		"error":           nil,
+	}
+	if status != 0 {
+		updates["response_status"] = status
+		updates["response_body"] = body
+	}
+
+	succeeded := sendErr == nil && status >= 200 && status < 300
+	if succeeded {
+		updates["status"] = models.DeliverySucceeded
+		updates["next_attempt_at"] = nil
+	} else {
+		message := fmt.Sprintf("endpoint responded with status %d", status)
+		if sendErr != nil {
+			message = sendErr.Error()
+		}
+		d.scheduleRetry(updates, delivery.Attempts, started, message)
+	}
+
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(delivery).Updates(updates).Error; err != nil {
+			return err
+		}
+		return d.recordOutcome(tx, webhook, succeeded)
+	})
+	if err == nil {
+		return
+	}
+
+	// The outcome could not be stored, so count the attempt as failed
+	// rather than leave the delivery to be sent again when the lease ends
+	log.Printf("[webhooks] failed to record delivery %s: %v", delivery.ID, err)
+	updates = map[string]interface{}{"duration_ms": duration, "response_status": nil, "response_body": nil}
+	d.scheduleRetry(updates, delivery.Attempts, started, "failed to record the response: "+err.Error())
+	err = d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(delivery).Updates(updates).Error; err != nil {
+			return err
+		}
+		return d.recordOutcome(tx, webhook, false)
+	})
+	if err != nil {
+		log.Printf("[webhooks] failed to record delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// scheduleRetry sets the retry time of a failed attempt, or fails the
+// delivery after the last attempt
+func (d *WebhookDispatcher) scheduleRetry(updates map[string]interface{}, attempts int, started time.Time, message string) {
+	updates["error"] = message
+	if attempts >= webhookMaxAttempts {
+		updates["status"] = models.DeliveryFailed
+		updates["next_attempt_at"] = nil
+	} else {
+		updates["next_attempt_at"] = started.Add(webhookRetryDelay(attempts))
+	}
+}
+
+// recordOutcome tracks consecutive failures and disables the webhook once
+// there are too many
+func (d *WebhookDispatcher) recordOutcome(tx *gorm.DB, webhook *models.Webhook, succeeded bool) error {
+	if succeeded {
+		return tx.Model(webhook).Where("consecutive_failures > 0").Update("consecutive_failures", 0).Error
+	}
+
+	// Counted in SQL, as other dispatchers may be delivering to the same webhook
+	if err := tx.Model(webhook).Update("consecutive_failures", gorm.Expr("consecutive_failures + 1")).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Webhook{}).
+		Where("id = ? AND enabled = ? AND consecutive_failures >= ?", webhook.ID, true, webhookDisableAfter).
+		Updates(map[string]interface{}{
+			"enabled":         false,
+			"disabled_at":     time.Now(),
+			"disabled_reason": fmt.Sprintf("Disabled after %d consecutive failed deliveries", webhookDisableAfter),
+		}).Error
+}
+
+// send posts the payload, returning the response status and the start of
+// the response body
+func (d *WebhookDispatcher) send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, strings.NewReader(string(delivery.Payload)))
+	if err != nil {
+		return 0, "", err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "BillTracker-Webhooks/1.0")
+	req.Header.Set("X-Webhook-Event", string(delivery.Event))
+	req.Header.Set("X-Webhook-Event-Id", delivery.EventID.String())
+	req.Header.Set("X-Webhook-Delivery", delivery.ID.String())
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+SignWebhookPayload(webhook.Secret, timestamp, []byte(delivery.Payload)))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseLog))
+	// Drain a little more so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	// Postgres text columns reject NUL bytes as well as invalid UTF-8
+	logged := strings.ReplaceAll(strings.ToValidUTF8(string(body), "�"), "\x00", "")
+	return resp.StatusCode, logged, nil
+}
+
+// SignWebhookPayload returns the hex HMAC-SHA256 of "<timestamp>.<body>"
+// keyed with the webhook secret. Receivers recompute it to verify a request
+// and should reject stale timestamps to prevent replays.
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	return hex.EncodeToString(hmacSHA256([]byte(secret), timestamp+"."+string(body)))
+}
+
+// webhookRetryDelay doubles the delay after every failed attempt
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := webhookRetryBase
+	for i := 1; i < attempts && delay < webhookRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookRetryMax)
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/dhani/bill-tracker-backend/internal/models"
+)
+
+// WebhookPayload is the JSON body posted to webhooks
+type WebhookPayload struct {
+	ID        uuid.UUID           `json:"id"`
+	Event     models.WebhookEvent `json:"event"`
+	CreatedAt time.Time           `json:"created_at"`
+	CompanyID uuid.UUID           `json:"company_id"`
+	Data      BillEventData       `json:"data"`
+}
+
+// BillEventData describes the bill an event is about, as it was when the
+// event happened
+type BillEventData struct {
+	Bill       *models.Bill        `json:"bill"`
+	Changes    models.FieldChanges `json:"changes,omitempty"`
+	FromStatus *models.BillStatus  `json:"from_status,omitempty"`
+	ToStatus   *models.BillStatus  `json:"to_status,omitempty"`
+	Payment    *models.Payment     `json:"payment,omitempty"`
+}
+
+// enqueueBillEvent queues an event for every enabled webhook of the bill's
+// company that subscribes to it. It runs inside the transaction that
+// changed the bill, so events are queued exactly when the change commits.
+func enqueueBillEvent(tx *gorm.DB, event models.WebhookEvent, billID uuid.UUID, data BillEventData) error {
+	subscribed, err := json.Marshal([]models.WebhookEvent{event})
+	if err != nil {
+		return err
+	}
+
+	var webhooks []models.Webhook
+	if err := tx.Select("id", "company_id").
+		Where("enabled = ? AND events @> ?::jsonb", true, string(subscribed)).
+		Where("company_id = (SELECT company_id FROM bills WHERE id = ?)", billID).
+		Find(&webhooks).Error; err != nil {
+		return err
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	var bill models.Bill
+	if err := tx.Unscoped().
+		Preload("User").
+		Preload("Vendor").
+		Preload("Category").
+		Preload("LineItems").
+		First(&bill, "id = ?", billID).Error; err != nil {
+		return err
+	}
+	data.Bill = &bill
+
+	payload := WebhookPayload{
+		ID:        uuid.New(),
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		CompanyID: bill.CompanyID,
+		Data:      data,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	deliveries := make([]models.WebhookDelivery, len(webhooks))
+	for i, webhook := range webhooks {
+		deliveries[i] = models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       payload.ID,
+			Event:         event,
+			Payload:       models.RawJSON(body),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: &payload.CreatedAt,
+		}
+	}
+	return tx.Create(&deliveries).Error
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/dhani/bill-tracker-backend/internal/models"
+	"github.com/dhani/bill-tracker-backend/internal/utils"
+)
+
+var (
+	ErrInvalidWebhook          = errors.New("invalid webhook")
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+type WebhookService struct {
+	db *gorm.DB
+}
+
+func NewWebhookService(db *gorm.DB) *WebhookService {
+	return &WebhookService{db: db}
+}
+
+// WebhookInput holds data for creating or updating a webhook. A secret is
+// generated when none is given.
+type WebhookInput struct {
+	URL         string                `json:"url" binding:"required"`
+	Events      []models.WebhookEvent `json:"events" binding:"required"`
+	Secret      *string               `json:"secret"`
+	Description *string               `json:"description"`
+	Enabled     *bool                 `json:"enabled"`
+}
+
+// List retrieves a company's webhooks
+func (s *WebhookService) List(companyID uuid.UUID) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := s.db.Where("company_id = ?", companyID).Order("created_at ASC").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// Get retrieves a webhook of the company
+func (s *WebhookService) Get(companyID, webhookID uuid.UUID) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := s.db.Where("company_id = ? AND id = ?", companyID, webhookID).First(&webhook).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// Create adds a webhook subscription
+func (s *WebhookService) Create(companyID uuid.UUID, input WebhookInput) (*models.Webhook, error) {
+	events, err := validateWebhook(&input)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := models.Webhook{
+		CompanyID:   companyID,
+		URL:         input.URL,
+		Events:      events,
+		Description: input.Description,
+		Enabled:     input.Enabled == nil || *input.Enabled,
+	}
+	if input.Secret != nil {
+		webhook.Secret = *input.Secret
+	} else if webhook.Secret, err = newWebhookSecret(); err != nil {
+		return nil, err
+	}
+
+	// Select every column so a disabled webhook is not saved with the column default
+	if err := s.db.Select("*").Create(&webhook).Error; err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// Update replaces a webhook's settings. Enabling a webhook clears its
+// failure count; deliveries queued while it was disabled are then sent.
+func (s *WebhookService) Update(companyID, webhookID uuid.UUID, input WebhookInput) (*models.Webhook, error) {
+	webhook, err := s.Get(companyID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := validateWebhook(&input)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{
+		"url":         input.URL,
+		"events":      events,
+		"description": input.Description,
+	}
+	if input.Secret != nil {
+		updates["secret"] = *input.Secret
+	}
+	if input.Enabled != nil {
+		updates["enabled"] = *input.Enabled
+		if *input.Enabled && !webhook.Enabled {
+			updates["consecutive_failures"] = 0
+			updates["disabled_at"] = nil
+			updates["disabled_reason"] = nil
+		}
+	}
+
+	if err := s.db.Model(webhook).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return s.Get(companyID, webhookID)
+}
+
+// Delete removes a webhook and its delivery log
+func (s *WebhookService) Delete(companyID, webhookID uuid.UUID) error {
+	result := s.db.Where("company_id = ? AND id = ?", companyID, webhookID).Delete(&models.Webhook{})
+	if result.RowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+	return result.Error
+}
+
+// ListDeliveries retrieves a page of a webhook's delivery log, newest first
+func (s *WebhookService) ListDeliveries(companyID, webhookID uuid.UUID, pagination utils.Pagination) ([]models.WebhookDelivery, int64, error) {
+	if _, err := s.Get(companyID, webhookID); err != nil {
+		return nil, 0, err
+	}
+
+	query := s.db.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var deliveries []models.WebhookDelivery
+	err := query.Order("created_at DESC").
+		Offset(pagination.GetOffset()).
+		Limit(pagination.PageSize).
+		Find(&deliveries).Error
+	return deliveries, total, err
+}
+
+// Redeliver queues a new delivery of the same event and payload, e.g. after
+// the receiving side fixed a bug. It is sent on the next dispatcher run.
+func (s *WebhookService) Redeliver(companyID, webhookID, deliveryID uuid.UUID) (*models.WebhookDelivery, error) {
+	if _, err := s.Get(companyID, webhookID); err != nil {
+		return nil, err
+	}
+
+	var original models.WebhookDelivery
+	if err := s.db.Where("webhook_id = ? AND id = ?", webhookID, deliveryID).First(&original).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	delivery := models.WebhookDelivery{
+		WebhookID:     webhookID,
+		EventID:       original.EventID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: &now,
+	}
+	if err := s.db.Create(&delivery).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// validateWebhook checks the input and returns its events without duplicates
+func validateWebhook(input *WebhookInput) (models.WebhookEventList, error) {
+	input.URL = strings.TrimSpace(input.URL)
+	u, err := url.Parse(input.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+	if u.User != nil {
+		return nil, fmt.Errorf("%w: url must not contain credentials; verify requests with the signature instead", ErrInvalidWebhook)
+	}
+
+	if input.Secret != nil && len(*input.Secret) < 16 {
+		return nil, fmt.Errorf("%w: secret must be at least 16 characters", ErrInvalidWebhook)
+	}
+
+	events := models.WebhookEventList{}
+	for _, event := range input.Events {
+		if !event.IsValid() {
+			return nil, fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, event)
+		}
+		if !events.Contains(event) {
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%w: subscribe to at least one event", ErrInvalidWebhook)
+	}
+	return events, nil
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
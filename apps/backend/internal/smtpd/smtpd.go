@@ -0,0 +1,546 @@
+// Package smtpd is a small SMTP server (RFC 5321) for receiving mail. It
+// implements what a mail transfer agent needs to hand over messages —
+// EHLO/HELO, MAIL, RCPT, DATA, RSET, NOOP and QUIT with the SIZE and
+// 8BITMIME extensions — and leaves routing and storage to a Handler. It does
+// not offer STARTTLS or AUTH, so it should sit behind an MTA or listen on a
+// private network.
+package smtpd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrServerClosed is returned by Serve after Shutdown
+var ErrServerClosed = errors.New("smtpd: server closed")
+
+// Envelope carries the SMTP transaction a message arrived with
+type Envelope struct {
+	RemoteAddr net.Addr
+	Helo       string
+	From       string
+	To         []string
+}
+
+// Handler routes and stores received mail
+type Handler interface {
+	// Recipient is called for each RCPT TO; an error rejects the recipient
+	Recipient(ctx context.Context, address string) error
+	// Deliver receives a complete message after DATA. An error is reported
+	// to the client; return an *Error to choose the reply code.
+	Deliver(ctx context.Context, envelope Envelope, data []byte) error
+}
+
+// Error is an SMTP reply returned by a Handler
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s", e.Code, e.Message)
+}
+
+const (
+	maxLineLength    = 1000 // RFC 5321 section 4.5.3.1.6, including CRLF
+	maxErrors        = 10
+	defaultMaxSize   = 25 << 20
+	defaultMaxRcpts  = 50
+	defaultTimeout   = 5 * time.Minute
+	defaultMaxConns  = 100
+	defaultLifetime  = 30 * time.Minute
+	defaultHostname  = "localhost"
+	handlerTimeout   = 2 * time.Minute
+	shutdownPollTime = 100 * time.Millisecond
+)
+
+// Server accepts SMTP connections and passes messages to Handler
+type Server struct {
+	Addr     string
+	Hostname string
+	Handler  Handler
+	// MaxSize is the largest accepted message in bytes
+	MaxSize int64
+	// MaxRecipients bounds RCPT commands per message
+	MaxRecipients int
+	// Timeout applies to each command and to the whole DATA transfer
+	Timeout time.Duration
+	// MaxConnections bounds concurrent sessions; further clients are
+	// turned away with a 421 until a session ends
+	MaxConnections int
+	// MaxLifetime bounds how long one connection may stay open in total
+	MaxLifetime time.Duration
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+}
+
+// ListenAndServe listens on Addr and serves connections until Shutdown
+func (s *Server) ListenAndServe() error {
+	l, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l until Shutdown
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	s.listener = l
+	s.conns = make(map[net.Conn]struct{})
+	s.mu.Unlock()
+
+	// Every session may buffer a whole message, so their number is bounded
+	slots := make(chan struct{}, s.maxConnections())
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				time.Sleep(shutdownPollTime)
+				continue
+			}
+			return err
+		}
+
+		select {
+		case slots <- struct{}{}:
+		default:
+			go refuse(conn)
+			continue
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		go func() {
+			defer func() {
+				conn.Close()
+				s.mu.Lock()
+				delete(s.conns, conn)
+				s.mu.Unlock()
+				<-slots
+			}()
+			s.newSession(conn).serve()
+		}()
+	}
+}
+
+// refuse turns away a client while the server is at capacity
+func refuse(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	fmt.Fprint(conn, "421 4.3.2 Too many connections, try again later\r\n")
+}
+
+// Shutdown stops accepting connections and waits for open sessions to end,
+// closing them when ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	s.mu.Unlock()
+
+	ticker := time.NewTicker(shutdownPollTime)
+	defer ticker.Stop()
+	for {
+		s.mu.Lock()
+		open := len(s.conns)
+		if ctx.Err() != nil {
+			for conn := range s.conns {
+				conn.Close()
+			}
+		}
+		s.mu.Unlock()
+
+		if open == 0 {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) hostname() string {
+	if s.Hostname != "" {
+		return s.Hostname
+	}
+	return defaultHostname
+}
+
+func (s *Server) maxSize() int64 {
+	if s.MaxSize > 0 {
+		return s.MaxSize
+	}
+	return defaultMaxSize
+}
+
+func (s *Server) maxRecipients() int {
+	if s.MaxRecipients > 0 {
+		return s.MaxRecipients
+	}
+	return defaultMaxRcpts
+}
+
+func (s *Server) maxConnections() int {
+	if s.MaxConnections > 0 {
+		return s.MaxConnections
+	}
+	return defaultMaxConns
+}
+
+func (s *Server) maxLifetime() time.Duration {
+	if s.MaxLifetime > 0 {
+		return s.MaxLifetime
+	}
+	return defaultLifetime
+}
+
+func (s *Server) timeout() time.Duration {
+	if s.Timeout > 0 {
+		return s.Timeout
+	}
+	return defaultTimeout
+}
+
+// session is the state of one SMTP connection
+type session struct {
+	server *Server
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+	// closesAt is when the connection is dropped whatever it is doing
+	closesAt time.Time
+
+	helo     string
+	from     string
+	hasFrom  bool
+	to       []string
+	errCount int
+}
+
+func (s *Server) newSession(conn net.Conn) *session {
+	return &session{
+		server:   s,
+		conn:     conn,
+		reader:   bufio.NewReaderSize(conn, maxLineLength),
+		writer:   bufio.NewWriter(conn),
+		closesAt: time.Now().Add(s.maxLifetime()),
+	}
+}
+
+func (s *session) serve() {
+	s.reply(220, s.server.hostname()+" ESMTP ready")
+
+	for {
+		s.setDeadline()
+
+		line, err := s.readLine()
+		if errors.Is(err, bufio.ErrBufferFull) {
+			s.reply(500, "5.5.2 Line too long")
+			if !s.discardLine() || !s.countError() {
+				return
+			}
+			continue
+		}
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		if !s.handle(strings.ToUpper(verb), strings.TrimSpace(arg)) {
+			return
+		}
+	}
+}
+
+// handle runs one command and reports whether the session continues
+func (s *session) handle(verb, arg string) bool {
+	switch verb {
+	case "HELO", "EHLO":
+		if arg == "" {
+			s.reply(501, "5.5.4 Domain required")
+			return s.countError()
+		}
+		s.helo = arg
+		s.reset()
+		if verb == "HELO" {
+			s.reply(250, s.server.hostname())
+			return true
+		}
+		s.replyLines(250,
+			s.server.hostname(),
+			"SIZE "+strconv.FormatInt(s.server.maxSize(), 10),
+			"8BITMIME",
+			"PIPELINING",
+		)
+	case "MAIL":
+		return s.mail(arg)
+	case "RCPT":
+		return s.rcpt(arg)
+	case "DATA":
+		return s.data()
+	case "RSET":
+		s.reset()
+		s.reply(250, "2.0.0 OK")
+	case "NOOP":
+		s.reply(250, "2.0.0 OK")
+	case "VRFY":
+		s.reply(252, "2.5.0 Cannot verify, but will accept the message")
+	case "QUIT":
+		s.reply(221, "2.0.0 Bye")
+		return false
+	case "STARTTLS", "AUTH":
+		s.reply(502, "5.5.1 Not supported")
+		return s.countError()
+	default:
+		s.reply(500, "5.5.2 Command not recognized")
+		return s.countError()
+	}
+	return true
+}
+
+func (s *session) mail(arg string) bool {
+	if s.helo == "" {
+		s.reply(503, "5.5.1 Send HELO or EHLO first")
+		return s.countError()
+	}
+	if s.hasFrom {
+		s.reply(503, "5.5.1 Sender already specified")
+		return s.countError()
+	}
+
+	address, params, ok := parsePath(arg, "FROM:")
+	if !ok {
+		s.reply(501, "5.5.4 Syntax: MAIL FROM:<address>")
+		return s.countError()
+	}
+	for _, param := range params {
+		key, value, _ := strings.Cut(param, "=")
+		if strings.EqualFold(key, "SIZE") {
+			if size, err := strconv.ParseInt(value, 10, 64); err == nil && size > s.server.maxSize() {
+				s.reply(552, "5.3.4 Message size exceeds fixed limit")
+				return true
+			}
+		}
+	}
+
+	s.from = address
+	s.hasFrom = true
+	s.reply(250, "2.1.0 OK")
+	return true
+}
+
+func (s *session) rcpt(arg string) bool {
+	if !s.hasFrom {
+		s.reply(503, "5.5.1 Send MAIL first")
+		return s.countError()
+	}
+	if len(s.to) >= s.server.maxRecipients() {
+		s.reply(452, "4.5.3 Too many recipients")
+		return true
+	}
+
+	address, _, ok := parsePath(arg, "TO:")
+	if !ok || address == "" {
+		s.reply(501, "5.5.4 Syntax: RCPT TO:<address>")
+		return s.countError()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), handlerTimeout)
+	defer cancel()
+	if err := s.server.Handler.Recipient(ctx, address); err != nil {
+		s.replyError(err, 550, "5.1.1 Mailbox unavailable")
+		return s.countError()
+	}
+
+	s.to = append(s.to, address)
+	s.reply(250, "2.1.5 OK")
+	return true
+}
+
+func (s *session) data() bool {
+	if len(s.to) == 0 {
+		s.reply(503, "5.5.1 Send RCPT first")
+		return s.countError()
+	}
+	s.reply(354, "End data with <CR><LF>.<CR><LF>")
+
+	// The whole transfer shares one deadline
+	s.setDeadline()
+	dot := textproto.NewReader(s.reader).DotReader()
+	data, err := io.ReadAll(io.LimitReader(dot, s.server.maxSize()+1))
+	if err != nil {
+		return false
+	}
+	if int64(len(data)) > s.server.maxSize() {
+		// Drain the rest of the message so the session stays in sync
+		if _, err := io.Copy(io.Discard, dot); err != nil {
+			return false
+		}
+		s.reset()
+		s.reply(552, "5.3.4 Message size exceeds fixed limit")
+		return true
+	}
+
+	envelope := Envelope{
+		RemoteAddr: s.conn.RemoteAddr(),
+		Helo:       s.helo,
+		From:       s.from,
+		To:         s.to,
+	}
+	s.reset()
+
+	ctx, cancel := context.WithTimeout(context.Background(), handlerTimeout)
+	defer cancel()
+	if err := s.server.Handler.Deliver(ctx, envelope, data); err != nil {
+		var smtpErr *Error
+		if !errors.As(err, &smtpErr) {
+			log.Printf("smtpd: delivery from %s failed: %v", envelope.From, err)
+		}
+		s.replyError(err, 451, "4.3.0 Message could not be processed, try again later")
+		return true
+	}
+
+	s.reply(250, "2.0.0 Message accepted")
+	return true
+}
+
+// setDeadline gives the next read or write the server timeout, but never
+// past the end of the connection's lifetime
+func (s *session) setDeadline() {
+	deadline := time.Now().Add(s.server.timeout())
+	if deadline.After(s.closesAt) {
+		deadline = s.closesAt
+	}
+	s.conn.SetDeadline(deadline)
+}
+
+// reset clears the current mail transaction
+func (s *session) reset() {
+	s.from = ""
+	s.hasFrom = false
+	s.to = nil
+}
+
+// countError records a failed command and reports whether to keep going
+func (s *session) countError() bool {
+	s.errCount++
+	if s.errCount >= maxErrors {
+		s.reply(421, "4.7.0 Too many errors, closing connection")
+		return false
+	}
+	return true
+}
+
+func (s *session) readLine() (string, error) {
+	line, err := s.reader.ReadSlice('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+// discardLine skips the rest of an overlong line
+func (s *session) discardLine() bool {
+	for {
+		_, err := s.reader.ReadSlice('\n')
+		if err == nil {
+			return true
+		}
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return false
+		}
+	}
+}
+
+func (s *session) reply(code int, message string) {
+	s.conn.SetWriteDeadline(time.Now().Add(s.server.timeout()))
+	fmt.Fprintf(s.writer, "%d %s\r\n", code, message)
+	s.writer.Flush()
+}
+
+func (s *session) replyLines(code int, lines ...string) {
+	s.conn.SetWriteDeadline(time.Now().Add(s.server.timeout()))
+	for i, line := range lines {
+		sep := "-"
+		if i == len(lines)-1 {
+			sep = " "
+		}
+		fmt.Fprintf(s.writer, "%d%s%s\r\n", code, sep, line)
+	}
+	s.writer.Flush()
+}
+
+// replyError answers with the handler's *Error or the given default
+func (s *session) replyError(err error, code int, message string) {
+	var smtpErr *Error
+	if errors.As(err, &smtpErr) {
+		s.reply(smtpErr.Code, smtpErr.Message)
+		return
+	}
+	s.reply(code, message)
+}
+
+// parsePath parses "FROM:<address> PARAM=value ..." and the RCPT equivalent.
+// The null reverse-path <> is returned as an empty address.
+func parsePath(arg, prefix string) (string, []string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", nil, false
+	}
+	arg = strings.TrimSpace(arg[len(prefix):])
+
+	var path string
+	var rest string
+	if strings.HasPrefix(arg, "<") {
+		end := strings.Index(arg, ">")
+		if end < 0 {
+			return "", nil, false
+		}
+		path, rest = arg[1:end], arg[end+1:]
+	} else {
+		// Some clients omit the angle brackets
+		path, rest, _ = strings.Cut(arg, " ")
+	}
+
+	// Drop a source route (@a,@b:user@host), which RFC 5321 says to ignore
+	if i := strings.LastIndex(path, ":"); strings.HasPrefix(path, "@") && i >= 0 {
+		path = path[i+1:]
+	}
+	if path != "" && !strings.Contains(path, "@") {
+		return "", nil, false
+	}
+	return path, strings.Fields(rest), true
+}
@@ -0,0 +1,340 @@
+// Package spreadsheet reads and writes tabular files as rows of strings.
+// XLSX support covers the subset produced by common spreadsheet applications:
+// the first worksheet, shared and inline strings, and numbers.
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrUnsupportedFormat is returned for files that are neither CSV nor XLSX
+	ErrUnsupportedFormat = errors.New("unsupported file format, expected .csv or .xlsx")
+	// ErrTooManyRows is returned once a file has more rows than the caller allows
+	ErrTooManyRows = errors.New("too many rows")
+)
+
+// maxEntrySize bounds a decompressed XLSX part, so a small zip cannot expand
+// into gigabytes of XML
+const maxEntrySize = 64 << 20
+
+// Width picks from the header row how many columns the caller uses; later
+// rows are cut to that width
+type Width func(header []string) int
+
+// Read parses a CSV or XLSX file, picking the format from the filename
+// extension. Reading stops with ErrTooManyRows after maxRows rows, the
+// header included.
+func Read(filename string, r io.Reader, maxRows int, width Width) ([][]string, error) {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv":
+		return ReadCSV(r, maxRows, width)
+	case ".xlsx":
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return ReadXLSX(bytes.NewReader(data), int64(len(data)), maxRows, width)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+// ReadCSV parses a comma separated file of at most maxRows rows. Rows may
+// have differing lengths.
+func ReadCSV(r io.Reader, maxRows int, width Width) ([][]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var rows [][]string
+	columns := 0
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) == maxRows {
+			return nil, ErrTooManyRows
+		}
+		if len(rows) == 0 {
+			// Drop a UTF-8 byte order mark written by some spreadsheet exports
+			if len(row) > 0 {
+				row[0] = strings.TrimPrefix(row[0], "\ufeff")
+			}
+			columns = width(row)
+		} else if len(row) > columns {
+			row = row[:columns]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxRow struct {
+	Cells []struct {
+		Ref    string   `xml:"r,attr"`
+		Type   string   `xml:"t,attr"`
+		Value  string   `xml:"v"`
+		Inline xlsxText `xml:"is"`
+	} `xml:"c"`
+}
+
+// ReadXLSX parses the first worksheet of an XLSX workbook, at most maxRows
+// rows of it. Numbers are returned as written in the file, so dates appear
+// as Excel serial numbers.
+func ReadXLSX(r io.ReaderAt, size int64, maxRows int, width Width) ([][]string, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("not an xlsx file: %w", err)
+	}
+
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	var shared xlsxSharedStrings
+	if file, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeXML(file, &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	sheetFile, err := firstSheet(files)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := openEntry(sheetFile)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	// Rows are decoded one at a time so that a huge sheet is rejected
+	// before it is in memory
+	var rows [][]string
+	columns := maxColumns
+	decoder := xml.NewDecoder(rc)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", sheetFile.Name, err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+		if len(rows) == maxRows {
+			return nil, ErrTooManyRows
+		}
+
+		var row xlsxRow
+		if err := decoder.DecodeElement(&row, &start); err != nil {
+			return nil, fmt.Errorf("%s: %w", sheetFile.Name, err)
+		}
+		values, err := rowValues(&row, &shared, columns)
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) == 0 {
+			columns = width(values)
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+// rowValues places a row's cells by their references, ignoring cells at or
+// beyond width. Trailing empty cells are trimmed.
+func rowValues(row *xlsxRow, shared *xlsxSharedStrings, width int) ([]string, error) {
+	var values []string
+	for i, cell := range row.Cells {
+		col := i
+		if cell.Ref != "" {
+			var err error
+			if col, err = columnIndex(cell.Ref); err != nil {
+				return nil, err
+			}
+		}
+		if col >= width {
+			continue
+		}
+
+		var value string
+		switch cell.Type {
+		case "s":
+			idx, err := strconv.Atoi(cell.Value)
+			if err != nil || idx < 0 || idx >= len(shared.Items) {
+				return nil, fmt.Errorf("cell %s: invalid shared string index", cell.Ref)
+			}
+			value = shared.Items[idx].String()
+		case "inlineStr":
+			value = cell.Inline.String()
+		case "b":
+			value = map[string]string{"1": "TRUE", "0": "FALSE"}[cell.Value]
+		default:
+			value = cell.Value
+		}
+		if value == "" {
+			continue
+		}
+		for len(values) <= col {
+			values = append(values, "")
+		}
+		values[col] = value
+	}
+	return values, nil
+}
+
+// firstSheet resolves the first worksheet through the workbook relationships,
+// falling back to the conventional sheet1.xml path
+func firstSheet(files map[string]*zip.File) (*zip.File, error) {
+	var workbook xlsxWorkbook
+	var rels xlsxRelationships
+	workbookFile, okWorkbook := files["xl/workbook.xml"]
+	relsFile, okRels := files["xl/_rels/workbook.xml.rels"]
+	if okWorkbook && okRels {
+		if err := decodeXML(workbookFile, &workbook); err != nil {
+			return nil, err
+		}
+		if err := decodeXML(relsFile, &rels); err != nil {
+			return nil, err
+		}
+		if len(workbook.Sheets) > 0 {
+			for _, rel := range rels.Relationships {
+				if rel.ID != workbook.Sheets[0].RelID {
+					continue
+				}
+				target := strings.TrimPrefix(rel.Target, "/")
+				if !strings.HasPrefix(target, "xl/") {
+					target = path.Join("xl", target)
+				}
+				if file, ok := files[target]; ok {
+					return file, nil
+				}
+			}
+		}
+	}
+
+	if file, ok := files["xl/worksheets/sheet1.xml"]; ok {
+		return file, nil
+	}
+	return nil, errors.New("workbook has no worksheets")
+}
+
+// maxColumns is the column limit of Excel worksheets, XFD
+const maxColumns = 16384
+
+// columnIndex converts a cell reference such as "AB12" into a zero-based column
+func columnIndex(ref string) (int, error) {
+	col := 0
+	n := 0
+	for _, ch := range ref {
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		col = col*26 + int(ch-'A'+1)
+		if col > maxColumns {
+			return 0, fmt.Errorf("cell reference %q is beyond the last column", ref)
+		}
+		n++
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("invalid cell reference %q", ref)
+	}
+	return col - 1, nil
+}
+
+func decodeXML(file *zip.File, v interface{}) error {
+	rc, err := openEntry(file)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("%s: %w", file.Name, err)
+	}
+	return nil
+}
+
+// openEntry opens a part of the archive, failing once more than
+// maxEntrySize bytes have been decompressed, whatever its header claims
+func openEntry(file *zip.File) (io.ReadCloser, error) {
+	if file.UncompressedSize64 > maxEntrySize {
+		return nil, fmt.Errorf("%s: larger than %d MB uncompressed", file.Name, maxEntrySize>>20)
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &limitedEntry{ReadCloser: rc, name: file.Name, remaining: maxEntrySize + 1}, nil
+}
+
+type limitedEntry struct {
+	io.ReadCloser
+	name      string
+	remaining int64
+}
+
+func (e *limitedEntry) Read(p []byte) (int, error) {
+	if int64(len(p)) > e.remaining {
+		p = p[:e.remaining]
+	}
+	n, err := e.ReadCloser.Read(p)
+	e.remaining -= int64(n)
+	if e.remaining <= 0 {
+		return n, fmt.Errorf("%s: larger than %d MB uncompressed", e.name, maxEntrySize>>20)
+	}
+	return n, err
+}
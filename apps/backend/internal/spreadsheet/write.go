@@ -0,0 +1,259 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Column describes an output column. Numeric columns are written as numbers
+// where the format distinguishes them (XLSX and JSON).
+type Column struct {
+	Name    string
+	Numeric bool
+}
+
+// Writer streams rows to an output format. Rows are written as they arrive so
+// large exports never need to be held in memory. Close must be called to
+// complete the file.
+type Writer interface {
+	WriteRow(values []string) error
+	Close() error
+}
+
+// ContentTypes maps supported export formats to their MIME type
+var ContentTypes = map[string]string{
+	"csv":  "text/csv; charset=utf-8",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"json": "application/json; charset=utf-8",
+}
+
+// NewWriter starts a file in the given format ("csv", "xlsx" or "json")
+func NewWriter(format string, w io.Writer, columns []Column) (Writer, error) {
+	switch format {
+	case "csv":
+		return newCSVWriter(w, columns)
+	case "xlsx":
+		return newXLSXWriter(w, columns)
+	case "json":
+		return newJSONWriter(w, columns)
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// escapeFormula stops spreadsheet applications from evaluating text that
+// looks like a formula, such as a vendor named "=HYPERLINK(...)", by
+// prefixing it with an apostrophe
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+type csvWriter struct {
+	w       *csv.Writer
+	columns []Column
+	row     []string
+}
+
+func newCSVWriter(w io.Writer, columns []Column) (*csvWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w), columns: columns}
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Name
+	}
+	return cw, cw.WriteRow(header)
+}
+
+// WriteRow escapes text cells; numeric columns keep their value, so
+// negative amounts stay numbers
+func (cw *csvWriter) WriteRow(values []string) error {
+	cw.row = cw.row[:0]
+	for i, value := range values {
+		if i >= len(cw.columns) || !cw.columns[i].Numeric {
+			value = escapeFormula(value)
+		}
+		cw.row = append(cw.row, value)
+	}
+	return cw.w.Write(cw.row)
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// jsonWriter writes an array of objects keyed by column name
+type jsonWriter struct {
+	w       *bufio.Writer
+	columns []Column
+	rows    int
+}
+
+func newJSONWriter(w io.Writer, columns []Column) (*jsonWriter, error) {
+	jw := &jsonWriter{w: bufio.NewWriter(w), columns: columns}
+	_, err := jw.w.WriteString("[")
+	return jw, err
+}
+
+func (jw *jsonWriter) WriteRow(values []string) error {
+	if jw.rows > 0 {
+		jw.w.WriteString(",")
+	}
+	jw.rows++
+
+	jw.w.WriteString("\n{")
+	for i, column := range jw.columns {
+		if i > 0 {
+			jw.w.WriteString(",")
+		}
+		key, _ := json.Marshal(column.Name)
+		jw.w.Write(key)
+		jw.w.WriteString(":")
+
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		switch {
+		case value == "":
+			jw.w.WriteString("null")
+		case column.Numeric && json.Valid([]byte(value)):
+			jw.w.WriteString(value)
+		default:
+			encoded, _ := json.Marshal(value)
+			jw.w.Write(encoded)
+		}
+	}
+	_, err := jw.w.WriteString("}")
+	return err
+}
+
+func (jw *jsonWriter) Close() error {
+	if _, err := jw.w.WriteString("\n]\n"); err != nil {
+		return err
+	}
+	return jw.w.Flush()
+}
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	xlsxWorkbookXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`
+	xlsxSheetHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetFooter = `</sheetData></worksheet>`
+)
+
+// xlsxWriter streams a single worksheet using inline strings, so no shared
+// string table has to be built up in memory
+type xlsxWriter struct {
+	zip     *zip.Writer
+	sheet   *bufio.Writer
+	columns []Column
+	rows    int
+}
+
+func newXLSXWriter(w io.Writer, columns []Column) (*xlsxWriter, error) {
+	archive := zip.NewWriter(w)
+	for _, part := range []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbookXML},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	} {
+		f, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	xw := &xlsxWriter{zip: archive, sheet: bufio.NewWriter(sheet), columns: columns}
+	xw.sheet.WriteString(xlsxSheetHeader)
+
+	// The header row is always text
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Name
+	}
+	return xw, xw.writeRow(header, false)
+}
+
+func (xw *xlsxWriter) WriteRow(values []string) error {
+	return xw.writeRow(values, true)
+}
+
+func (xw *xlsxWriter) writeRow(values []string, typed bool) error {
+	xw.rows++
+	fmt.Fprintf(xw.sheet, `<row r="%d">`, xw.rows)
+	for i, value := range values {
+		if value == "" {
+			continue
+		}
+		ref := fmt.Sprintf("%s%d", columnName(i), xw.rows)
+		if typed && i < len(xw.columns) && xw.columns[i].Numeric {
+			fmt.Fprintf(xw.sheet, `<c r="%s"><v>%s</v></c>`, ref, value)
+			continue
+		}
+		fmt.Fprintf(xw.sheet, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+		if err := xml.EscapeText(xw.sheet, []byte(escapeFormula(value))); err != nil {
+			return err
+		}
+		xw.sheet.WriteString(`</t></is></c>`)
+	}
+	_, err := xw.sheet.WriteString(`</row>`)
+	return err
+}
+
+func (xw *xlsxWriter) Close() error {
+	if _, err := xw.sheet.WriteString(xlsxSheetFooter); err != nil {
+		return err
+	}
+	if err := xw.sheet.Flush(); err != nil {
+		return err
+	}
+	return xw.zip.Close()
+}
+
+// columnName converts a zero-based column index into a letter reference such as "AB"
+func columnName(i int) string {
+	var name strings.Builder
+	for i++; i > 0; i = (i - 1) / 26 {
+		name.WriteByte(byte('A' + (i-1)%26))
+	}
+	letters := []byte(name.String())
+	for l, r := 0, len(letters)-1; l < r; l, r = l+1, r-1 {
+		letters[l], letters[r] = letters[r], letters[l]
+	}
+	return string(letters)
+}
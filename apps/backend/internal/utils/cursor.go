@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCursor is returned for cursor tokens that cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks a position in a keyset-paginated list: the sort key and ID of
+// the row the next page starts after. It is handed to clients as an opaque
+// token and carries the sort so follow-up requests need not repeat it.
+type Cursor struct {
+	Sort     string          `json:"s"`
+	Order    string          `json:"o"`
+	Value    json.RawMessage `json:"v"`
+	ID       uuid.UUID       `json:"i"`
+	Backward bool            `json:"b,omitempty"`
+}
+
+// Encode returns the cursor as a URL-safe token
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a token produced by Cursor.Encode
+func DecodeCursor(token string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == uuid.Nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Order != "asc" && cursor.Order != "desc" {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// CursorPage holds the navigation data of a keyset page
+type CursorPage struct {
+	NextCursor string
+	PrevCursor string
+	TotalItems *int64
+}
+
+// ApplyKeyset restricts a query to the rows after the cursor and orders it by
+// (column, idColumn). Backward cursors walk the list in reverse; the fetched
+// rows must then be reversed, which KeysetPage does.
+func ApplyKeyset(db *gorm.DB, column, idColumn string, desc bool, cursor *Cursor, value interface{}) *gorm.DB {
+	reverse := cursor != nil && cursor.Backward
+	if desc != reverse {
+		if cursor != nil {
+			db = db.Where(fmt.Sprintf("(%s, %s) < (?, ?)", column, idColumn), value, cursor.ID)
+		}
+		return db.Order(column + " DESC").Order(idColumn + " DESC")
+	}
+	if cursor != nil {
+		db = db.Where(fmt.Sprintf("(%s, %s) > (?, ?)", column, idColumn), value, cursor.ID)
+	}
+	return db.Order(column + " ASC").Order(idColumn + " ASC")
+}
+
+// KeysetPage trims rows fetched with Limit(pageSize+1), restores their order
+// for backward pages and builds the cursors pointing at neighbouring pages.
+// key returns a row's sort value and ID.
+func KeysetPage[T any](rows []T, pageSize int, cursor *Cursor, sort, order string, key func(*T) (interface{}, uuid.UUID)) ([]T, CursorPage, error) {
+	var page CursorPage
+
+	hasMore := len(rows) > pageSize
+	if hasMore {
+		rows = rows[:pageSize]
+	}
+	backward := cursor != nil && cursor.Backward
+	if backward {
+		for l, r := 0, len(rows)-1; l < r; l, r = l+1, r-1 {
+			rows[l], rows[r] = rows[r], rows[l]
+		}
+	}
+	if len(rows) == 0 {
+		return rows, page, nil
+	}
+
+	build := func(row *T, backward bool) (string, error) {
+		value, id := key(row)
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		return Cursor{Sort: sort, Order: order, Value: raw, ID: id, Backward: backward}.Encode(), nil
+	}
+
+	var err error
+	if hasMore || backward {
+		if page.NextCursor, err = build(&rows[len(rows)-1], false); err != nil {
+			return nil, page, err
+		}
+	}
+	if cursor != nil && (hasMore || !backward) {
+		if page.PrevCursor, err = build(&rows[0], true); err != nil {
+			return nil, page, err
+		}
+	}
+	return rows, page, nil
+}
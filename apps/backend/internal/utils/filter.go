@@ -0,0 +1,206 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidFilter is returned for filters that cannot be parsed or are not allowed
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// FilterType determines how a filter value is parsed and which operators apply
+type FilterType int
+
+const (
+	FilterString FilterType = iota
+	FilterNumber
+	FilterDate
+	FilterBool
+	FilterUUID
+)
+
+// FilterField describes a filterable field of a resource
+type FilterField struct {
+	Column string
+	Type   FilterType
+	// Values restricts a string field to an enumeration
+	Values []string
+	// Normalize is applied to string values before validation, e.g. upper-casing
+	Normalize func(string) string
+}
+
+// FilterFields maps the query parameter names a resource accepts to their fields
+type FilterFields map[string]FilterField
+
+// Condition is a parsed, validated filter ready to be applied to a query
+type Condition struct {
+	Column   string
+	Operator string
+	Value    interface{}
+}
+
+var filterOperators = map[string]string{
+	"eq":  "=",
+	"ne":  "<>",
+	"gt":  ">",
+	"gte": ">=",
+	"lt":  "<",
+	"lte": "<=",
+	"in":  "IN",
+}
+
+var filterKeyPattern = regexp.MustCompile(`^([a-z_]+)\[([a-z]+)\]$`)
+
+// ParseFilters reads filters from query parameters of the form
+//
+//	field=value             equality
+//	field=a,b               any of the listed values
+//	field[op]=value         op is one of eq, ne, gt, gte, lt, lte, in
+//
+// Range operators apply to number and date fields only. Parameters that are
+// not filter fields (page, sort, ...) are ignored, but an unknown field used
+// with an operator is rejected so typos surface as errors.
+func ParseFilters(query url.Values, fields FilterFields) ([]Condition, error) {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var conditions []Condition
+	for _, key := range keys {
+		name, op := key, ""
+		if match := filterKeyPattern.FindStringSubmatch(key); match != nil {
+			name, op = match[1], match[2]
+		}
+
+		field, ok := fields[name]
+		if !ok {
+			if op != "" {
+				return nil, fmt.Errorf("%w: unknown field %q, expected one of: %s",
+					ErrInvalidFilter, name, strings.Join(fields.Names(), ", "))
+			}
+			continue
+		}
+
+		var raw []string
+		for _, value := range query[key] {
+			for _, part := range strings.Split(value, ",") {
+				if part = strings.TrimSpace(part); part != "" {
+					raw = append(raw, part)
+				}
+			}
+		}
+		if len(raw) == 0 {
+			continue
+		}
+
+		if op == "" {
+			op = "eq"
+			if len(raw) > 1 {
+				op = "in"
+			}
+		}
+		operator, ok := filterOperators[op]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown operator %q on %s", ErrInvalidFilter, op, name)
+		}
+		if isRangeOperator(op) && field.Type != FilterNumber && field.Type != FilterDate {
+			return nil, fmt.Errorf("%w: operator %q is not supported on %s", ErrInvalidFilter, op, name)
+		}
+		if op != "in" && len(raw) > 1 {
+			return nil, fmt.Errorf("%w: %s[%s] takes a single value", ErrInvalidFilter, name, op)
+		}
+
+		values := make([]interface{}, len(raw))
+		for i, value := range raw {
+			parsed, err := parseFilterValue(field, value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %v", ErrInvalidFilter, name, err)
+			}
+			values[i] = parsed
+		}
+
+		condition := Condition{Column: field.Column, Operator: operator, Value: values[0]}
+		if op == "in" {
+			condition.Value = values
+		}
+		conditions = append(conditions, condition)
+	}
+
+	return conditions, nil
+}
+
+// ApplyFilters adds parsed conditions to a query as parameterized clauses
+func ApplyFilters(db *gorm.DB, conditions []Condition) *gorm.DB {
+	for _, condition := range conditions {
+		db = db.Where(fmt.Sprintf("%s %s ?", condition.Column, condition.Operator), condition.Value)
+	}
+	return db
+}
+
+// Names returns the accepted filter names in alphabetical order
+func (f FilterFields) Names() []string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isRangeOperator(op string) bool {
+	return op == "gt" || op == "gte" || op == "lt" || op == "lte"
+}
+
+func parseFilterValue(field FilterField, value string) (interface{}, error) {
+	switch field.Type {
+	case FilterNumber:
+		number, err := decimal.NewFromString(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+		return number, nil
+	case FilterDate:
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a date in YYYY-MM-DD format", value)
+		}
+		return date, nil
+	case FilterBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not true or false", value)
+		}
+		return b, nil
+	case FilterUUID:
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid ID", value)
+		}
+		return id, nil
+	default:
+		if field.Normalize != nil {
+			value = field.Normalize(value)
+		}
+		if len(field.Values) > 0 {
+			for _, allowed := range field.Values {
+				if value == allowed {
+					return value, nil
+				}
+			}
+			return nil, fmt.Errorf("%q is not one of: %s", value, strings.Join(field.Values, ", "))
+		}
+		return value, nil
+	}
+}
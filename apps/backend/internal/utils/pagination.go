@@ -1,17 +1,29 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
 )
 
-// Pagination holds pagination parameters
+// ErrInvalidSort is returned for sort fields a resource does not allow
+var ErrInvalidSort = errors.New("invalid sort field")
+
+// Pagination holds pagination parameters. Passing a cursor parameter (empty
+// for the first page) switches from offset to keyset pagination.
 type Pagination struct {
-	Page     int    `json:"page"`
-	PageSize int    `json:"page_size"`
-	Sort     string `json:"sort"`
-	Order    string `json:"order"`
+	Page         int    `json:"page"`
+	PageSize     int    `json:"page_size"`
+	Sort         string `json:"sort"`
+	Order        string `json:"order"`
+	Cursor       string `json:"cursor,omitempty"`
+	UseCursor    bool   `json:"-"`
+	IncludeTotal bool   `json:"-"`
 }
 
 // GetPagination extracts pagination from query params
@@ -35,12 +47,32 @@ func GetPagination(c *gin.Context) Pagination {
 		order = "desc"
 	}
 
+	// Keyset pages skip the COUNT(*) unless the client asks for it
+	cursor, useCursor := c.GetQuery("cursor")
+	includeTotal, _ := strconv.ParseBool(c.Query("include_total"))
+
 	return Pagination{
-		Page:     page,
-		PageSize: pageSize,
-		Sort:     sort,
-		Order:    order,
+		Page:         page,
+		PageSize:     pageSize,
+		Sort:         sort,
+		Order:        order,
+		Cursor:       cursor,
+		UseCursor:    useCursor,
+		IncludeTotal: includeTotal,
+	}
+}
+
+// DecodeCursor returns the request's cursor, or nil for the first page
+func (p *Pagination) DecodeCursor() (*Cursor, error) {
+	if p.Cursor == "" {
+		return nil, nil
+	}
+	cursor, err := DecodeCursor(p.Cursor)
+	if err != nil {
+		return nil, err
 	}
+	p.Sort, p.Order = cursor.Sort, cursor.Order
+	return cursor, nil
 }
 
 // GetOffset calculates the database offset
@@ -48,7 +80,31 @@ func (p *Pagination) GetOffset() int {
 	return (p.Page - 1) * p.PageSize
 }
 
-// GetOrderClause returns the GORM order clause
-func (p *Pagination) GetOrderClause() string {
-	return p.Sort + " " + p.Order
+// SortFields maps the sort names a resource accepts to their columns
+type SortFields map[string]string
+
+// Names returns the accepted sort names in alphabetical order
+func (f SortFields) Names() []string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OrderBy builds the ORDER BY clause for a whitelisted sort field. The primary
+// key is added as a tie-breaker so rows with equal values page consistently.
+func (p *Pagination) OrderBy(fields SortFields) (clause.OrderBy, error) {
+	column, ok := fields[p.Sort]
+	if !ok {
+		return clause.OrderBy{}, fmt.Errorf("%w %q, expected one of: %s",
+			ErrInvalidSort, p.Sort, strings.Join(fields.Names(), ", "))
+	}
+
+	desc := p.Order == "desc"
+	return clause.OrderBy{Columns: []clause.OrderByColumn{
+		{Column: clause.Column{Name: column}, Desc: desc},
+		{Column: clause.Column{Name: "id"}, Desc: desc},
+	}}, nil
 }
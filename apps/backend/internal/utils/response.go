@@ -14,13 +14,16 @@ type APIResponse struct {
 	Error   string      `json:"error,omitempty"`
 }
 
-// PaginatedResponse wraps paginated data
+// PaginatedResponse wraps paginated data. Offset pages carry page numbers;
+// keyset pages carry cursors and only include totals when asked to.
 type PaginatedResponse struct {
 	Items      interface{} `json:"items"`
-	TotalItems int64       `json:"total_items"`
-	Page       int         `json:"page"`
+	TotalItems *int64      `json:"total_items,omitempty"`
+	Page       int         `json:"page,omitempty"`
 	PageSize   int         `json:"page_size"`
-	TotalPages int         `json:"total_pages"`
+	TotalPages *int        `json:"total_pages,omitempty"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	PrevCursor string      `json:"prev_cursor,omitempty"`
 }
 
 // Success sends a success response
@@ -49,6 +52,15 @@ func Error(c *gin.Context, statusCode int, message string) {
 	})
 }
 
+// ErrorWithData sends an error response carrying details the client can act on
+func ErrorWithData(c *gin.Context, statusCode int, message string, data interface{}) {
+	c.JSON(statusCode, APIResponse{
+		Success: false,
+		Error:   message,
+		Data:    data,
+	})
+}
+
 // BadRequest sends a 400 error
 func BadRequest(c *gin.Context, message string) {
 	Error(c, http.StatusBadRequest, message)
@@ -85,10 +97,33 @@ func Paginated(c *gin.Context, items interface{}, total int64, page, pageSize in
 		Success: true,
 		Data: PaginatedResponse{
 			Items:      items,
-			TotalItems: total,
+			TotalItems: &total,
 			Page:       page,
 			PageSize:   pageSize,
-			TotalPages: totalPages,
+			TotalPages: &totalPages,
 		},
 	})
 }
+
+// CursorPaginated sends a keyset paginated response
+func CursorPaginated(c *gin.Context, items interface{}, pageSize int, page CursorPage) {
+	response := PaginatedResponse{
+		Items:      items,
+		TotalItems: page.TotalItems,
+		PageSize:   pageSize,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	}
+	if page.TotalItems != nil {
+		totalPages := int(*page.TotalItems) / pageSize
+		if int(*page.TotalItems)%pageSize > 0 {
+			totalPages++
+		}
+		response.TotalPages = &totalPages
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Success: true,
+		Data:    response,
+	})
+}
@@ -25,6 +25,7 @@ export function useAuth() {
         onSuccess: (data) => {
             token.value = data.token;
             localStorage.setItem('token', data.token);
+            localStorage.setItem('refresh_token', data.refresh_token);
             queryClient.setQueryData(['auth', 'me'], data.user);
             router.push('/');
         },
@@ -35,17 +36,28 @@ export function useAuth() {
         onSuccess: (data) => {
             token.value = data.token;
             localStorage.setItem('token', data.token);
+            localStorage.setItem('refresh_token', data.refresh_token);
             queryClient.setQueryData(['auth', 'me'], data.user);
             router.push('/');
         },
     });
 
+    // Store tokens obtained outside the login form, e.g. from Google sign-in
+    const completeSignIn = (accessToken: string, refreshToken: string) => {
+        token.value = accessToken;
+        localStorage.setItem('token', accessToken);
+        localStorage.setItem('refresh_token', refreshToken);
+        queryClient.invalidateQueries({ queryKey: ['auth', 'me'] });
+        router.replace('/');
+    };
+
     const logoutMutation = useMutation({
         mutationFn: () => authService.logout(),
         onSettled: () => {
             token.value = null;
             currentUser.value = null;
             localStorage.removeItem('token');
+            localStorage.removeItem('refresh_token');
             queryClient.clear();
             router.push('/login');
         },
@@ -64,6 +76,7 @@ export function useAuth() {
         registerAsync: registerMutation.mutateAsync,
         isRegistering: registerMutation.isPending,
         registerError: registerMutation.error,
+        completeSignIn,
         logout: logoutMutation.mutate,
         isLoggingOut: logoutMutation.isPending,
     };
@@ -1,4 +1,4 @@
-import axios from 'axios';
+import axios, { type InternalAxiosRequestConfig } from 'axios';
 
 // Create api instance
 const api = axios.create({
@@ -22,15 +22,53 @@ api.interceptors.request.use(
     }
 );
 
+// Single in-flight refresh shared by requests that fail together
+let refreshing: Promise<string | null> | null = null;
+
+// Exchange the stored refresh token for a new token pair
+async function refreshAccessToken(): Promise<string | null> {
+    const refreshToken = localStorage.getItem('refresh_token');
+    if (!refreshToken) {
+        return null;
+    }
+    try {
+        // Plain axios so a failed refresh does not re-enter the interceptor
+        const response = await axios.post(`${api.defaults.baseURL}/auth/refresh`, {
+            refresh_token: refreshToken,
+        });
+        const { token, refresh_token } = response.data.data;
+        localStorage.setItem('token', token);
+        localStorage.setItem('refresh_token', refresh_token);
+        return token;
+    } catch {
+        return null;
+    }
+}
+
 // Response interceptor for API calls
 api.interceptors.response.use(
     (response) => {
         return response;
     },
     async (error) => {
+        const request = error.config as (InternalAxiosRequestConfig & { _retried?: boolean }) | undefined;
+        if (error.response?.status === 401 && request && !request._retried && !request.url?.startsWith('/auth/')) {
+            // Access tokens are short-lived; renew once and replay the request
+            request._retried = true;
+            refreshing ??= refreshAccessToken().finally(() => {
+                refreshing = null;
+            });
+            const token = await refreshing;
+            if (token) {
+                request.headers.Authorization = `Bearer ${token}`;
+                return api(request);
+            }
+        }
+
         if (error.response?.status === 401) {
-            // Clear token and redirect to login if 401
+            // Clear tokens and redirect to login if 401
             localStorage.removeItem('token');
+            localStorage.removeItem('refresh_token');
             localStorage.removeItem('user');
 
             // Only redirect if not already on auth pages
@@ -64,6 +64,16 @@ const router = createRouter({
             component: () => import('../views/Login.vue'),
             meta: { guestOnly: true }
         },
+        {
+            path: '/auth/callback',
+            name: 'auth-callback',
+            component: () => import('../views/AuthCallback.vue')
+        },
+        {
+            path: '/verify-email',
+            name: 'verify-email',
+            component: () => import('../views/VerifyEmail.vue')
+        },
         {
             path: '/register',
             name: 'register',
@@ -19,7 +19,16 @@ export const authService = {
     },
 
     async logout(): Promise<void> {
-        await api.post('/auth/logout');
+        await api.post('/auth/logout', { refresh_token: localStorage.getItem('refresh_token') ?? '' });
+    },
+
+    async verifyEmail(token: string): Promise<{ email: string }> {
+        const response = await api.get<ApiResponse<{ email: string }>>('/auth/verify-email', { params: { token } });
+        return response.data.data;
+    },
+
+    async resendVerification(email: string): Promise<void> {
+        await api.post('/auth/verify-email/resend', { email });
     },
 
     async me(): Promise<User> {
@@ -12,9 +12,23 @@ export interface User {
     updated_at: string;
 }
 
-export type BillStatus = 'draft' | 'unpaid' | 'paid' | 'overdue';
+export interface Session {
+    id: string;
+    user_id: string;
+    refreshed_at?: string;
+    expires_at: string;
+    ip_address?: string;
+    user_agent?: string;
+    // 'password', 'sso' or the identity provider, e.g. 'google'
+    sign_in_method?: string;
+    last_used_at?: string;
+    created_at: string;
+    current: boolean;
+}
+
+export type BillStatus = 'draft' | 'pending_approval' | 'unpaid' | 'partially_paid' | 'paid' | 'overdue';
 export type RecurringFrequency = 'weekly' | 'monthly' | 'yearly';
-export type ActivityAction = 'created' | 'updated' | 'status_changed' | 'payment_reminder_sent' | 'attachment_added' | 'attachment_removed' | 'deleted';
+export type ActivityAction = 'created' | 'updated' | 'status_changed' | 'payment_recorded' | 'approved' | 'rejected' | 'payment_reminder_sent' | 'attachment_added' | 'attachment_removed' | 'deleted';
 
 export interface Vendor {
     id: string;
@@ -26,6 +40,7 @@ export interface Vendor {
     contact_info?: string;
     address?: string;
     location?: string;
+    tax_id?: string;
     created_at: string;
     updated_at: string;
 }
@@ -44,20 +59,33 @@ export interface Category {
 export interface BillAttachment {
     id: string;
     bill_id: string;
-    file_name: string;
+    filename: string;
     file_url: string;
     file_type: string;
-    uploaded_by: string;
-    created_at: string;
+    file_size: number;
+    sha256: string;
+    uploaded_by?: string;
+    uploaded_at: string;
+    download_url?: string;
+    download_url_expires_at?: string;
     uploader?: User;
 }
 
+export interface FieldChange {
+    field: string;
+    old: unknown;
+    new: unknown;
+}
+
 export interface BillActivity {
     id: string;
     bill_id: string;
     user_id?: string;
     action: ActivityAction;
     details?: string;
+    from_status?: BillStatus;
+    to_status?: BillStatus;
+    changes?: FieldChange[];
     created_at: string;
     user?: User;
 }
@@ -66,11 +94,14 @@ export interface Bill {
     id: string;
     company_id: string;
     user_id: string;
+    parent_bill_id?: string;
     vendor_id?: string;
     category_id?: string;
     title: string;
     invoice_number?: string;
     amount: string; // Decimal as string from API
+    amount_paid: string;
+    outstanding_balance: string;
     currency: string;
     due_date: string;
     paid_date?: string;
@@ -89,6 +120,37 @@ export interface Bill {
     category?: Category;
     attachments?: BillAttachment[];
     activities?: BillActivity[];
+    payments?: Payment[];
+    line_items?: BillLineItem[];
+}
+
+export interface BillLineItem {
+    id: string;
+    bill_id: string;
+    category_id?: string;
+    position: number;
+    description: string;
+    quantity: string;
+    unit_price: string;
+    tax_amount: string;
+    total: string;
+    created_at: string;
+    updated_at: string;
+    category?: Category;
+}
+
+export interface Payment {
+    id: string;
+    company_id: string;
+    bill_id: string;
+    user_id?: string;
+    amount: string;
+    paid_date: string;
+    method?: string;
+    reference?: string;
+    notes?: string;
+    created_at: string;
+    user?: User;
 }
 
 // Auth DTOs
@@ -106,10 +168,20 @@ export interface RegisterInput {
 
 export interface AuthResponse {
     token: string;
+    expires_at: string;
+    refresh_token: string;
     user: User;
 }
 
 // Bill DTOs
+export interface LineItemInput {
+    description: string;
+    category_id?: string;
+    quantity?: number | string;
+    unit_price: number | string;
+    tax_amount?: number | string;
+}
+
 export interface CreateBillInput {
     title: string;
     vendor_id?: string;
@@ -124,6 +196,8 @@ export interface CreateBillInput {
     payment_method?: string;
     notes?: string;
     status?: BillStatus;
+    line_items?: LineItemInput[];
+    confirm_duplicate?: boolean;
 }
 
 export interface UpdateBillInput {
@@ -140,13 +214,157 @@ export interface UpdateBillInput {
     payment_method?: string;
     notes?: string;
     status?: BillStatus;
+    line_items?: LineItemInput[];
+}
+
+export interface ImportRowError {
+    row: number;
+    field?: string;
+    message: string;
+}
+
+export interface BillImportResult {
+    dry_run: boolean;
+    total_rows: number;
+    valid_rows: number;
+    created: number;
+    created_vendors: string[];
+    created_categories: string[];
+    errors: ImportRowError[];
+}
+
+export interface EInvoiceResult {
+    format: 'ubl' | 'cii';
+    bill: Bill;
+    vendor_created: boolean;
+    attachment: BillAttachment;
+    warnings: string[];
 }
 
+export type ReminderTrigger = 'before_due' | 'overdue';
+
+export interface ReminderRule {
+    id: string;
+    company_id: string;
+    name: string;
+    trigger: ReminderTrigger;
+    days: number;
+    notify_admins: boolean;
+    enabled: boolean;
+    subject_template?: string;
+    text_template?: string;
+    html_template?: string;
+    created_at: string;
+    updated_at: string;
+}
+
+export interface ReminderRuleInput {
+    name: string;
+    trigger: ReminderTrigger;
+    days: number;
+    notify_admins?: boolean;
+    enabled?: boolean;
+    subject_template?: string;
+    text_template?: string;
+    html_template?: string;
+}
+
+export type WebhookEvent = 'bill.created' | 'bill.updated' | 'bill.status_changed' | 'bill.payment_recorded' | 'bill.paid' | 'bill.deleted';
+
+export interface Webhook {
+    id: string;
+    company_id: string;
+    url: string;
+    secret: string;
+    events: WebhookEvent[];
+    description?: string;
+    enabled: boolean;
+    consecutive_failures: number;
+    disabled_at?: string;
+    disabled_reason?: string;
+    created_at: string;
+    updated_at: string;
+}
+
+export interface WebhookInput {
+    url: string;
+    events: WebhookEvent[];
+    secret?: string;
+    description?: string;
+    enabled?: boolean;
+}
+
+export interface WebhookDelivery {
+    id: string;
+    webhook_id: string;
+    event_id: string;
+    event: WebhookEvent;
+    payload: unknown;
+    status: 'pending' | 'succeeded' | 'failed';
+    attempts: number;
+    next_attempt_at?: string;
+    last_attempt_at?: string;
+    response_status?: number;
+    response_body?: string;
+    error?: string;
+    duration_ms?: number;
+    created_at: string;
+    updated_at: string;
+}
+
+export interface CalendarSubscription {
+    url: string;
+    webcal_url: string;
+}
+
+export interface SSOConfig {
+    id: string;
+    company_id: string;
+    issuer: string;
+    client_id: string;
+    allowed_domains: string[];
+    // Domains whose TXT record _billtracker-verification.<domain> holds verification_token
+    verified_domains: string[];
+    verification_token: string;
+    default_role: UserRole;
+    enabled: boolean;
+    enforced: boolean;
+    created_at: string;
+    updated_at: string;
+}
+
+export interface SSOConfigInput {
+    issuer: string;
+    client_id: string;
+    client_secret?: string;
+    allowed_domains: string[];
+    default_role?: UserRole;
+    enabled?: boolean;
+    enforced: boolean;
+}
+
+// What a company keeps users with unverified email addresses from doing
+export type EmailVerificationPolicy = 'off' | 'bills' | 'login';
+
+export type BillSortField = 'created_at' | 'updated_at' | 'due_date' | 'paid_date' | 'amount' | 'title' | 'status' | 'currency' | 'invoice_number';
+
 export interface BillFilters {
-    status?: string;
+    status?: string; // comma separated for several statuses
     search?: string;
     page: number;
     page_size: number;
+    sort?: BillSortField;
+    order?: 'asc' | 'desc';
+    cursor?: string; // enables keyset pagination; empty for the first page
+    include_total?: boolean;
+    'amount[gte]'?: number | string;
+    'amount[lte]'?: number | string;
+    'due_date[gte]'?: string;
+    'due_date[lte]'?: string;
+    vendor_id?: string;
+    category_id?: string;
+    currency?: string;
+    is_recurring?: boolean;
 }
 
 // Vendor DTOs
@@ -158,6 +376,7 @@ export interface CreateVendorInput {
     contact_info?: string;
     address?: string;
     location?: string;
+    tax_id?: string;
 }
 
 export interface UpdateVendorInput {
@@ -168,6 +387,7 @@ export interface UpdateVendorInput {
     contact_info?: string;
     address?: string;
     location?: string;
+    tax_id?: string;
 }
 
 // Category DTOs
@@ -198,11 +418,13 @@ export interface ChangePasswordInput {
 
 // Dashboard DTOs
 export interface DashboardStats {
+    currency: string;
     total_expense: string;
     paid_this_month: string;
     unpaid_amount: string;
     overdue_bills_count: number;
     expense_change_percent: number;
+    missing_rates?: string[];
 }
 
 export interface MonthlyExpense {
@@ -223,6 +445,8 @@ export interface PaginationMeta {
     page_size: number;
     total_items: number;
     total_pages: number;
+    next_cursor?: string;
+    prev_cursor?: string;
 }
 
 export interface PaginatedResponse<T> {